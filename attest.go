@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// an attestation is a claim about a found key signed by that key, so that anyone can check that whoever
+// reports the address holds its key, without learning it. the claim is signed as an EIP-191
+// personal_sign message, so any Ethereum tool that verifies signed messages can check it, as can
+// 'vanity verify -attestation'.
+
+type attestation struct {
+	Message   string `json:"message"`   // the claim, as JSON
+	Signature string `json:"signature"` // by the claim's address, in hex, with v 27 or 28
+}
+
+// claim is what an attestation says about a key.
+type claim struct {
+	Address       string    `json:"address"`
+	Prefix        string    `json:"prefix,omitempty"`
+	Suffix        string    `json:"suffix,omitempty"`
+	CaseSensitive bool      `json:"case_sensitive,omitempty"` // omitted if case was ignored or is not known
+	Chain         string    `json:"chain"`
+	Timestamp     time.Time `json:"timestamp"`
+	Version       string    `json:"version"`
+}
+
+// claimFor returns the claim for the key described by meta.
+func claimFor(meta metadata) claim {
+	return claim{meta.Address, meta.Prefix, meta.Suffix, meta.CaseSensitive, meta.Chain, meta.Timestamp, meta.Version}
+}
+
+// attest signs c with key, which must be the key of c.Address.
+func attest(key *ecdsa.PrivateKey, c claim) (*attestation, error) {
+	if crypto.PubkeyToAddress(key.PublicKey).Hex() != c.Address {
+		return nil, fmt.Errorf("the key is not the key of %s", c.Address)
+	}
+	return signMessage(key, c)
+}
+
+// signMessage signs v, as JSON, with key, as attestations are signed.
+func signMessage(key *ecdsa.PrivateKey, v any) (*attestation, error) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(accounts.TextHash(msg), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return &attestation{Message: string(msg), Signature: hexutil.Encode(sig)}, nil
+}
+
+var errBadAttestation = errors.New("the attestation is not signed by the key of its address")
+
+// check checks that a is signed by the key of the address it names, and returns its claim.
+func (a *attestation) check() (claim, error) {
+	var c claim
+	if err := json.Unmarshal([]byte(a.Message), &c); err != nil {
+		return c, fmt.Errorf("invalid attestation message: %w", err)
+	}
+	if !common.IsHexAddress(c.Address) {
+		return c, fmt.Errorf("invalid attestation address %q", c.Address)
+	}
+	pub, err := a.signer()
+	if err != nil {
+		return c, fmt.Errorf("%w: %w", errBadAttestation, err)
+	}
+	if crypto.PubkeyToAddress(*pub) != common.HexToAddress(c.Address) {
+		return c, errBadAttestation
+	}
+	return c, nil
+}
+
+// signer returns the public key that signed a, whether or not it is the key of the address it names.
+func (a *attestation) signer() (*ecdsa.PublicKey, error) {
+	sig, err := hexutil.Decode(a.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature %q", a.Signature)
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	return crypto.SigToPub(accounts.TextHash([]byte(a.Message)), sig)
+}
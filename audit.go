@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// auditLog appends a JSON line to a file whenever a search, a generate run or a serve job, starts,
+// finds a key and ends, so that whoever runs searches for others can show what was generated, when and
+// where it went. the lines of a search share its "run" ID; a search with no "end" line was killed.
+// nothing secret is written: no key material, and no passphrases or the query strings of URLs given as
+// flags. the file is only ever appended to, one whole line per write, so that several processes can
+// share it. the methods do nothing on a nil *auditLog, so callers needn't check whether one was asked for.
+type auditLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{f: f}, nil
+}
+
+func (a *auditLog) close() {
+	if a != nil {
+		a.f.Close()
+	}
+}
+
+// auditStart describes a search as it starts.
+type auditStart struct {
+	Command       string            `json:"command"` // generate or serve
+	Tenant        string            `json:"tenant,omitempty"`
+	Prefix        string            `json:"prefix,omitempty"`
+	Suffix        string            `json:"suffix,omitempty"`
+	Patterns      int               `json:"patterns,omitempty"` // in the -patterns or -prefixes list
+	CaseSensitive bool              `json:"case_sensitive"`
+	Chain         string            `json:"chain"`
+	Backend       string            `json:"backend"`
+	Flags         map[string]string `json:"flags,omitempty"` // the flags given, or the options of a job
+	User          string            `json:"user,omitempty"`
+	Host          string            `json:"host,omitempty"`
+	Version       string            `json:"version"`
+}
+
+type auditEvent struct {
+	event
+	Run string `json:"run"`
+}
+
+func (a *auditLog) write(v any) {
+	if a == nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("could not write to the audit log", "err", err)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// synced, as a line lost to a crash is a gap in the trail
+	if _, err = a.f.Write(append(b, '\n')); err == nil {
+		err = a.f.Sync()
+	}
+	if err != nil {
+		slog.Error("could not write to the audit log", "path", a.f.Name(), "err", err)
+	}
+}
+
+func (a *auditLog) start(run string, s auditStart) {
+	if a == nil {
+		return
+	}
+	s.User, s.Host, s.Version = currentUser(), hostname(), version
+	a.write(struct {
+		auditEvent
+		auditStart
+	}{auditEvent{event{"start", time.Now()}, run}, s})
+}
+
+// found records a key found for run, with the paths it was written to, if any.
+func (a *auditLog) found(run, addr, path, bundle string, attempts uint64) {
+	a.write(struct {
+		auditEvent
+		Address  string `json:"address"`
+		Path     string `json:"path,omitempty"`
+		Bundle   string `json:"bundle,omitempty"`
+		Attempts uint64 `json:"attempts"`
+	}{auditEvent{event{"found", time.Now()}, run}, addr, path, bundle, attempts})
+}
+
+// end records the end of run. status is done, failed or canceled, as for serve's jobs, and reason says
+// why a search that is not done ended.
+func (a *auditLog) end(run, status, reason string, found int, attempts, invalid uint64, elapsed time.Duration) {
+	a.write(struct {
+		auditEvent
+		Status         string  `json:"status"`
+		Reason         string  `json:"reason,omitempty"`
+		Found          int     `json:"found"`
+		Attempts       uint64  `json:"attempts"`
+		Invalid        uint64  `json:"invalid,omitempty"`
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+	}{auditEvent{event{"end", time.Now()}, run}, status, reason, found, attempts, invalid, elapsed.Seconds()})
+}
+
+// newRunID returns a random ID for a generate run.
+func newRunID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// auditFlags returns the flags set in fs, from the command line or defaults, with the user information
+// and query strings of URLs, which can hold credentials, redacted.
+func auditFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		v := f.Value.String()
+		if u, err := url.Parse(v); err == nil && u.Scheme != "" && u.Host != "" {
+			if u.User != nil {
+				u.User = url.User("REDACTED")
+			}
+			if u.RawQuery != "" {
+				u.RawQuery = "REDACTED"
+			}
+			v = u.String()
+		}
+		flags[f.Name] = v
+	})
+	return flags
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
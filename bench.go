@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// backendNames returns the names of the backends for flag descriptions.
+func backendNames() string {
+	names := make([]string, len(vanity.Backends))
+	for i, b := range vanity.Backends {
+		names[i] = string(b.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// selectBackend returns the backend named by -backend, or Fast if -f was given instead.
+func selectBackend(name string, fast bool) (vanity.Backend, error) {
+	b := vanity.Backend(name)
+	if fast && name == string(vanity.Geth) {
+		b = vanity.Fast
+	}
+	if _, ok := b.Info(); !ok {
+		return "", fmt.Errorf("unknown backend %q; use one of %s", name, backendNames())
+	}
+	return b, nil
+}
+
+// calibration is a saved set of bench results, used by estimate in place of measuring the rate itself.
+type calibration struct {
+	Version  string             `json:"version"`
+	GOOS     string             `json:"goos"`
+	GOARCH   string             `json:"goarch"`
+	Workers  int                `json:"workers"`
+	Measured time.Time          `json:"measured"`
+	Rates    map[string]float64 `json:"rates"` // keys/s by backend name
+}
+
+// defaultCalibrationPath returns calibration.json next to the default config file.
+func defaultCalibrationPath() string {
+	if p := defaultConfigPath(); p != "" {
+		return filepath.Join(filepath.Dir(p), "calibration.json")
+	}
+	return ""
+}
+
+func loadCalibration(path string) (*calibration, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := new(calibration)
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// bench implements the bench subcommand, which compares the key rates of the available backends.
+func bench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	var (
+		dur     *time.Duration = fs.Duration("d", 3*time.Second, "how long to measure each backend for")
+		workers *int           = fs.Int("workers", runtime.GOMAXPROCS(0), "number of workers to measure with")
+		save    *bool          = fs.Bool("save", false, "save the results as a calibration profile for estimate")
+		profile *string        = fs.String("profile", defaultCalibrationPath(), "path of the calibration profile")
+		prof                   = addProfileFlags(fs)
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s bench [flags]\n\nmeasures the key rate of each key generation backend.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *workers < 1 {
+		fatal(usageError{fmt.Errorf("-workers must be at least 1")})
+	}
+	if err := prof.start(); err != nil {
+		fatal(err)
+	}
+	defer prof.stop()
+
+	c := calibration{
+		Version:  version,
+		GOOS:     runtime.GOOS,
+		GOARCH:   runtime.GOARCH,
+		Workers:  *workers,
+		Measured: time.Now().UTC(),
+		Rates:    make(map[string]float64, len(vanity.Backends)),
+	}
+	fmt.Printf("measuring each backend for %s with %d workers\n\n", *dur, *workers)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "backend\tkeys/s\trelative\tdescription")
+	var base float64
+	for _, b := range vanity.Backends {
+		rate, err := vanity.MeasureRate(context.Background(), b.Name, 0, false, *dur, *workers)
+		if err != nil {
+			fatal(err)
+		}
+		if base == 0 {
+			base = rate
+		}
+		c.Rates[string(b.Name)] = rate
+		fmt.Fprintf(tw, "%s\t%.0f\t%.2fx\t%s\n", b.Name, rate, rate/base, b.Description)
+	}
+	tw.Flush()
+
+	if !*save {
+		return exitOK
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Dir(*profile), 0700); err != nil {
+		fatal(err)
+	}
+	if err = os.WriteFile(*profile, append(data, '\n'), 0600); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("\nsaved calibration profile to %s\n", *profile)
+	return exitOK
+}
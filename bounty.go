@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// a bounty is a split-key search offered to anyone. the owner of a base key publishes a job, signed by
+// the base key, naming the pattern, the fee and the address it is paid from, and a deadline. a worker
+// who finds an offset publishes a claim signed by the offset itself, which proves that they hold an
+// offset giving a matching address without revealing it, and names the address to pay. once paid, the
+// worker hands over the offset, which 'bounty verify -offset' checks before the owner combines it with
+// 'splitkey'. both files are signed as attestations are, with EIP-191 personal_sign.
+
+// bountyVersion is the version of the job and claim formats.
+const bountyVersion = 1
+
+// bountyJob is what a job file's message holds.
+type bountyJob struct {
+	Version         int            `json:"version"`
+	PublicKey       hexutil.Bytes  `json:"public_key"` // base public key, compressed
+	Prefix          string         `json:"prefix,omitempty"`
+	Suffix          string         `json:"suffix,omitempty"`
+	CaseInsensitive bool           `json:"case_insensitive,omitempty"`
+	Fee             string         `json:"fee,omitempty"` // such as "0.05 ETH"; informational
+	FeeAddress      common.Address `json:"fee_address"`   // the fee is paid from
+	Deadline        time.Time      `json:"deadline"`
+}
+
+// bountyClaim is what a claim file's message holds.
+type bountyClaim struct {
+	Version   int            `json:"version"`
+	Job       common.Hash    `json:"job"` // keccak-256 of the job's message
+	Address   string         `json:"address"`
+	Payout    common.Address `json:"payout"` // the fee is paid to
+	ClaimedAt time.Time      `json:"claimed_at"`
+}
+
+// readBountyJob reads the job file at path and checks that it is signed by its base key.
+func readBountyJob(path string) (*bountyJob, common.Hash, error) {
+	var a attestation
+	if err := readJSON(path, &a); err != nil {
+		return nil, common.Hash{}, err
+	}
+	var job bountyJob
+	if err := json.Unmarshal([]byte(a.Message), &job); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: invalid job: %w", path, err)
+	}
+	if job.Version != bountyVersion {
+		return nil, common.Hash{}, fmt.Errorf("%s: unsupported job version %d", path, job.Version)
+	}
+	if err := vanity.ValidatePattern(job.Prefix + job.Suffix); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: %w", path, err)
+	}
+	signer, err := a.signer()
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if !bytes.Equal(crypto.CompressPubkey(signer), job.PublicKey) {
+		return nil, common.Hash{}, fmt.Errorf("%s: the job is not signed by its base key", path)
+	}
+	return &job, crypto.Keccak256Hash([]byte(a.Message)), nil
+}
+
+// checkBountyClaim checks that the claim file at path is for job, and is signed by an offset that gives
+// its address, which must match the job's pattern, and returns the claim.
+func checkBountyClaim(path string, job *bountyJob, jobHash common.Hash) (*bountyClaim, error) {
+	var a attestation
+	if err := readJSON(path, &a); err != nil {
+		return nil, err
+	}
+	var c bountyClaim
+	if err := json.Unmarshal([]byte(a.Message), &c); err != nil {
+		return nil, fmt.Errorf("%s: invalid claim: %w", path, err)
+	}
+	if c.Job != jobHash {
+		return nil, fmt.Errorf("%s: the claim is for another job", path)
+	}
+	// the signer's public key is the offset times G, so adding it to the base public key gives the
+	// public key of the address claimed
+	k, err := a.signer()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	base, err := crypto.DecompressPubkey(job.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	combined, err := addPublicKeys(base, k)
+	if err != nil {
+		return nil, err
+	}
+	addr := crypto.PubkeyToAddress(*combined)
+	if addr.Hex() != c.Address {
+		return nil, fmt.Errorf("%s: the claim's signer gives %s, not %s", path, addr.Hex(), c.Address)
+	}
+	if !vanity.NewMatcher(job.Prefix, job.Suffix, job.CaseInsensitive).Match(addr[:]) {
+		return nil, fmt.Errorf("%s: %s does not match the job's pattern", path, addr.Hex())
+	}
+	return &c, nil
+}
+
+var errLateClaim = errors.New("the claim was received after the deadline")
+
+// checkReceived returns when the claim at path was received, which must be by job's deadline: at
+// received, an RFC 3339 timestamp, if it is given, or else when the file was last modified. the claim's
+// own time is only the worker's word; when it arrived is what counts.
+func checkReceived(path, received string, job *bountyJob) (time.Time, error) {
+	var at time.Time
+	if received != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, received); err != nil {
+			return at, usageError{fmt.Errorf("invalid -received: %w", err)}
+		}
+	} else {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return at, err
+		}
+		at = fi.ModTime()
+	}
+	if at.After(job.Deadline) {
+		return at, errLateClaim
+	}
+	return at, nil
+}
+
+// parseDeadline parses a deadline given as a duration from now or an RFC 3339 timestamp.
+func parseDeadline(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d).UTC().Truncate(time.Second), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid deadline %q; give a duration, such as 72h, or an RFC 3339 timestamp", s)
+	}
+	return t, nil
+}
+
+// bounty implements the bounty subcommand, whose own subcommands are the steps of a bounty.
+func bounty(args []string) int {
+	steps := []struct {
+		name, desc string
+		run        func(args []string) int
+	}{
+		{"new", "write a job, signed by a base key from 'splitkey -new', for anyone to search for", bountyNew},
+		{"claim", "search for a job's offset and write a claim proving it was found, without revealing it", bountyClaimCmd},
+		{"verify", "check a job and a claim, and the offset once it is handed over", bountyVerify},
+	}
+	if len(args) > 0 {
+		for _, s := range steps {
+			if s.name == args[0] {
+				return s.run(args[1:])
+			}
+		}
+	}
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "usage: %s bounty <step> [flags]\n\noffers a split-key search to anyone for a fee. workers prove that they found an offset before\nthey are paid, and hand it over after.\n\nsteps:\n", os.Args[0])
+	for _, s := range steps {
+		fmt.Fprintf(w, "  %-7s %s\n", s.name, s.desc)
+	}
+	return exitUsage
+}
+
+func bountyNew(args []string) int {
+	fs := flag.NewFlagSet("bounty new", flag.ExitOnError)
+	var (
+		keyPath     *string = fs.String("k", "base.key", "path of the base key file, from 'splitkey -new'")
+		inPass      *string = fs.String("in-pass", "", "file containing the passphrase of an encrypted base key (defaults to $VANITY_PASSPHRASE)")
+		prefix      *string = fs.String("p", "", "output address prefix (excluding 0x)")
+		suffix      *string = fs.String("s", "", "output address suffix")
+		insensitive *bool   = fs.Bool("i", false, "accept case-insensitive solutions")
+		fee         *string = fs.String("fee", "", "fee offered, such as '0.05 ETH'")
+		feeAddr     *string = fs.String("fee-address", "", "address the fee is paid from, so that workers can check that it holds the fee")
+		deadline    *string = fs.String("deadline", "168h", "time until which claims are accepted, as a duration from now or an RFC 3339 timestamp")
+		out         *string = fs.String("o", "job.json", "output path of the job")
+	)
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if err := vanity.ValidatePattern(*prefix + *suffix); err != nil {
+		fatal(err)
+	}
+	from, err := parseAddress("fee-address", *feeAddr)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	due, err := parseDeadline(*deadline, time.Now())
+	if err != nil {
+		fatal(usageError{err})
+	}
+	data, err := os.ReadFile(*keyPath)
+	if err != nil {
+		fatal(err)
+	}
+	base, err := loadKey(data, *inPass, "")
+	clear(data)
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(base)
+	job := bountyJob{
+		Version:         bountyVersion,
+		PublicKey:       crypto.CompressPubkey(&base.PublicKey),
+		Prefix:          *prefix,
+		Suffix:          *suffix,
+		CaseInsensitive: *insensitive,
+		Fee:             *fee,
+		FeeAddress:      from,
+		Deadline:        due,
+	}
+	a, err := signMessage(base, job)
+	if err != nil {
+		fatal(err)
+	}
+	if err = writeNew(*out, a, 0644); err != nil {
+		fatal(err)
+	}
+	slog.Info("wrote the job; publish it", "path", *out, "deadline", due, "expected_attempts", vanity.Difficulty(*prefix, *suffix, !*insensitive))
+	return exitOK
+}
+
+func bountyClaimCmd(args []string) int {
+	fs := flag.NewFlagSet("bounty claim", flag.ExitOnError)
+	var (
+		jobPath    *string = fs.String("job", "job.json", "path of the job")
+		payout     *string = fs.String("payout", "", "address the fee is to be paid to")
+		out        *string = fs.String("o", "claim.json", "output path of the claim, to send to the job's owner")
+		offsetPath *string = fs.String("offset-out", "offset.key", "output path of the offset, to hand over once paid; keep it private until then")
+		numWorkers *int    = fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines")
+	)
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	to, err := parseAddress("payout", *payout)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	job, jobHash, err := readBountyJob(*jobPath)
+	if err != nil {
+		fatal(err)
+	}
+	if time.Now().After(job.Deadline) {
+		fatal(fmt.Errorf("the job's deadline, %s, has passed", job.Deadline.Format(time.RFC3339)))
+	}
+	base, err := crypto.DecompressPubkey(job.PublicKey)
+	if err != nil {
+		fatal(err)
+	}
+	search, err := splitSearch(base, job.Prefix, job.Suffix, job.CaseInsensitive, *numWorkers)
+	if err != nil {
+		fatal(err)
+	}
+	for _, p := range []string{*out, *offsetPath} {
+		if _, err = os.Stat(p); err == nil {
+			fatal(fmt.Errorf("%s already exists", p))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithDeadline(ctx, job.Deadline)
+	defer cancel()
+	slog.Info("searching", "prefix", job.Prefix, "suffix", job.Suffix, "fee", job.Fee, "fee_address", job.FeeAddress.Hex(), "deadline", job.Deadline)
+	res, err := search.Run(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.Error("the job's deadline passed", "attempts", search.Attempts())
+		return exitGaveUp
+	case errors.Is(err, context.Canceled):
+		slog.Info("interrupted", "attempts", search.Attempts())
+		return exitInterrupted
+	case err != nil:
+		fatal(err)
+	}
+	defer vanity.ZeroKey(res.Key)
+	// the offset is written first, so that a claim is never made for an offset that was lost
+	if err = saveHex(*offsetPath, res.Key); err != nil {
+		fatal(err)
+	}
+	// res.Key is the offset: signing with it proves that it is held
+	a, err := signMessage(res.Key, bountyClaim{Version: bountyVersion, Job: jobHash, Address: res.Address.Hex(), Payout: to, ClaimedAt: time.Now().UTC()})
+	if err != nil {
+		fatal(err)
+	}
+	if err = writeNew(*out, a, 0644); err != nil {
+		fatal(err)
+	}
+	if _, err = checkBountyClaim(*out, job, jobHash); err != nil {
+		fatal(fmt.Errorf("internal error: %w", err))
+	}
+	fmt.Println(res.Address.Hex())
+	slog.Info("wrote the claim; send it to the job's owner, and the offset once paid", "claim", *out, "offset", *offsetPath, "attempts", search.Attempts())
+	return exitOK
+}
+
+func bountyVerify(args []string) int {
+	fs := flag.NewFlagSet("bounty verify", flag.ExitOnError)
+	var (
+		jobPath   *string = fs.String("job", "job.json", "path of the job")
+		claimPath *string = fs.String("claim", "", "path of a claim to check")
+		offset    *string = fs.String("offset", "", "offset handed over for the claim, in hex, to check")
+		received  *string = fs.String("received", "", "time the claim was received, as an RFC 3339 timestamp (defaults to the modification time of the -claim file)")
+	)
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *offset != "" && *claimPath == "" {
+		fatal(usageError{errors.New("-offset needs the -claim it is for")})
+	}
+	job, jobHash, err := readBountyJob(*jobPath)
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	slog.Info("the job is signed by its base key", "prefix", job.Prefix, "suffix", job.Suffix, "fee", job.Fee, "fee_address", job.FeeAddress.Hex(), "deadline", job.Deadline)
+	if *claimPath == "" {
+		return exitOK
+	}
+	c, err := checkBountyClaim(*claimPath, job, jobHash)
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	at, err := checkReceived(*claimPath, *received, job)
+	if errors.Is(err, errLateClaim) {
+		slog.Error("the claim was received after the deadline", "received", at, "deadline", job.Deadline)
+		return exitFailure
+	} else if err != nil {
+		fatal(err)
+	}
+	slog.Info("the claim proves that its signer holds an offset giving a matching address", "address", c.Address, "payout", c.Payout.Hex(), "claimed_at", c.ClaimedAt)
+	if *offset != "" {
+		base, err := crypto.DecompressPubkey(job.PublicKey)
+		if err != nil {
+			fatal(err)
+		}
+		_, addr, err := checkSplitOffset(base, vanity.NewMatcher(job.Prefix, job.Suffix, job.CaseInsensitive), *offset)
+		if err == nil && addr.Hex() != c.Address {
+			err = fmt.Errorf("the offset gives %s, not the claimed %s", addr.Hex(), c.Address)
+		}
+		if err != nil {
+			slog.Error(err.Error())
+			return exitFailure
+		}
+		slog.Info("the offset gives the claimed address", "combine", fmt.Sprintf("%s splitkey -k BASE_KEY -offset %s -expect %s", os.Args[0], *offset, c.Address))
+	}
+	fmt.Println(c.Address)
+	return exitOK
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func mustKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// writeSigned signs v with key, lets change alter the attestation, and writes it to a new file.
+func writeSigned(t *testing.T, key *ecdsa.PrivateKey, v any, change func(*attestation)) string {
+	t.Helper()
+	a, err := signMessage(key, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != nil {
+		change(a)
+	}
+	f, err := os.CreateTemp(t.TempDir(), "*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := f.Name()
+	f.Close()
+	os.Remove(path)
+	if err = writeNew(path, a, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// flipSignature changes the last byte of s in a's signature, as a forger or a corrupted file would. the
+// signature still recovers, but to another key.
+func flipSignature(a *attestation) {
+	sig, _ := hexutil.Decode(a.Signature)
+	sig[63] ^= 1
+	a.Signature = hexutil.Encode(sig)
+}
+
+func TestReadBountyJob(t *testing.T) {
+	base, other := mustKey(t), mustKey(t)
+	job := func(pub []byte) bountyJob {
+		return bountyJob{Version: bountyVersion, PublicKey: pub, Prefix: "a", Deadline: time.Now().Add(time.Hour)}
+	}
+	tests := []struct {
+		name   string
+		key    *ecdsa.PrivateKey
+		job    bountyJob
+		change func(*attestation)
+		ok     bool
+	}{
+		{"signed by its base key", base, job(crypto.CompressPubkey(&base.PublicKey)), nil, true},
+		{"signed by another key", other, job(crypto.CompressPubkey(&base.PublicKey)), nil, false},
+		// the same key, but not in the compressed form the signer is compared in
+		{"uncompressed public key", base, job(crypto.FromECDSAPub(&base.PublicKey)), nil, false},
+		{"public key with a byte added", base, job(append(crypto.CompressPubkey(&base.PublicKey), 0)), nil, false},
+		{"bad signature", base, job(crypto.CompressPubkey(&base.PublicKey)), flipSignature, false},
+		{"altered message", base, job(crypto.CompressPubkey(&base.PublicKey)), func(a *attestation) {
+			a.Message = strings.Replace(a.Message, `"prefix":"a"`, `"prefix":"b"`, 1)
+		}, false},
+	}
+	for _, tt := range tests {
+		_, _, err := readBountyJob(writeSigned(t, tt.key, tt.job, tt.change))
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckBountyClaim(t *testing.T) {
+	base := mustKey(t)
+	path := writeSigned(t, base, bountyJob{Version: bountyVersion, PublicKey: crypto.CompressPubkey(&base.PublicKey), Prefix: "a", Deadline: time.Now().Add(time.Hour)}, nil)
+	job, jobHash, err := readBountyJob(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// offsets whose combined address matches the job's pattern, and does not
+	var match, miss *ecdsa.PrivateKey
+	for match == nil || miss == nil {
+		k := mustKey(t)
+		addr, err := vanity.SplitKeyAddress(&base.PublicKey, crypto.FromECDSA(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vanity.NewMatcher("a", "", false).Match(addr[:]) {
+			match = k
+		} else {
+			miss = k
+		}
+	}
+	claimFor := func(k *ecdsa.PrivateKey) bountyClaim {
+		addr, _ := vanity.SplitKeyAddress(&base.PublicKey, crypto.FromECDSA(k))
+		return bountyClaim{Version: bountyVersion, Job: jobHash, Address: addr.Hex(), ClaimedAt: time.Now()}
+	}
+	otherJob := claimFor(match)
+	otherJob.Job[0] ^= 1
+	wrongAddress := claimFor(match)
+	wrongAddress.Address = claimFor(miss).Address
+
+	tests := []struct {
+		name   string
+		key    *ecdsa.PrivateKey
+		claim  bountyClaim
+		change func(*attestation)
+		err    string // in the error, or empty if the claim is good
+	}{
+		{"good", match, claimFor(match), nil, ""},
+		{"another job", match, otherJob, nil, "another job"},
+		{"bad signature", match, claimFor(match), flipSignature, "gives"},
+		{"signed by another offset", miss, claimFor(match), nil, "gives"},
+		{"address not of the signer", match, wrongAddress, nil, "gives"},
+		{"address not matching the pattern", miss, claimFor(miss), nil, "does not match"},
+	}
+	for _, tt := range tests {
+		_, err := checkBountyClaim(writeSigned(t, tt.key, tt.claim, tt.change), job, jobHash)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want one saying %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCheckReceived(t *testing.T) {
+	deadline := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	job := &bountyJob{Deadline: deadline}
+	path := filepath.Join(t.TempDir(), "claim.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		modified time.Time
+		received string
+		err      error // nil if on time
+	}{
+		{"received before the deadline", deadline.Add(time.Hour), "2026-01-02T15:04:04Z", nil},
+		{"received at the deadline", deadline.Add(time.Hour), "2026-01-02T15:04:05Z", nil},
+		{"received after the deadline", deadline.Add(-time.Hour), "2026-01-02T15:04:06Z", errLateClaim},
+		{"received after the deadline in another zone", deadline.Add(-time.Hour), "2026-01-02T16:04:06+01:00", errLateClaim},
+		{"modified before the deadline", deadline.Add(-time.Second), "", nil},
+		{"modified after the deadline", deadline.Add(time.Second), "", errLateClaim},
+	}
+	for _, tt := range tests {
+		if err := os.Chtimes(path, tt.modified, tt.modified); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := checkReceived(path, tt.received, job); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	var usageErr usageError
+	if _, err := checkReceived(path, "yesterday", job); !errors.As(err, &usageErr) {
+		t.Errorf("an invalid -received: got error %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// version is the tool version recorded in result metadata. it can be set at build time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
+var errNoPassphrase = errors.New("no passphrase provided; set VANITY_PASSPHRASE or use the -pass flag")
+
+// readPassphrase returns the contents of the file at path, minus any trailing newline, or, if path is
+// empty, the value of the VANITY_PASSPHRASE environment variable.
+func readPassphrase(path string) ([]byte, error) {
+	if path == "" {
+		if s := os.Getenv("VANITY_PASSPHRASE"); s != "" {
+			return []byte(s), nil
+		}
+		return nil, errNoPassphrase
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimRight(b, "\r\n")
+	if len(b) == 0 {
+		return nil, errNoPassphrase
+	}
+	return b, nil
+}
+
+// metadata describes how a key was found. it never contains key material.
+type metadata struct {
+	Address       string    `json:"address"`
+	Prefix        string    `json:"prefix,omitempty"`
+	Suffix        string    `json:"suffix,omitempty"`
+	CaseSensitive bool      `json:"case_sensitive"`
+	Chain         string    `json:"chain"`
+	Attempts      uint64    `json:"attempts"`
+	Timestamp     time.Time `json:"timestamp"`
+	Version       string    `json:"version"`
+	Entropy       string    `json:"entropy,omitempty"` // where the key's randomness came from
+
+	WithdrawalCredentials string `json:"withdrawal_credentials,omitempty"` // with -withdrawal
+
+	Attestation *attestation `json:"attestation,omitempty"` // of the other fields, by the key
+}
+
+// writeBundle writes an OpenPGP symmetrically encrypted tar archive containing the private key, a JSON
+// metadata sidecar and QR codes of the address and key to path. the archive can be opened with any
+// OpenPGP implementation, e.g. `gpg -d bundle.tar.gpg | tar x`.
+func writeBundle(path string, pass []byte, key *ecdsa.PrivateKey, meta metadata) error {
+	secret := crypto.FromECDSA(key)
+	defer clear(secret)
+	keyHex := make([]byte, hex.EncodedLen(len(secret)))
+	defer clear(keyHex)
+	hex.Encode(keyHex, secret)
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	addrQR, err := qrPNG([]byte(meta.Address))
+	if err != nil {
+		return err
+	}
+	keyQR, err := qrPNG(keyHex)
+	if err != nil {
+		return err
+	}
+	defer clear(keyQR)
+
+	name := strings.TrimPrefix(meta.Address, "0x")
+	var tbuf bytes.Buffer
+	defer func() { clear(tbuf.Bytes()) }() // the archive is only written encrypted
+	tw := tar.NewWriter(&tbuf)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{name + "/priv.key", keyHex},
+		{name + "/metadata.json", append(metaJSON, '\n')},
+		{name + "/address.png", addrQR},
+		{name + "/priv.key.png", keyQR},
+	} {
+		hdr := &tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(file.data)),
+			ModTime: meta.Timestamp,
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err = tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w, err := openpgp.SymmetricallyEncrypt(f, pass, &openpgp.FileHints{IsBinary: true, FileName: name + ".tar"}, &packet.Config{
+		DefaultCipher: packet.CipherAES256,
+		S2KCount:      65011712, // the maximum the s2k count encoding allows
+	})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err = w.Write(tbuf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func qrPNG(data []byte) ([]byte, error) {
+	q, err := qrEncode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = q.WritePNG(&buf, 8); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// chatNotifier sends messages to a Telegram chat and a Slack channel through their bots, so that the
+// end of a long search reaches a phone without a service to receive a -webhook. the bots' tokens are
+// read from the environment rather than flags, which other users can see in the process list. messages
+// never hold key material. the methods do nothing on a nil *chatNotifier.
+type chatNotifier struct {
+	telegramToken, telegramChat string
+	slackToken, slackChannel    string
+}
+
+// newChatNotifier returns a notifier for the given Telegram chat and Slack channel, either of which may
+// be empty, or nil if both are.
+func newChatNotifier(telegramChat, slackChannel string) (*chatNotifier, error) {
+	if telegramChat == "" && slackChannel == "" {
+		return nil, nil
+	}
+	n := &chatNotifier{telegramChat: telegramChat, slackChannel: slackChannel}
+	if telegramChat != "" {
+		if n.telegramToken = os.Getenv("TELEGRAM_BOT_TOKEN"); n.telegramToken == "" {
+			return nil, errors.New("-telegram-chat: set TELEGRAM_BOT_TOKEN")
+		}
+	}
+	if slackChannel != "" {
+		if n.slackToken = os.Getenv("SLACK_BOT_TOKEN"); n.slackToken == "" {
+			return nil, errors.New("-slack-channel: set SLACK_BOT_TOKEN")
+		}
+	}
+	return n, nil
+}
+
+// send sends text to every chat, and returns the errors of those it could not be sent to.
+func (n *chatNotifier) send(text string) error {
+	if n == nil {
+		return nil
+	}
+	var errs []error
+	if n.telegramChat != "" {
+		if err := postChat("https://api.telegram.org/bot"+n.telegramToken+"/sendMessage", "", map[string]string{"chat_id": n.telegramChat, "text": text}); err != nil {
+			errs = append(errs, fmt.Errorf("telegram: %w", err))
+		}
+	}
+	if n.slackChannel != "" {
+		if err := postChat("https://slack.com/api/chat.postMessage", n.slackToken, map[string]string{"channel": n.slackChannel, "text": text}); err != nil {
+			errs = append(errs, fmt.Errorf("slack: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postChat posts msg as JSON to a bot API at endpoint, with token as a bearer token if it is not
+// empty. both APIs answer with {"ok": bool}, and describe failures in "description" (Telegram) or
+// "error" (Slack).
+func postChat(endpoint, token string, msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "vanity/"+version)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Telegram's URL holds the token
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var r struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Error       string `json:"error"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&r); err != nil {
+		return fmt.Errorf("%s: %w", resp.Status, err)
+	}
+	if !r.OK {
+		return fmt.Errorf("%s: %s%s", resp.Status, r.Description, r.Error)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/crypto/scrypt"
+)
+
+// checkpoint is the state needed to resume a search. every key is drawn independently, so apart from
+// the options the search was started with, the only state worth keeping is how much work has been done
+// and which results have already been saved. searches of a -range also keep the keys they have left,
+// so that resuming them neither repeats nor skips any.
+type checkpoint struct {
+	Version   string            `json:"version"`
+	Flags     map[string]string `json:"flags"` // flags set on the command line, by name
+	Found     []common.Address  `json:"found"`
+	Attempts  uint64            `json:"attempts"`
+	Elapsed   time.Duration     `json:"elapsed"`
+	Ranges    []string          `json:"ranges,omitempty"`     // the keys left, as START:SIZE
+	RangeSize uint64            `json:"range_size,omitempty"` // of the whole -range, or its -part
+}
+
+// checkpoints are encrypted with the -pass passphrase wherever they are kept: the keys a -range search
+// has left, or the attempts made by an incremental walk, narrow down where its results lie, so a leaked
+// checkpoint gives away part of the neighbourhood of keys found after it was written.
+
+// loadCheckpoint reads the checkpoint at path, encrypted with pass. checkpoints written as plain JSON,
+// by versions that did not encrypt them, are still read.
+func loadCheckpoint(path string, pass []byte) (*checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(b)
+	c := new(checkpoint)
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		err = json.Unmarshal(b, c)
+	} else {
+		c, err = openCheckpoint(b, pass)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// write atomically replaces the file at path with c encrypted with pass, so that a crash mid-write
+// never leaves a truncated checkpoint behind.
+func (c *checkpoint) write(path string, pass []byte) error {
+	b, err := c.seal(pass)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, b)
+}
+
+// checkpointMagic begins every encrypted checkpoint. it is followed by the scrypt salt and then by the
+// JSON sealed with AES-256-GCM, as job results are, under the key scrypt derives from the passphrase.
+const checkpointMagic = "vanity-checkpoint-1\n"
+
+const checkpointSaltLen = 16
+
+var errBadCheckpoint = errors.New("not a vanity checkpoint, or written by an older version")
+
+// checkpointKey derives the key that seals a checkpoint from pass. the parameters are those of keystores;
+// checkpoints are rare enough to afford them.
+func checkpointKey(pass, salt []byte) ([]byte, error) {
+	return scrypt.Key(pass, salt, v4ScryptN, v4ScryptR, v4ScryptP, 32)
+}
+
+// seal returns c as JSON, encrypted with pass.
+func (c *checkpoint) seal(pass []byte) ([]byte, error) {
+	plain, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plain)
+	salt := make([]byte, checkpointSaltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := checkpointKey(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	sealed, err := seal(key, plain)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(checkpointMagic), salt...), sealed...), nil
+}
+
+// openCheckpoint decrypts the checkpoint sealed in data with pass.
+func openCheckpoint(data, pass []byte) (*checkpoint, error) {
+	rest, ok := bytes.CutPrefix(data, []byte(checkpointMagic))
+	if !ok || len(rest) < checkpointSaltLen {
+		return nil, errBadCheckpoint
+	}
+	key, err := checkpointKey(pass, rest[:checkpointSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	plain, err := unseal(key, rest[checkpointSaltLen:])
+	if err != nil {
+		return nil, errors.New("wrong passphrase")
+	}
+	defer clear(plain)
+	c := new(checkpoint)
+	if err = json.Unmarshal(plain, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// removeCheckpoint overwrites the checkpoint at path with zeros before removing it, so that it cannot be
+// recovered from the blocks it took up. filesystems that copy on write, and SSDs that remap blocks, may
+// still keep the old contents somewhere, which is why checkpoints are encrypted too.
+func removeCheckpoint(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err == nil && fi.Mode().IsRegular() {
+		_, err = f.Write(make([]byte, fi.Size()))
+		if err == nil {
+			err = f.Sync()
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// writeAtomic replaces the file at path with data, readable only by its owner.
+func writeAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/crypto/scrypt"
+)
+
+// a cluster is a search shared by the machines on a LAN that run 'cluster' with the same rendezvous
+// string. they find each other by multicast, as mDNS does, without a coordinator: each announces its
+// pattern and key rate every few seconds, and the one that finds a key announces its address, which
+// stops the others. messages are authenticated with a key derived from the rendezvous string by scrypt,
+// so only machines that know it can join, or stop, the search, and carry a time and a nonce, so that
+// one recorded earlier cannot be replayed to stop a later search.
+//
+// discovery is by multicast only, so machines on different networks cannot cluster. the key space is
+// shared out by each machine walking from its own random start, which it never sends, so that no other
+// machine, nor anyone who learns the rendezvous string, can search another's part for the key it finds.
+// the chance of two machines' walks overlapping is negligible.
+
+const (
+	clusterGroup    = "239.255.86.73:9736" // administratively scoped, like mDNS's, but not its port
+	clusterInterval = 5 * time.Second      // between announcements
+	clusterExpiry   = 3 * clusterInterval  // after which a silent peer is dropped
+	clusterWait     = 30 * time.Second     // for a peer's pattern, without -p or -s
+	clusterMaxAge   = time.Minute          // how far a message's time may be from this machine's clock
+)
+
+// clusterMessage is a multicast message.
+type clusterMessage struct {
+	Type        string    `json:"type"` // announce or found
+	Node        string    `json:"node"`
+	Prefix      string    `json:"prefix,omitempty"`
+	Suffix      string    `json:"suffix,omitempty"`
+	Insensitive bool      `json:"insensitive,omitempty"`
+	Attempts    uint64    `json:"attempts,omitempty"`
+	Rate        float64   `json:"rate,omitempty"` // keys per second
+	Address     string    `json:"address,omitempty"`
+	Time        time.Time `json:"time"`  // when it was sent
+	Nonce       string    `json:"nonce"` // unique to each message, so that replays can be told apart
+}
+
+func (m *clusterMessage) samePattern(o *clusterMessage) bool {
+	return m.Prefix == o.Prefix && m.Suffix == o.Suffix && m.Insensitive == o.Insensitive
+}
+
+// clusterConn sends and receives authenticated cluster messages.
+type clusterConn struct {
+	key   []byte // HMAC-SHA256 key
+	group *net.UDPAddr
+	in    *net.UDPConn
+	out   *net.UDPConn
+	seen  map[string]time.Time // nonces of the messages received in the last 2*clusterMaxAge; only recv uses it
+}
+
+func openCluster(rendezvous string) (*clusterConn, error) {
+	group, err := net.ResolveUDPAddr("udp4", clusterGroup)
+	if err != nil {
+		return nil, err
+	}
+	in, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, err
+	}
+	out, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	key, err := clusterKey(rendezvous)
+	if err != nil {
+		in.Close()
+		out.Close()
+		return nil, err
+	}
+	return &clusterConn{key: key, group: group, in: in, out: out, seen: make(map[string]time.Time)}, nil
+}
+
+// clusterKey derives the MAC key from the rendezvous string. the string is chosen by people and may be
+// guessable, so it is stretched with the keystore scrypt parameters to slow down guesses checked against
+// captured messages. the salt cannot be random, since every machine must derive the same key unaided,
+// but it keeps the key apart from any other use of the same string.
+func clusterKey(rendezvous string) ([]byte, error) {
+	return scrypt.Key([]byte(rendezvous), []byte("vanity cluster rendezvous"), v4ScryptN, v4ScryptR, v4ScryptP, 32)
+}
+
+func (c *clusterConn) Close() error {
+	c.out.Close()
+	return c.in.Close()
+}
+
+// send sends m, stamped with the time and a fresh nonce, followed by its MAC.
+func (c *clusterConn) send(m clusterMessage) error {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	m.Time, m.Nonce = time.Now().UTC(), hex.EncodeToString(nonce[:])
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(b)
+	_, err = c.out.Write(mac.Sum(b))
+	return err
+}
+
+// recv returns the next message with a valid MAC that is neither stale nor a replay, ignoring others.
+func (c *clusterConn) recv() (clusterMessage, error) {
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := c.in.ReadFromUDP(buf)
+		if err != nil {
+			return clusterMessage{}, err
+		}
+		if n < sha256.Size {
+			continue
+		}
+		body, sum := buf[:n-sha256.Size], buf[n-sha256.Size:n]
+		mac := hmac.New(sha256.New, c.key)
+		mac.Write(body)
+		if !hmac.Equal(mac.Sum(nil), sum) {
+			continue // another cluster's, or forged
+		}
+		var m clusterMessage
+		if json.Unmarshal(body, &m) != nil {
+			continue
+		}
+		now := time.Now()
+		if d := now.Sub(m.Time); d > clusterMaxAge || d < -clusterMaxAge {
+			slog.Debug("ignoring a stale cluster message", "node", m.Node, "sent", m.Time)
+			continue
+		}
+		if _, ok := c.seen[m.Nonce]; ok || m.Nonce == "" {
+			continue // a replay, or a copy of a message already received
+		}
+		for nonce, t := range c.seen {
+			if now.Sub(t) > 2*clusterMaxAge {
+				delete(c.seen, nonce)
+			}
+		}
+		c.seen[m.Nonce] = now
+		return m, nil
+	}
+}
+
+// clusterPeer is what is known of another machine.
+type clusterPeer struct {
+	rate float64
+	seen time.Time
+}
+
+// cluster implements the cluster subcommand, which shares a search with the other machines on the LAN
+// running it with the same rendezvous string.
+func cluster(args []string) int {
+	fs := flag.NewFlagSet("cluster", flag.ExitOnError)
+	var (
+		rendezvous  *string        = fs.String("rendezvous", "", "string that the machines sharing the search have in common; it also keeps others out, so make it hard to guess")
+		prefix      *string        = fs.String("p", "", "output address prefix (excluding 0x); without -p or -s, the pattern is taken from the other machines")
+		suffix      *string        = fs.String("s", "", "output address suffix")
+		insensitive *bool          = fs.Bool("i", false, "accept case-insensitive solutions")
+		out         *string        = fs.String("o", "priv.key", "private key file output path, should this machine find the key")
+		numWorkers  *int           = fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines")
+		progress    *time.Duration = fs.Duration("progress", 30*time.Second, "interval between reports of the cluster's key rate; 0 disables them")
+		riskyOut    *bool          = fs.Bool("risky-output", false, "write the key to a terminal, a directory every user can write to, or a network or cloud-synced folder without asking first")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s cluster -rendezvous STRING [flags]\n\nshares a search with the machines on this network running '%[1]s cluster' with the same\n-rendezvous, found by multicast. the key is saved on the machine that finds it, and the others stop.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *rendezvous == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := confirmDestination(*out, *riskyOut, true); err != nil {
+		fatal(usageError{err})
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		fatal(err)
+	}
+	self := clusterMessage{Type: "announce", Node: hex.EncodeToString(id[:]), Prefix: *prefix, Suffix: *suffix, Insensitive: *insensitive}
+	conn, err := openCluster(*rendezvous)
+	if err != nil {
+		fatal(fmt.Errorf("joining the multicast group: %w", err))
+	}
+	defer conn.Close()
+	msgs := make(chan clusterMessage, 64)
+	go func() {
+		for {
+			m, err := conn.recv()
+			if err != nil {
+				close(msgs)
+				return
+			}
+			if m.Node != self.Node {
+				msgs <- m
+			}
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *prefix == "" && *suffix == "" {
+		slog.Info("waiting for another machine's pattern", "timeout", clusterWait)
+		wait := time.After(clusterWait)
+		for self.Prefix == "" && self.Suffix == "" {
+			select {
+			case m, ok := <-msgs:
+				if !ok {
+					fatal(errors.New("the multicast connection closed"))
+				}
+				if m.Type == "announce" {
+					self.Prefix, self.Suffix, self.Insensitive = m.Prefix, m.Suffix, m.Insensitive
+				}
+			case <-wait:
+				fatal(usageError{errors.New("no other machine announced a pattern; give one with -p or -s")})
+			case <-ctx.Done():
+				return exitInterrupted
+			}
+		}
+	}
+	if err = vanity.ValidatePattern(self.Prefix + self.Suffix); err != nil {
+		fatal(err)
+	}
+	search, err := vanity.New(
+		vanity.WithPrefix(self.Prefix),
+		vanity.WithSuffix(self.Suffix),
+		vanity.WithCaseInsensitive(self.Insensitive),
+		vanity.WithWorkers(*numWorkers),
+		vanity.WithBackend(vanity.Walk), // from a random start of its own
+	)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu     sync.Mutex
+		peers  = make(map[string]*clusterPeer)
+		winner clusterMessage // another machine's find
+	)
+	// announce this machine and listen for the others until the search is over
+	go func() {
+		t := time.NewTicker(clusterInterval)
+		defer t.Stop()
+		last, lastAt := uint64(0), time.Now()
+		conn.send(self)
+		for {
+			select {
+			case <-t.C:
+				a := search.Attempts()
+				mu.Lock()
+				self.Attempts, self.Rate = a, float64(a-last)/time.Since(lastAt).Seconds()
+				m := self
+				mu.Unlock()
+				last, lastAt = a, time.Now()
+				if err := conn.send(m); err != nil {
+					slog.Warn("could not announce this machine", "err", err)
+				}
+			case m, ok := <-msgs:
+				if !ok {
+					return
+				}
+				switch {
+				case !m.samePattern(&self):
+					slog.Debug("ignoring a machine searching for another pattern", "node", m.Node)
+				case m.Type == "found" && common.IsHexAddress(m.Address) && search.Matches(common.HexToAddress(m.Address)):
+					mu.Lock()
+					winner = m
+					mu.Unlock()
+					cancel()
+					return
+				case m.Type == "announce":
+					mu.Lock()
+					if _, ok := peers[m.Node]; !ok {
+						slog.Info("a machine joined", "node", m.Node)
+					}
+					peers[m.Node] = &clusterPeer{rate: m.Rate, seen: time.Now()}
+					mu.Unlock()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	if *progress > 0 {
+		go func() {
+			t := time.NewTicker(*progress)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					mu.Lock()
+					total := self.Rate
+					for id, p := range peers {
+						if time.Since(p.seen) > clusterExpiry {
+							slog.Info("a machine left", "node", id)
+							delete(peers, id)
+							continue
+						}
+						total += p.rate
+					}
+					n := len(peers) + 1
+					mu.Unlock()
+					slog.Info("progress", "machines", n, "cluster_keys_per_sec", int(total), "attempts", search.Attempts())
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	slog.Info("searching", "node", self.Node, "prefix", self.Prefix, "suffix", self.Suffix, "expected_attempts", search.Difficulty())
+	res, err := search.Run(ctx)
+	mu.Lock()
+	w := winner
+	mu.Unlock()
+	switch {
+	case w.Node != "":
+		slog.Info("another machine found the key", "node", w.Node, "address", w.Address, "attempts", search.Attempts())
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		slog.Info("interrupted", "attempts", search.Attempts())
+		return exitInterrupted
+	case err != nil:
+		fatal(err)
+	}
+	defer vanity.ZeroKey(res.Key)
+	// the others stop as soon as they hear; a few copies make up for lost datagrams
+	mu.Lock()
+	found := self
+	mu.Unlock()
+	found.Type, found.Address = "found", res.Address.Hex()
+	for range 3 {
+		if err := conn.send(found); err != nil {
+			slog.Warn("could not tell the other machines", "err", err)
+		}
+	}
+	fmt.Println(res.Address.Hex())
+	path := saveChecked(*out, func(path string) error {
+		if err := saveHex(path, res.Key); err != nil {
+			return err
+		}
+		return checkKeyFile(path, "hex", nil, res.Address)
+	})
+	slog.Info("saved the key", "path", path, "attempts", search.Attempts())
+	return exitOK
+}
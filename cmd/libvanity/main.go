@@ -0,0 +1,132 @@
+// Command libvanity builds the search as a C shared library for other languages to call directly:
+//
+//	go build -buildmode=c-shared -o libvanity.so ./cmd/libvanity
+//
+// see vanity.h for the interface. the build also writes a libvanity.h generated by cgo, which declares
+// the same function with cgo's own type names.
+package main
+
+/*
+#include <stddef.h>
+#include <string.h>
+*/
+import "C"
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+	"unsafe"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// status codes returned by vanity_search; keep in sync with vanity.h. they match the exit statuses of
+// the command where they overlap.
+const (
+	statusOK          = 0
+	statusFailure     = 1
+	statusUsage       = 2
+	statusPattern     = 3
+	statusGaveUp      = 5
+	statusShortBuffer = 6
+)
+
+// options is the JSON object accepted by vanity_search.
+type options struct {
+	Suffix          string  `json:"suffix"`
+	CaseInsensitive bool    `json:"case_insensitive"`
+	Backend         string  `json:"backend"`
+	Workers         int     `json:"workers"`
+	MaxAttempts     uint64  `json:"max_attempts"`
+	TimeoutSeconds  float64 `json:"timeout_seconds"`
+}
+
+type result struct {
+	Address    string `json:"address"`
+	PrivateKey string `json:"private_key"`
+	Attempts   uint64 `json:"attempts"`
+}
+
+//export vanity_search
+func vanity_search(prefix, opts *C.char, out *C.char, outLen C.size_t) C.int {
+	var o options
+	if opts != nil {
+		if s := C.GoString(opts); s != "" {
+			if err := json.Unmarshal([]byte(s), &o); err != nil {
+				return write(out, outLen, statusUsage, errorJSON(err))
+			}
+		}
+	}
+	var p string
+	if prefix != nil {
+		p = C.GoString(prefix)
+	}
+	res, status, err := search(p, o)
+	if err != nil {
+		return write(out, outLen, status, errorJSON(err))
+	}
+	b, _ := json.Marshal(res)
+	return write(out, outLen, statusOK, b)
+}
+
+func search(prefix string, o options) (result, int, error) {
+	vopts := []vanity.Option{
+		vanity.WithPrefix(prefix),
+		vanity.WithSuffix(o.Suffix),
+		vanity.WithCaseInsensitive(o.CaseInsensitive),
+		vanity.WithMaxAttempts(o.MaxAttempts),
+	}
+	if o.Backend != "" {
+		vopts = append(vopts, vanity.WithBackend(vanity.Backend(o.Backend)))
+	}
+	if o.Workers > 0 {
+		vopts = append(vopts, vanity.WithWorkers(o.Workers))
+	}
+	s, err := vanity.New(vopts...)
+	if err != nil {
+		if errors.Is(err, vanity.ErrInvalid) || errors.Is(err, vanity.ErrTooLongInvalid) || errors.Is(err, vanity.ErrNoPattern) {
+			return result{}, statusPattern, err
+		}
+		return result{}, statusUsage, err
+	}
+	ctx := context.Background()
+	if o.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(o.TimeoutSeconds*float64(time.Second)))
+		defer cancel()
+	}
+	res, err := s.Run(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return result{}, statusGaveUp, errors.New("timed out")
+	case errors.Is(err, vanity.ErrMaxAttempts):
+		return result{}, statusGaveUp, err
+	case err != nil:
+		return result{}, statusFailure, err
+	}
+	return result{res.Address.Hex(), hex.EncodeToString(crypto.FromECDSA(res.Key)), s.Attempts()}, statusOK, nil
+}
+
+func errorJSON(err error) []byte {
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	return b
+}
+
+// write copies b and a terminating NUL to out, which has room for outLen bytes, and returns status, or
+// statusShortBuffer if b does not fit.
+func write(out *C.char, outLen C.size_t, status int, b []byte) C.int {
+	if out == nil || int(outLen) < len(b)+1 {
+		return statusShortBuffer
+	}
+	dst := unsafe.Slice((*byte)(unsafe.Pointer(out)), int(outLen))
+	dst[copy(dst, b)] = 0
+	return C.int(status)
+}
+
+func main() {}
@@ -0,0 +1,44 @@
+/*
+ * vanity.h: the interface of libvanity, built with
+ *
+ *     go build -buildmode=c-shared -o libvanity.so ./cmd/libvanity
+ */
+#ifndef VANITY_H
+#define VANITY_H
+
+#include <stddef.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* status codes returned by vanity_search */
+#define VANITY_OK           0 /* out holds the result */
+#define VANITY_FAILURE      1 /* out holds {"error": "..."} */
+#define VANITY_USAGE        2 /* invalid options; out holds {"error": "..."} */
+#define VANITY_PATTERN      3 /* invalid pattern; out holds {"error": "..."} */
+#define VANITY_GAVE_UP      5 /* timed out or reached max_attempts; out holds {"error": "..."} */
+#define VANITY_SHORT_BUFFER 6 /* out was too small; nothing was written */
+
+/*
+ * vanity_search searches for a key whose address begins with prefix (hex, without 0x) and blocks until
+ * it finds one or gives up. opts is NULL or a JSON object with any of the fields
+ *
+ *     {"suffix": "", "case_insensitive": false, "backend": "geth", "workers": 0,
+ *      "max_attempts": 0, "timeout_seconds": 0}
+ *
+ * where zeros mean the defaults: one worker per CPU and no limits. the result, or an error, is written
+ * to out as a NUL-terminated JSON object of at most out_len bytes:
+ *
+ *     {"address": "0x...", "private_key": "<hex>", "attempts": 1234}
+ *
+ * a result always fits in 256 bytes; error messages may need more. it is safe to call from several
+ * threads at once.
+ */
+int vanity_search(const char *prefix, const char *opts, char *out, size_t out_len);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,144 @@
+//go:build js && wasm
+
+// Command wasm runs the search in a browser. it defines a global vanity object whose generate method
+// takes a prefix and an optional object of options and returns a Promise of the first match:
+//
+//	const {address, privateKey, attempts} = await vanity.generate("dead", {
+//		suffix: "",                // hex characters the address must end with
+//		caseInsensitive: true,
+//		backend: "geth",           // see vanity.backends
+//		maxAttempts: 0,            // 0 means no limit
+//		signal: controller.signal, // an AbortSignal that cancels the search
+//		onProgress: p => {},       // called about once a second with {attempts, rate, elapsed, bestScore}
+//	});
+//
+// Go code running under wasm shares the page's thread, so the search yields to the event loop every few
+// thousand attempts to keep the page responsive; running it in a Web Worker is smoother still. build it
+// with
+//
+//	GOOS=js GOARCH=wasm go build -o vanity.wasm ./cmd/wasm
+//
+// and load it with the wasm_exec.js that ships with the same Go release (in $(go env GOROOT)/lib/wasm,
+// or misc/wasm before Go 1.24).
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"syscall/js"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// yieldEvery is how many attempts the search makes between yields to the JavaScript event loop.
+const yieldEvery = 4096
+
+func main() {
+	backends := make([]any, len(vanity.Backends))
+	for i, b := range vanity.Backends {
+		backends[i] = map[string]any{"name": string(b.Name), "description": b.Description, "security": b.Security}
+	}
+	js.Global().Set("vanity", js.ValueOf(map[string]any{
+		"generate": js.FuncOf(generate),
+		"backends": backends,
+	}))
+	select {} // the functions must outlive main
+}
+
+// generate implements vanity.generate.
+func generate(_ js.Value, args []js.Value) any {
+	var prefix string
+	opts := js.Undefined()
+	if len(args) > 0 {
+		prefix = args[0].String()
+	}
+	if len(args) > 1 {
+		opts = args[1]
+	}
+	executor := js.FuncOf(func(_ js.Value, fns []js.Value) any {
+		resolve, reject := fns[0], fns[1]
+		go func() {
+			res, err := search(prefix, opts)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(res)
+		}()
+		return nil
+	})
+	defer executor.Release() // the Promise constructor calls it synchronously
+	return js.Global().Get("Promise").New(executor)
+}
+
+// search runs a search configured by the JavaScript options object opts and returns its result as a
+// value for JavaScript.
+func search(prefix string, opts js.Value) (map[string]any, error) {
+	get := func(name string) js.Value {
+		if opts.Type() != js.TypeObject {
+			return js.Undefined()
+		}
+		return opts.Get(name)
+	}
+	vopts := []vanity.Option{
+		vanity.WithPrefix(prefix),
+		vanity.WithWorkers(1), // there is only one thread
+		vanity.WithMilestones(yieldEvery, func(uint64) { time.Sleep(time.Millisecond) }),
+	}
+	if v := get("suffix"); v.Truthy() {
+		vopts = append(vopts, vanity.WithSuffix(v.String()))
+	}
+	if v := get("caseInsensitive"); v.Truthy() {
+		vopts = append(vopts, vanity.WithCaseInsensitive(true))
+	}
+	if v := get("backend"); v.Truthy() {
+		vopts = append(vopts, vanity.WithBackend(vanity.Backend(v.String())))
+	}
+	if v := get("maxAttempts"); v.Truthy() {
+		vopts = append(vopts, vanity.WithMaxAttempts(uint64(v.Float())))
+	}
+	if fn := get("onProgress"); fn.Type() == js.TypeFunction {
+		vopts = append(vopts, vanity.WithTrackBest(true), vanity.WithProgress(time.Second, func(p vanity.Progress) {
+			fn.Invoke(map[string]any{
+				"attempts":  float64(p.Attempts),
+				"rate":      p.Rate,
+				"elapsed":   p.Elapsed.Seconds(),
+				"bestScore": p.BestScore,
+			})
+		}))
+	}
+	s, err := vanity.New(vopts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if sig := get("signal"); sig.Truthy() {
+		if sig.Get("aborted").Bool() {
+			return nil, errors.New("aborted")
+		}
+		onAbort := js.FuncOf(func(js.Value, []js.Value) any {
+			cancel()
+			return nil
+		})
+		defer onAbort.Release()
+		sig.Call("addEventListener", "abort", onAbort)
+		defer sig.Call("removeEventListener", "abort", onAbort)
+	}
+
+	res, err := s.Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil, errors.New("aborted")
+	} else if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"address":    res.Address.Hex(),
+		"privateKey": hex.EncodeToString(crypto.FromECDSA(res.Key)),
+		"attempts":   float64(s.Attempts()),
+	}, nil
+}
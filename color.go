@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	ansiMatch = "\x1b[1;32m" // bold green
+	ansiDim   = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+// useColor reports whether output to f should be colored: f must be a terminal, and neither -no-color
+// nor the NO_COLOR environment variable (https://no-color.org) may be set.
+func useColor(f *os.File, noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(f)
+}
+
+// highlight returns the checksummed hex form of a with the characters that match prefix and suffix in
+// color and the rest dimmed. only the leading characters that match the prefix and the trailing ones
+// that match the suffix are colored, so partial matches are shown accurately too.
+func highlight(a common.Address, prefix, suffix string, insensitive bool) string {
+	h := a.Hex()
+	eq := func(x, y byte) bool {
+		if insensitive {
+			return strings.EqualFold(string(x), string(y))
+		}
+		return x == y
+	}
+	p := 0
+	for p < len(prefix) && eq(prefix[p], h[2+p]) {
+		p++
+	}
+	s := 0
+	for s < len(suffix) && len(h)-s-1 >= 2+p && eq(suffix[len(suffix)-1-s], h[len(h)-1-s]) {
+		s++
+	}
+
+	var b strings.Builder
+	b.WriteString(h[:2])
+	if p > 0 {
+		b.WriteString(ansiMatch + h[2:2+p] + ansiReset)
+	}
+	b.WriteString(ansiDim + h[2+p:len(h)-s] + ansiReset)
+	if s > 0 {
+		b.WriteString(ansiMatch + h[len(h)-s:] + ansiReset)
+	}
+	return b.String()
+}
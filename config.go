@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/naoina/toml"
+)
+
+// defaultConfigPath returns $VANITY_CONFIG if it is set, and otherwise vanity/config.toml in the user's
+// config directory (~/.config on Linux).
+func defaultConfigPath() string {
+	if p := os.Getenv("VANITY_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vanity", "config.toml")
+}
+
+// envName returns the environment variable that sets the flag with the given name, e.g. VANITY_MAX_ATTEMPTS
+// for -max-attempts.
+func envName(flagName string) string {
+	return "VANITY_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyDefaults sets each flag in set that was not given on the command line from its VANITY_* environment
+// variable or, failing that, from the top-level key of the same name in the TOML config file at path.
+// a missing config file is only an error if required is true. keys that set does not define are ignored,
+// since the same file configures every subcommand.
+func applyDefaults(set *flag.FlagSet, path string, required bool) error {
+	given := make(map[string]bool)
+	set.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var conf map[string]any
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err = toml.Unmarshal(b, &conf); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		case errors.Is(err, fs.ErrNotExist) && !required:
+		default:
+			return err
+		}
+	}
+
+	var err error
+	set.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if e := set.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), e)
+			}
+			return
+		}
+		if v, ok := conf[f.Name]; ok {
+			if _, isTable := v.(map[string]any); isTable {
+				return
+			}
+			if e := set.Set(f.Name, fmt.Sprint(v)); e != nil {
+				err = fmt.Errorf("%s: %s: %w", path, f.Name, e)
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// convert implements the convert subcommand, which rewrites a key file in another format.
+func convert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	var (
+		in       *string = fs.String("k", "priv.key", "path of the key file to convert: hex, keystore (v3 or eip2335) or mnemonic")
+		out      *string = fs.String("o", "", "output path (for -format foundry, defaults to ADDRESS in ~/.foundry/keystores)")
+		format   *string = fs.String("format", "hex", "output format: hex, eip2335, or foundry, a keystore for 'cast wallet' and 'forge script --account'")
+		kdf      *string = fs.String("kdf", "scrypt", "key derivation function for eip2335 keystores: scrypt or pbkdf2")
+		inPass   *string = fs.String("in-pass", "", "file containing the passphrase of an encrypted input key (defaults to $VANITY_PASSPHRASE), or the BIP-39 passphrase for mnemonics")
+		passFile *string = fs.String("pass", "", "file containing the passphrase used to encrypt the output (defaults to $VANITY_PASSPHRASE)")
+		hdPath   *string = fs.String("path", accounts.DefaultBaseDerivationPath.String(), "BIP-32 derivation path for mnemonics")
+		riskyOut *bool   = fs.Bool("risky-output", false, "write a hex key to a terminal, a directory every user can write to, or a network or cloud-synced folder without asking first")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s convert [flags]\n\nconverts a key file to another format.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *out == "" && *format != "foundry" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fatal(err)
+	}
+	key, err := loadKey(data, *inPass, *hdPath)
+	if err != nil {
+		fatal(err)
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	switch *format {
+	case "hex":
+		if err = confirmDestination(*out, *riskyOut, true); err != nil {
+			fatal(usageError{err})
+		}
+		err = saveHex(*out, key)
+	case "eip2335":
+		var pass []byte
+		if pass, err = readPassphrase(*passFile); err != nil {
+			fatal(err)
+		}
+		err = saveV4(*out, key, pass, *kdf, "vanity address "+addr.Hex())
+	case "foundry":
+		var pass []byte
+		if pass, err = readPassphrase(*passFile); err != nil {
+			fatal(err)
+		}
+		if *out == "" {
+			dir, err := foundryKeystores()
+			if err != nil {
+				fatal(err)
+			}
+			*out = filepath.Join(dir, addr.Hex())
+		}
+		if err = saveFoundry(*out, key, pass); err == nil {
+			fmt.Fprintf(os.Stderr, "use the key with --account %s\n", filepath.Base(*out))
+		}
+	default:
+		err = fmt.Errorf("unknown key format %q", *format)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(addr)
+	return exitOK
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// plaintext keys are only as safe as where they are written. destinationRisks finds the places that
+// give them away: a terminal, which scrollback and screen sharing keep, a directory that other users
+// can write to, where they can replace the file or read it if its mode allows, an existing file that
+// other users can read, whose mode os.WriteFile keeps, and network and cloud-synced folders, which copy
+// the key to machines and accounts that the user does not control. directories that other users can
+// merely list are not among them, as keys are written readable only by their owner.
+
+// syncedFolders are the folder names under which the common cloud storage clients sync files, in lower
+// case. a folder whose name begins with one of them, such as "OneDrive - Company", counts.
+var syncedFolders = []string{"dropbox", "google drive", "googledrive", "my drive", "onedrive", "icloud drive", "mobile documents", "cloudstorage", "box", "box sync", "pcloud", "mega", "megasync", "nextcloud", "owncloud", "tresorit", "yandex.disk"}
+
+// destinationRisks returns the reasons why writing a plaintext key to path is risky. templated parts of
+// path are not known yet, so only the directories above them are checked.
+func destinationRisks(path string) []string {
+	var risks []string
+	if !strings.ContainsAny(path, "{}") {
+		fi, err := os.Stat(path)
+		switch {
+		case err != nil:
+		case fi.Mode()&os.ModeCharDevice != 0:
+			// such as /dev/stdout or /dev/tty. pipes are not opened, which would wait for a reader
+			if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+				tty := isTerminal(f)
+				f.Close()
+				if tty {
+					return []string{"it is a terminal, whose scrollback keeps the key"}
+				}
+			}
+		case fi.Mode().IsRegular() && fi.Mode().Perm()&0044 != 0:
+			risks = append(risks, fmt.Sprintf("it already exists with mode %v, which the key would keep and other users can read", fi.Mode().Perm()))
+		}
+	}
+	dir := filepath.Dir(path)
+	for strings.ContainsAny(dir, "{}") {
+		dir = filepath.Dir(dir)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if fi, err := os.Stat(dir); err == nil && fi.Mode().Perm()&0002 != 0 {
+		risks = append(risks, fmt.Sprintf("%s can be written to by every user", dir))
+	}
+	if kind := networkFS(dir); kind != "" {
+		risks = append(risks, fmt.Sprintf("%s is on a network filesystem (%s)", dir, kind))
+	}
+	if d := syncedFolder(dir); d != "" {
+		risks = append(risks, fmt.Sprintf("%s looks like a cloud-synced folder", d))
+	}
+	return risks
+}
+
+// syncedFolder returns the first directory in dir, which must be absolute, that looks like a cloud
+// storage client's synced folder, or "" if there is none.
+func syncedFolder(dir string) string {
+	var parts []string
+	for d := dir; ; d = filepath.Dir(d) {
+		parts = append(parts, d)
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+	for i := len(parts) - 1; i >= 0; i-- {
+		name := strings.ToLower(filepath.Base(parts[i]))
+		for _, s := range syncedFolders {
+			if name == s || strings.HasPrefix(name, s+" ") || strings.HasPrefix(name, s+"-") {
+				return parts[i]
+			}
+		}
+	}
+	return ""
+}
+
+var errRiskyOutput = errors.New("not writing the key to a risky destination; pass -risky-output to write it there anyway")
+
+// confirmDestination checks where a plaintext key would be written to path. if it is risky, it asks
+// whether to go on when it can ask on a terminal, and otherwise fails unless allowed.
+func confirmDestination(path string, allowed, canAsk bool) error {
+	risks := destinationRisks(path)
+	if len(risks) == 0 || allowed {
+		for _, r := range risks {
+			slog.Warn("writing the key to a risky destination", "path", path, "risk", r)
+		}
+		return nil
+	}
+	if !canAsk || !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return fmt.Errorf("%w: %s: %s", errRiskyOutput, path, strings.Join(risks, "; "))
+	}
+	fmt.Fprintf(os.Stderr, "the key would be written to %s, which is risky:\n", path)
+	for _, r := range risks {
+		fmt.Fprintf(os.Stderr, "  - %s\n", r)
+	}
+	fmt.Fprint(os.Stderr, "write it there anyway? [y/N] ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(line)); a != "y" && a != "yes" {
+		return errRiskyOutput
+	}
+	return nil
+}
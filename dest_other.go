@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// network filesystems are only recognized on Linux, whose statfs reports the filesystem type.
+func networkFS(dir string) string {
+	return ""
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// successProbability returns the probability that at least one of n attempts succeeds when the
+// expected number of attempts is d.
+func successProbability(n, d float64) float64 {
+	return -math.Expm1(n * math.Log1p(-1/d))
+}
+
+// fmtSeconds formats a possibly astronomical number of seconds.
+func fmtSeconds(s float64) string {
+	const (
+		day  = 24 * 60 * 60
+		year = 365.25 * day
+	)
+	switch {
+	case math.IsInf(s, 0) || math.IsNaN(s):
+		return "forever"
+	case s >= 2*year:
+		return fmt.Sprintf("%.3g years", s/year)
+	case s >= 2*day:
+		return fmt.Sprintf("%.1f days", s/day)
+	case s >= 1:
+		return time.Duration(s * float64(time.Second)).Round(time.Second).String()
+	default:
+		return time.Duration(s * float64(time.Second)).Round(time.Millisecond).String()
+	}
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// a threshold key is generated with the distributed key generation of FROST (a Pedersen DKG with
+// proofs of knowledge), run as a ceremony over a directory the participants share. each of the n
+// participants picks a random polynomial of degree t-1, publishes commitments to its coefficients, and
+// sends every other participant the polynomial's value at their index, encrypted to them. a
+// participant's share is the sum of the values sent to it, any t shares determine the group key, the
+// sum of the polynomials' constant terms, and fewer reveal nothing about it.
+//
+// rather than run the ceremony again until the group key has a matching address, which would take as
+// many ceremonies as a search takes keys, the search is for an offset k such that the group public key
+// plus k*G has a matching address, as in a split-key search. k is public, and adding it to every share
+// adds it to the group key. the shares can be combined with 'dkg combine', or used by a threshold ECDSA
+// signer that takes Shamir shares; FROST's own signatures are Schnorr signatures, which Ethereum
+// accounts cannot use.
+
+// dkgState is a participant's secrets for a ceremony, kept until it has finished.
+type dkgState struct {
+	Index         int             `json:"index"`
+	Threshold     int             `json:"threshold"`
+	Participants  int             `json:"participants"`
+	Coefficients  []hexutil.Bytes `json:"coefficients"`
+	EncryptionKey hexutil.Bytes   `json:"encryption_key"` // the shares sent to this participant are encrypted to
+}
+
+// dkgCommitment is what a participant publishes first, as round1-INDEX.json.
+type dkgCommitment struct {
+	Index         int             `json:"index"`
+	Threshold     int             `json:"threshold"`
+	Participants  int             `json:"participants"`
+	Commitments   []hexutil.Bytes `json:"commitments"` // of the coefficients, compressed
+	Proof         hexutil.Bytes   `json:"proof"`       // signature by the constant term, proving it is known
+	EncryptionKey hexutil.Bytes   `json:"encryption_key"`
+}
+
+// dkgPacket is the value of one participant's polynomial at another's index, published encrypted as
+// share-FROM-TO.json.
+type dkgPacket struct {
+	From       int           `json:"from"`
+	To         int           `json:"to"`
+	Ciphertext hexutil.Bytes `json:"ciphertext"`
+}
+
+// dkgShare is a participant's share of the key, once the offset is added.
+type dkgShare struct {
+	Index          int           `json:"index"`
+	Threshold      int           `json:"threshold"`
+	Participants   int           `json:"participants"`
+	Address        string        `json:"address"`
+	GroupPublicKey hexutil.Bytes `json:"group_public_key"` // without the offset
+	Offset         hexutil.Bytes `json:"offset"`
+	Share          hexutil.Bytes `json:"share"`
+	PublicShare    hexutil.Bytes `json:"public_share"` // Share*G
+}
+
+func dkgRound1Path(dir string, i int) string {
+	return filepath.Join(dir, fmt.Sprintf("round1-%d.json", i))
+}
+
+func dkgPacketPath(dir string, from, to int) string {
+	return filepath.Join(dir, fmt.Sprintf("share-%d-%d.json", from, to))
+}
+
+// dkgProofHash is what a participant signs with its constant term, binding its commitments.
+func dkgProofHash(c *dkgCommitment) []byte {
+	var hdr [12]byte
+	binary.BigEndian.PutUint32(hdr[0:], uint32(c.Index))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(c.Threshold))
+	binary.BigEndian.PutUint32(hdr[8:], uint32(c.Participants))
+	parts := [][]byte{[]byte("vanity dkg commitment"), hdr[:], c.EncryptionKey}
+	for _, cm := range c.Commitments {
+		parts = append(parts, cm)
+	}
+	return crypto.Keccak256(parts...)
+}
+
+// dkgScalar decodes a scalar, which must be below the group order and not zero.
+func dkgScalar(b []byte) (secp256k1.ModNScalar, error) {
+	var s secp256k1.ModNScalar
+	if len(b) != 32 || s.SetByteSlice(b) || s.IsZero() {
+		return s, errors.New("invalid scalar")
+	}
+	return s, nil
+}
+
+func dkgPoint(b []byte) (secp256k1.JacobianPoint, error) {
+	var p secp256k1.JacobianPoint
+	k, err := secp256k1.ParsePubKey(b)
+	if err != nil {
+		return p, err
+	}
+	k.AsJacobian(&p)
+	return p, nil
+}
+
+func compressPoint(p secp256k1.JacobianPoint) []byte {
+	p.ToAffine()
+	return secp256k1.NewPublicKey(&p.X, &p.Y).SerializeCompressed()
+}
+
+func indexScalar(i int) secp256k1.ModNScalar {
+	var x secp256k1.ModNScalar
+	x.SetInt(uint32(i))
+	return x
+}
+
+// evalPolynomial returns the value at index i of the polynomial with coefficients a.
+func evalPolynomial(a []secp256k1.ModNScalar, i int) secp256k1.ModNScalar {
+	x := indexScalar(i)
+	r := a[len(a)-1]
+	for k := len(a) - 2; k >= 0; k-- {
+		r.Mul(&x).Add(&a[k])
+	}
+	return r
+}
+
+// evalCommitments returns the value at index i of the polynomial committed to by c, times G.
+func evalCommitments(c []secp256k1.JacobianPoint, i int) secp256k1.JacobianPoint {
+	x := indexScalar(i)
+	r := c[len(c)-1]
+	for k := len(c) - 2; k >= 0; k-- {
+		secp256k1.ScalarMultNonConst(&x, &r, &r)
+		secp256k1.AddNonConst(&r, &c[k], &r)
+	}
+	return r
+}
+
+// writeNew writes v to path as JSON, with perm, unless path exists.
+func writeNew(path string, v any, perm os.FileMode) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	defer clear(b)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	defer clear(b)
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// readCommitments reads and checks every participant's commitments for the ceremony that st is part of,
+// and returns them, by index from 1, and a fingerprint of them.
+func readCommitments(dir string, st *dkgState) ([][]secp256k1.JacobianPoint, []*ecdsa.PublicKey, string, error) {
+	points := make([][]secp256k1.JacobianPoint, st.Participants+1)
+	encKeys := make([]*ecdsa.PublicKey, st.Participants+1)
+	var all [][]byte
+	for i := 1; i <= st.Participants; i++ {
+		path := dkgRound1Path(dir, i)
+		var c dkgCommitment
+		if err := readJSON(path, &c); err != nil {
+			return nil, nil, "", err
+		}
+		if c.Index != i || c.Threshold != st.Threshold || c.Participants != st.Participants || len(c.Commitments) != st.Threshold {
+			return nil, nil, "", fmt.Errorf("%s is not for participant %d of a %d-of-%d ceremony", path, i, st.Threshold, st.Participants)
+		}
+		signer, err := crypto.SigToPub(dkgProofHash(&c), c.Proof)
+		if err != nil || !bytes.Equal(crypto.CompressPubkey(signer), c.Commitments[0]) {
+			return nil, nil, "", fmt.Errorf("%s: participant %d did not prove that it knows its secret", path, i)
+		}
+		if encKeys[i], err = crypto.DecompressPubkey(c.EncryptionKey); err != nil {
+			return nil, nil, "", fmt.Errorf("%s: encryption key: %w", path, err)
+		}
+		for _, cm := range c.Commitments {
+			p, err := dkgPoint(cm)
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("%s: %w", path, err)
+			}
+			points[i] = append(points[i], p)
+			all = append(all, cm)
+		}
+		all = append(all, c.EncryptionKey)
+	}
+	fp := crypto.Keccak256(all...)
+	return points, encKeys, fmt.Sprintf("%x-%x-%x", fp[0:2], fp[2:4], fp[4:6]), nil
+}
+
+func (st *dkgState) coefficients() ([]secp256k1.ModNScalar, error) {
+	a := make([]secp256k1.ModNScalar, len(st.Coefficients))
+	for k, b := range st.Coefficients {
+		var err error
+		if a[k], err = dkgScalar(b); err != nil {
+			return nil, fmt.Errorf("coefficient %d: %w", k, err)
+		}
+	}
+	return a, nil
+}
+
+func readDKGState(path string) (*dkgState, error) {
+	var st dkgState
+	if err := readJSON(path, &st); err != nil {
+		return nil, err
+	}
+	if st.Index < 1 || st.Index > st.Participants || st.Threshold < 1 || st.Threshold > st.Participants || len(st.Coefficients) != st.Threshold {
+		return nil, fmt.Errorf("%s is not a valid ceremony state", path)
+	}
+	return &st, nil
+}
+
+// dkg implements the dkg subcommand, whose own subcommands are the steps of a threshold key ceremony.
+func dkg(args []string) int {
+	steps := []struct {
+		name, desc string
+		run        func(args []string) int
+	}{
+		{"start", "make this participant's secrets and publish commitments to them", dkgStart},
+		{"shares", "once every participant has started, publish the encrypted shares for the others", dkgShares},
+		{"finish", "once every participant has published its shares, compute this participant's share", dkgFinish},
+		{"combine", "combine threshold many shares into the key", dkgCombine},
+	}
+	if len(args) > 0 {
+		for _, s := range steps {
+			if s.name == args[0] {
+				return s.run(args[1:])
+			}
+		}
+	}
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "usage: %s dkg <step> [flags]\n\ngenerates a key shared by n participants, any t of whom can use it, whose address matches a\npattern, without any of them holding the key. the participants run each step in turn, publishing\nfiles to a directory they share, such as a shared folder or repository.\n\nsteps:\n", os.Args[0])
+	for _, s := range steps {
+		fmt.Fprintf(w, "  %-8s %s\n", s.name, s.desc)
+	}
+	return exitUsage
+}
+
+func dkgStart(args []string) int {
+	fs := flag.NewFlagSet("dkg start", flag.ExitOnError)
+	var (
+		dir       *string = fs.String("dir", ".", "directory the participants share")
+		index     *int    = fs.Int("index", 0, "this participant's index, from 1 to -n")
+		threshold *int    = fs.Int("t", 0, "number of participants needed to use the key")
+		n         *int    = fs.Int("n", 0, "number of participants")
+		statePath *string = fs.String("state", "dkg.state", "path to keep this participant's secrets at until the ceremony is finished; it must not be shared")
+	)
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *n < 2 || *threshold < 1 || *threshold > *n || *index < 1 || *index > *n {
+		fatal(usageError{errors.New("give -n of at least 2, -t from 1 to -n and -index from 1 to -n")})
+	}
+	st := &dkgState{Index: *index, Threshold: *threshold, Participants: *n}
+	c := &dkgCommitment{Index: *index, Threshold: *threshold, Participants: *n}
+	var a0 *ecdsa.PrivateKey
+	for range *threshold {
+		k, err := crypto.GenerateKey()
+		if err != nil {
+			fatal(err)
+		}
+		if a0 == nil {
+			a0 = k
+		} else {
+			defer vanity.ZeroKey(k)
+		}
+		st.Coefficients = append(st.Coefficients, crypto.FromECDSA(k))
+		c.Commitments = append(c.Commitments, crypto.CompressPubkey(&k.PublicKey))
+	}
+	defer vanity.ZeroKey(a0)
+	enc, err := crypto.GenerateKey()
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(enc)
+	st.EncryptionKey = crypto.FromECDSA(enc)
+	c.EncryptionKey = crypto.CompressPubkey(&enc.PublicKey)
+	if c.Proof, err = crypto.Sign(dkgProofHash(c), a0); err != nil {
+		fatal(err)
+	}
+	defer func() {
+		for _, b := range st.Coefficients {
+			clear(b)
+		}
+		clear(st.EncryptionKey)
+	}()
+	if err = writeNew(*statePath, st, 0600); err != nil {
+		fatal(err)
+	}
+	if err = writeNew(dkgRound1Path(*dir, *index), c, 0644); err != nil {
+		fatal(err)
+	}
+	slog.Info("published the commitments", "path", dkgRound1Path(*dir, *index), "next", "dkg shares, once every participant has started")
+	return exitOK
+}
+
+func dkgShares(args []string) int {
+	fs := flag.NewFlagSet("dkg shares", flag.ExitOnError)
+	var (
+		dir       *string = fs.String("dir", ".", "directory the participants share")
+		statePath *string = fs.String("state", "dkg.state", "path of this participant's secrets, from dkg start")
+	)
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	st, err := readDKGState(*statePath)
+	if err != nil {
+		fatal(err)
+	}
+	_, encKeys, fp, err := readCommitments(*dir, st)
+	if err != nil {
+		fatal(err)
+	}
+	a, err := st.coefficients()
+	if err != nil {
+		fatal(err)
+	}
+	defer func() {
+		for k := range a {
+			a[k].Zero()
+		}
+	}()
+	for j := 1; j <= st.Participants; j++ {
+		if j == st.Index {
+			continue
+		}
+		v := evalPolynomial(a, j)
+		b := v.Bytes()
+		v.Zero()
+		ct, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(encKeys[j]), b[:], nil, packetLabel(st.Index, j))
+		clear(b[:])
+		if err != nil {
+			fatal(err)
+		}
+		if err = writeNew(dkgPacketPath(*dir, st.Index, j), dkgPacket{st.Index, j, ct}, 0644); err != nil {
+			fatal(err)
+		}
+	}
+	slog.Info("published the shares; check that every participant sees the same fingerprint", "fingerprint", fp, "next", "dkg finish, once every participant has published its shares")
+	return exitOK
+}
+
+// packetLabel binds a packet's ciphertext to its sender and recipient.
+func packetLabel(from, to int) []byte { return fmt.Appendf(nil, "vanity dkg share %d to %d", from, to) }
+
+func dkgFinish(args []string) int {
+	fs := flag.NewFlagSet("dkg finish", flag.ExitOnError)
+	var (
+		dir         *string = fs.String("dir", ".", "directory the participants share")
+		statePath   *string = fs.String("state", "dkg.state", "path of this participant's secrets, from dkg start")
+		prefix      *string = fs.String("p", "", "output address prefix (excluding 0x)")
+		suffix      *string = fs.String("s", "", "output address suffix")
+		insensitive *bool   = fs.Bool("i", false, "accept case-insensitive solutions")
+		offsetHex   *string = fs.String("offset", "", "offset found by the participant who searched, in hex; without it, this participant searches for one and prints it for the others")
+		out         *string = fs.String("o", "dkg.share", "output path of this participant's share")
+		workers     *int    = fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines")
+		long        *bool   = fs.Bool("l", false, "accept long prefixes")
+	)
+	var timeOut timeoutFlag
+	// not -t, which is the threshold in 'dkg start'
+	fs.Var(&timeOut, "timeout", "maximum acceptable search time for the offset, as a duration (90m, 2h30m) or a number of seconds")
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if err := vanity.ValidatePattern(*prefix + *suffix); err != nil {
+		fatal(err)
+	}
+	if *offsetHex == "" && !*long && timeOut == 0 {
+		// split-key searches walk from the offset, as the walk backend does
+		err := expectLong(vanity.Difficulty(*prefix, *suffix, !*insensitive), vanity.Walk, *workers, *insensitive)
+		if errors.Is(err, vanity.ErrTooLong) {
+			err = fmt.Errorf("%w; re-run with the -l flag or set a timeout with the -timeout flag if you wish to continue", err)
+		}
+		if err != nil {
+			fatal(err)
+		}
+	}
+	st, err := readDKGState(*statePath)
+	if err != nil {
+		fatal(err)
+	}
+	points, _, fp, err := readCommitments(*dir, st)
+	if err != nil {
+		fatal(err)
+	}
+	a, err := st.coefficients()
+	if err != nil {
+		fatal(err)
+	}
+	encKey, err := crypto.ToECDSA(st.EncryptionKey)
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(encKey)
+	dec := ecies.ImportECDSA(encKey)
+
+	// the share is the sum of every polynomial at this index, each checked against its commitments
+	share := evalPolynomial(a, st.Index)
+	defer share.Zero()
+	for k := range a {
+		a[k].Zero()
+	}
+	group := points[1][0]
+	for i := 1; i <= st.Participants; i++ {
+		if i > 1 {
+			secp256k1.AddNonConst(&group, &points[i][0], &group)
+		}
+		if i == st.Index {
+			continue
+		}
+		path := dkgPacketPath(*dir, i, st.Index)
+		var pkt dkgPacket
+		if err = readJSON(path, &pkt); err != nil {
+			fatal(err)
+		}
+		b, err := dec.Decrypt(pkt.Ciphertext, nil, packetLabel(i, st.Index))
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", path, err))
+		}
+		v, err := dkgScalar(b)
+		clear(b)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", path, err))
+		}
+		var got secp256k1.JacobianPoint
+		secp256k1.ScalarBaseMultNonConst(&v, &got)
+		if want := evalCommitments(points[i], st.Index); !bytes.Equal(compressPoint(got), compressPoint(want)) {
+			fatal(fmt.Errorf("%s: participant %d sent a share that does not match its commitments", path, i))
+		}
+		share.Add(&v)
+		v.Zero()
+	}
+	slog.Info("check that every participant sees the same fingerprint", "fingerprint", fp)
+	groupPub, err := crypto.DecompressPubkey(compressPoint(group))
+	if err != nil {
+		fatal(err)
+	}
+	search, err := splitSearch(groupPub, *prefix, *suffix, *insensitive, *workers)
+	if err != nil {
+		fatal(err)
+	}
+	var offset []byte
+	if *offsetHex != "" {
+		if offset, err = hexutil.Decode("0x" + strings.TrimPrefix(*offsetHex, "0x")); err != nil {
+			fatal(usageError{fmt.Errorf("invalid -offset: %w", err)})
+		}
+	} else {
+		slog.Info("searching for an offset", "prefix", *prefix, "suffix", *suffix)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		if timeOut > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(timeOut))
+			defer cancel()
+		}
+		res, err := search.Run(ctx)
+		stop()
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Error("timed out", "attempts", search.Attempts())
+			return exitGaveUp
+		} else if errors.Is(err, context.Canceled) {
+			slog.Info("interrupted", "attempts", search.Attempts())
+			return exitInterrupted
+		} else if err != nil {
+			fatal(err)
+		}
+		offset = make([]byte, 32)
+		res.Key.D.FillBytes(offset)
+		vanity.ZeroKey(res.Key)
+	}
+	addr, err := checkOffset(search, groupPub, offset)
+	if err != nil {
+		fatal(err)
+	}
+	k, err := dkgScalar(offset)
+	if err != nil {
+		fatal(usageError{fmt.Errorf("invalid -offset: %w", err)})
+	}
+	share.Add(&k)
+	sb := share.Bytes()
+	defer clear(sb[:])
+	var pubShare secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&share, &pubShare)
+	s := dkgShare{
+		Index:          st.Index,
+		Threshold:      st.Threshold,
+		Participants:   st.Participants,
+		Address:        addr.Hex(),
+		GroupPublicKey: compressPoint(group),
+		Offset:         offset,
+		Share:          sb[:],
+		PublicShare:    compressPoint(pubShare),
+	}
+	if err = writeNew(*out, s, 0600); err != nil {
+		fatal(err)
+	}
+	fmt.Println(addr.Hex())
+	if *offsetHex == "" {
+		slog.Info("found an offset; give it to the other participants", "offset", hexutil.Encode(offset), "next", fmt.Sprintf("dkg finish -offset %x", offset))
+	}
+	slog.Info("saved this participant's share; the ceremony state is no longer needed", "path", *out, "state", *statePath)
+	return exitOK
+}
+
+// combineDKGShares interpolates shares, which must have distinct indices, at 0, and returns the key
+// they share. the shares' scalars are cleared.
+func combineDKGShares(shares []dkgShare) (*ecdsa.PrivateKey, error) {
+	indices := make([]int, len(shares))
+	for k, s := range shares {
+		indices[k] = s.Index
+	}
+	var d secp256k1.ModNScalar
+	defer d.Zero()
+	for _, s := range shares {
+		v, err := dkgScalar(s.Share)
+		clear(s.Share)
+		if err != nil {
+			return nil, fmt.Errorf("participant %d's share: %w", s.Index, err)
+		}
+		l := lagrangeAtZero(s.Index, indices)
+		d.Add(v.Mul(&l))
+		v.Zero()
+	}
+	b := d.Bytes()
+	defer clear(b[:])
+	return crypto.ToECDSA(b[:])
+}
+
+// lagrangeAtZero returns the Lagrange coefficient of index i for interpolating at 0 from indices.
+func lagrangeAtZero(i int, indices []int) secp256k1.ModNScalar {
+	var num, den secp256k1.ModNScalar
+	num.SetInt(1)
+	den.SetInt(1)
+	xi := indexScalar(i)
+	for _, j := range indices {
+		if j == i {
+			continue
+		}
+		xj := indexScalar(j)
+		num.Mul(&xj)
+		var d secp256k1.ModNScalar
+		d.NegateVal(&xi).Add(&xj) // j - i
+		den.Mul(&d)
+	}
+	return *num.Mul(den.InverseNonConst())
+}
+
+func dkgCombine(args []string) int {
+	fs := flag.NewFlagSet("dkg combine", flag.ExitOnError)
+	var (
+		out      *string = fs.String("o", "priv.key", "output path of the combined key, in hex")
+		riskyOut *bool   = fs.Bool("risky-output", false, "write the key to a terminal, a directory every user can write to, or a network or cloud-synced folder without asking first")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s dkg combine [flags] SHARE...\n\ncombines threshold many shares from dkg finish into the key they share.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	var (
+		shares  []dkgShare
+		indices []int
+	)
+	for _, path := range fs.Args() {
+		var s dkgShare
+		if err := readJSON(path, &s); err != nil {
+			fatal(err)
+		}
+		if len(shares) > 0 && (s.Address != shares[0].Address || s.Threshold != shares[0].Threshold) {
+			fatal(fmt.Errorf("%s is a share of a different key", path))
+		}
+		for _, i := range indices {
+			if i == s.Index {
+				fatal(fmt.Errorf("%s: more than one share of participant %d", path, i))
+			}
+		}
+		shares, indices = append(shares, s), append(indices, s.Index)
+	}
+	if len(shares) < shares[0].Threshold {
+		fatal(usageError{fmt.Errorf("the key needs %d shares, not %d", shares[0].Threshold, len(shares))})
+	}
+	key, err := combineDKGShares(shares[:shares[0].Threshold])
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(key)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	if addr != common.HexToAddress(shares[0].Address) {
+		fatal(fmt.Errorf("the shares combine to %s, not %s", addr.Hex(), shares[0].Address))
+	}
+	if err = confirmDestination(*out, *riskyOut, true); err != nil {
+		fatal(usageError{err})
+	}
+	if err = saveHex(*out, key); err != nil {
+		fatal(err)
+	}
+	fmt.Println(addr.Hex())
+	return exitOK
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// subsets returns every subset of {1, ..., n} with k members.
+func subsets(n, k int) [][]int {
+	if k == 0 {
+		return [][]int{nil}
+	}
+	var all [][]int
+	for last := k; last <= n; last++ {
+		for _, s := range subsets(last-1, k-1) {
+			all = append(all, append(s, last))
+		}
+	}
+	return all
+}
+
+func randomScalar(t *testing.T) secp256k1.ModNScalar {
+	t.Helper()
+	k, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k.Key
+}
+
+// TestDKGCombine runs the arithmetic of a ceremony, as dkg finish does, and checks that every subset of
+// threshold many shares combines to the group key plus the offset, and that fewer do not.
+func TestDKGCombine(t *testing.T) {
+	tests := []struct{ threshold, participants int }{
+		{1, 2}, {2, 2}, {2, 3}, {3, 5}, {4, 4}, {3, 7},
+	}
+	for _, tt := range tests {
+		// each participant's polynomial and its commitments; the group key is the sum of the constant terms
+		var (
+			group    secp256k1.ModNScalar
+			groupPub secp256k1.JacobianPoint
+			polys    [][]secp256k1.ModNScalar
+			commits  [][]secp256k1.JacobianPoint
+		)
+		for i := range tt.participants {
+			a := make([]secp256k1.ModNScalar, tt.threshold)
+			c := make([]secp256k1.JacobianPoint, tt.threshold)
+			for k := range a {
+				a[k] = randomScalar(t)
+				secp256k1.ScalarBaseMultNonConst(&a[k], &c[k])
+			}
+			group.Add(&a[0])
+			if i == 0 {
+				groupPub = c[0]
+			} else {
+				secp256k1.AddNonConst(&groupPub, &c[0], &groupPub)
+			}
+			polys, commits = append(polys, a), append(commits, c)
+		}
+		offset := make([]byte, 32)
+		if _, err := rand.Read(offset); err != nil {
+			t.Fatal(err)
+		}
+		k, err := dkgScalar(offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := crypto.DecompressPubkey(compressPoint(groupPub))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := vanity.SplitKeyAddress(pub, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shares := make([]secp256k1.ModNScalar, tt.participants+1)
+		for i := 1; i <= tt.participants; i++ {
+			for p := range polys {
+				v := evalPolynomial(polys[p], i)
+				var got secp256k1.JacobianPoint
+				secp256k1.ScalarBaseMultNonConst(&v, &got)
+				if want := evalCommitments(commits[p], i); !bytes.Equal(compressPoint(got), compressPoint(want)) {
+					t.Fatalf("%d-of-%d: participant %d's value for %d does not match its commitments", tt.threshold, tt.participants, p+1, i)
+				}
+				shares[i].Add(&v)
+			}
+			shares[i].Add(&k)
+		}
+		combine := func(indices []int) (string, error) {
+			var ss []dkgShare
+			for _, i := range indices {
+				b := shares[i].Bytes()
+				ss = append(ss, dkgShare{Index: i, Share: b[:]})
+			}
+			key, err := combineDKGShares(ss)
+			if err != nil {
+				return "", err
+			}
+			return crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
+		}
+		for _, indices := range subsets(tt.participants, tt.threshold) {
+			got, err := combine(indices)
+			if err != nil {
+				t.Fatalf("%d-of-%d, shares %v: %v", tt.threshold, tt.participants, indices, err)
+			}
+			if got != want.Hex() {
+				t.Errorf("%d-of-%d, shares %v: combined to %s, want %s", tt.threshold, tt.participants, indices, got, want.Hex())
+			}
+		}
+		if tt.threshold > 1 {
+			for _, indices := range subsets(tt.participants, tt.threshold-1) {
+				if got, _ := combine(indices); got == want.Hex() {
+					t.Errorf("%d-of-%d, shares %v: fewer than the threshold combined to the key", tt.threshold, tt.participants, indices)
+				}
+			}
+		}
+	}
+}
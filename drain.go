@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// healthz serves GET /healthz, which succeeds for as long as the server is up.
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"}, nil)
+}
+
+// readyz serves GET /readyz, which fails once the server has begun to drain, so that load balancers stop
+// sending it jobs.
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"}, nil)
+		return
+	}
+	var queued, running int
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		switch j.status {
+		case jobQueued:
+			queued++
+		case jobRunning:
+			running++
+		}
+		j.mu.Unlock()
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ready", "queued": queued, "running": running}, nil)
+}
+
+// unfinished returns the number of jobs that are queued or running.
+func (s *server) unfinished() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		if j.status == jobQueued || j.status == jobRunning {
+			n++
+		}
+		j.mu.Unlock()
+	}
+	return n
+}
+
+// serverState is what -state keeps of a server across restarts: its jobs, whether finished or not, and
+// its tenants' CPU time.
+type serverState struct {
+	Jobs  []savedJob               `json:"jobs"`
+	Usage map[string]time.Duration `json:"usage,omitempty"`
+}
+
+// savedJob is a job as kept in the -state file. results stay sealed, so the file reveals no keys, but
+// unfinished jobs keep the keys their results will be sealed with.
+type savedJob struct {
+	ID       string     `json:"id"`
+	Tenant   string     `json:"tenant,omitempty"`
+	Request  jobRequest `json:"request"`
+	Created  time.Time  `json:"created"`
+	Status   string     `json:"status"`
+	Elapsed  float64    `json:"elapsed"` // seconds spent running
+	Attempts uint64     `json:"attempts"`
+	Address  string     `json:"address,omitempty"`
+	Sealed   []byte     `json:"sealed,omitempty"`
+	Key      []byte     `json:"key,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// save writes every job the server remembers to path. it must only be called once no jobs are running.
+func (s *server) save(path string) error {
+	s.mu.Lock()
+	st := serverState{Jobs: make([]savedJob, 0, len(s.order)), Usage: s.usage}
+	for _, id := range s.order {
+		j := s.jobs[id]
+		j.mu.Lock()
+		sj := savedJob{
+			ID:       j.id,
+			Tenant:   j.tenant,
+			Request:  j.req,
+			Created:  j.created,
+			Status:   j.status,
+			Attempts: j.search.Attempts(),
+			Sealed:   j.sealed,
+			Key:      j.key,
+			Error:    j.err,
+		}
+		if !j.started.IsZero() && !j.finished.IsZero() {
+			sj.Elapsed = j.finished.Sub(j.started).Seconds()
+		}
+		if j.result != nil {
+			sj.Address = j.result.Address
+		}
+		j.mu.Unlock()
+		st.Jobs = append(st.Jobs, sj)
+	}
+	s.mu.Unlock()
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, append(b, '\n'))
+}
+
+// restore loads the jobs saved at path, if it exists, queueing the unfinished ones to run again. their
+// attempts carry over and their timeouts are reduced by the time they have already run.
+func (s *server) restore(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var st serverState
+	if err = json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	for t, d := range st.Usage {
+		s.usage[t] = d
+	}
+	var requeued int
+	for _, sj := range st.Jobs {
+		req := sj.Request
+		if sj.Status == jobQueued && req.TimeoutSeconds > 0 {
+			req.TimeoutSeconds = max(req.TimeoutSeconds-sj.Elapsed, 1e-3)
+		}
+		j, err := s.newJob(req, caller{tenant: sj.Tenant})
+		if err != nil {
+			slog.Warn("dropping saved job", "id", sj.ID, "err", err)
+			continue
+		}
+		j.id, j.created, j.status, j.err, j.sealed, j.key = sj.ID, sj.Created, sj.Status, sj.Error, sj.Sealed, sj.Key
+		j.search.AddAttempts(sj.Attempts)
+		if sj.Address != "" {
+			j.result = &jobResult{Address: sj.Address}
+		}
+		if j.status == jobQueued {
+			select {
+			case s.queue <- j:
+				requeued++
+			default:
+				slog.Warn("dropping saved job; the queue is full", "id", sj.ID)
+				continue
+			}
+		} else {
+			close(j.done)
+		}
+		s.jobs[j.id] = j
+		s.order = append(s.order, j.id)
+	}
+	slog.Info("restored jobs", "path", path, "jobs", len(s.order), "queued", requeued)
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// paymentURI returns the EIP-681 URI that asks a wallet to pay addr, on the chain with chainID if it is
+// not 0. wallets open it from a link, or from a QR code of it.
+// see https://eips.ethereum.org/EIPS/eip-681
+func paymentURI(addr common.Address, chainID uint64) string {
+	if chainID == 0 {
+		return "ethereum:" + addr.Hex()
+	}
+	return fmt.Sprintf("ethereum:%s@%d", addr.Hex(), chainID)
+}
+
+// writeURIQR writes a PNG QR code of uri to path.
+func writeURIQR(path, uri string) error {
+	png, err := qrPNG([]byte(uri))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, png, 0644)
+}
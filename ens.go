@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// an ENS name's reverse record is what wallets show for an address. the address claims it itself, by
+// calling setName on the reverse registrar, which sets the record of ADDRESS.addr.reverse, in lower-case
+// hex, to the name. wallets only show the name once its forward address record points back to the
+// address, which is set by the name's owner, often not the new address.
+
+// reverseRegistrars are the ENS reverse registrars, by chain ID.
+var reverseRegistrars = map[uint64]common.Address{
+	1: common.HexToAddress("0xa58E81fe9b61B5c3fE2AFD33CF304c454AbFc7Cb"),
+}
+
+// unsignedTx is a transaction for a wallet or signer to sign and send, in the form eth_sendTransaction
+// takes. those with no nonce or fees are for the wallet to fill them in.
+type unsignedTx struct {
+	Description          string          `json:"description"`
+	Type                 *hexutil.Uint64 `json:"type,omitempty"`
+	From                 common.Address  `json:"from"`
+	To                   common.Address  `json:"to"`
+	Value                *hexutil.Big    `json:"value"`
+	Data                 hexutil.Bytes   `json:"data"`
+	Nonce                *hexutil.Uint64 `json:"nonce,omitempty"`
+	Gas                  *hexutil.Uint64 `json:"gas,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// ensTemplate is what the ens subcommand writes.
+type ensTemplate struct {
+	Name         string       `json:"name"`
+	Address      string       `json:"address"`
+	Transactions []unsignedTx `json:"transactions"`
+	Cast         string       `json:"cast"`   // the same transaction with Foundry's cast
+	Ethers       string       `json:"ethers"` // and with ethers.js, given a signer for the address
+	Notes        []string     `json:"notes"`
+}
+
+var setNameArgs = abi.Arguments{{Type: mustType("string")}}
+
+func mustType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// checkENSName checks that name looks like an ENS name. full ENSIP-15 normalization is left to the
+// ENS app; this catches names that could never be normalized, such as ones with spaces or capitals.
+func checkENSName(name string) error {
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("%q is not an ENS name such as name.eth", name)
+	}
+	for _, l := range labels {
+		switch {
+		case l == "":
+			return fmt.Errorf("%q has an empty label", name)
+		case strings.ContainsAny(l, " \t\n/\\"):
+			return fmt.Errorf("%q contains spaces or slashes", name)
+		case l != strings.ToLower(l):
+			return fmt.Errorf("%q is not normalized: ENS names are in lower case", name)
+		}
+	}
+	return nil
+}
+
+// reverseTemplate returns the transaction, sent from addr on the chain with chainID through registrar,
+// that sets addr's reverse record to name.
+func reverseTemplate(addr, registrar common.Address, name string, chainID uint64) (*ensTemplate, error) {
+	args, err := setNameArgs.Pack(name)
+	if err != nil {
+		return nil, err
+	}
+	data := append(crypto.Keccak256([]byte("setName(string)"))[:4], args...)
+	id := new(big.Int).SetUint64(chainID)
+	t := &ensTemplate{
+		Name:    name,
+		Address: addr.Hex(),
+		Transactions: []unsignedTx{{
+			Description: fmt.Sprintf("set the reverse record of %s to %s", addr.Hex(), name),
+			From:        addr,
+			To:          registrar,
+			Value:       (*hexutil.Big)(new(big.Int)),
+			Data:        data,
+			ChainID:     (*hexutil.Big)(id),
+		}},
+		Cast:   fmt.Sprintf("cast send %s 'setName(string)' %s --from %s --chain %d --rpc-url \"$ETH_RPC_URL\"", registrar.Hex(), shellQuote(name), addr.Hex(), chainID),
+		Ethers: fmt.Sprintf("await new ethers.Contract(%q, [\"function setName(string) returns (bytes32)\"], signer).setName(%q)", registrar.Hex(), name),
+		Notes: []string{
+			fmt.Sprintf("the transaction must be sent from %s, which needs ETH for gas", addr.Hex()),
+			fmt.Sprintf("wallets only show %s for the address once its address record points to %s; its owner can set that in the ENS app", name, addr.Hex()),
+		},
+	}
+	return t, nil
+}
+
+// ens implements the ens subcommand, which writes the unsigned transaction that claims an ENS name's
+// reverse record for an address, so that wallets show the name for it.
+func ens(args []string) int {
+	fs := flag.NewFlagSet("ens", flag.ExitOnError)
+	var (
+		name      *string = fs.String("name", "", "ENS name to set as the address's reverse record, such as name.eth")
+		address   *string = fs.String("a", "", "address to claim the reverse record for")
+		keyPath   *string = fs.String("k", "", "or the key file of that address: hex, keystore (v3 or eip2335) or mnemonic")
+		inPass    *string = fs.String("in-pass", "", "file containing the passphrase of an encrypted key file (defaults to $VANITY_PASSPHRASE), or the BIP-39 passphrase for mnemonics")
+		hdPath    *string = fs.String("path", accounts.DefaultBaseDerivationPath.String(), "BIP-32 derivation path for mnemonics")
+		chainID   *uint64 = fs.Uint64("chain-id", 1, "ID of the chain the transaction is for")
+		registrar *string = fs.String("registrar", "", "address of the reverse registrar (default: ENS's, on mainnet)")
+		out       *string = fs.String("o", "", "write the template to this file instead of stdout")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s ens [flags]\n\nwrites the unsigned transaction, and cast and ethers.js equivalents, that sets an address's ENS\nreverse record. it can be run for each key found with generate -on-success '%[1]s ens -a {addr} -name NAME'.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *name == "" || (*address == "") == (*keyPath == "") {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := checkENSName(*name); err != nil {
+		fatal(usageError{err})
+	}
+
+	var addr common.Address
+	if *address != "" {
+		if !common.IsHexAddress(*address) {
+			fatal(usageError{fmt.Errorf("invalid address %q", *address)})
+		}
+		addr = common.HexToAddress(*address)
+	} else {
+		data, err := os.ReadFile(*keyPath)
+		if err != nil {
+			fatal(err)
+		}
+		key, err := loadKey(data, *inPass, *hdPath)
+		clear(data)
+		if err != nil {
+			fatal(err)
+		}
+		addr = crypto.PubkeyToAddress(key.PublicKey)
+		vanity.ZeroKey(key)
+	}
+	reg, ok := reverseRegistrars[*chainID]
+	switch {
+	case *registrar != "" && !common.IsHexAddress(*registrar):
+		fatal(usageError{fmt.Errorf("invalid registrar address %q", *registrar)})
+	case *registrar != "":
+		reg = common.HexToAddress(*registrar)
+	case !ok:
+		fatal(usageError{fmt.Errorf("no reverse registrar is known for chain %d; give its address with -registrar", *chainID)})
+	}
+
+	t, err := reverseTemplate(addr, reg, *name, *chainID)
+	if err != nil {
+		fatal(err)
+	}
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	b = append(b, '\n')
+	if *out == "" {
+		os.Stdout.Write(b)
+	} else if err = os.WriteFile(*out, b, 0644); err != nil {
+		fatal(err)
+	}
+	return exitOK
+}
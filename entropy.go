@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log/slog"
+	"runtime"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// checkEntropy checks crypto/rand before any key is generated with it, and warns of setups known to
+// seed it poorly. a key found with bad entropy is worse than no key, so a failed check is fatal.
+func checkEntropy() error {
+	if err := vanity.CheckEntropy(); err != nil {
+		return err
+	}
+	if w := entropyWarning(); w != "" {
+		slog.Warn(w)
+	}
+	return nil
+}
+
+// entropySource names where crypto/rand takes its data from on this platform, for result metadata.
+func entropySource() string {
+	switch runtime.GOOS {
+	case "linux", "android", "freebsd", "dragonfly", "solaris", "illumos":
+		return "crypto/rand (getrandom)"
+	case "darwin", "ios", "openbsd":
+		return "crypto/rand (arc4random_buf)"
+	case "netbsd":
+		return "crypto/rand (kern.arandom)"
+	case "windows":
+		return "crypto/rand (ProcessPrng)"
+	case "js", "wasip1":
+		return "crypto/rand (the host's)"
+	}
+	return "crypto/rand"
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// entropyWarning returns a warning if the system is a virtual machine with no hardware random number
+// generator: neither RDRAND nor RDSEED passed through from the CPU, nor a virtio-rng device. the
+// kernel then seeds itself from timings that a VM's host controls and that clones of the same image
+// share, which has produced duplicate keys in practice.
+func entropyWarning() string {
+	b, _ := os.ReadFile("/proc/cpuinfo")
+	var flags []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == "flags" {
+			flags = strings.Fields(v)
+			break
+		}
+	}
+	if !slices.Contains(flags, "hypervisor") || slices.Contains(flags, "rdrand") || slices.Contains(flags, "rdseed") {
+		return ""
+	}
+	if rng := readSysfs("/sys/class/misc/hw_random/rng_current"); rng != "" && rng != "none" {
+		return ""
+	}
+	return "running in a virtual machine with no hardware random number generator; make sure it is given one, such as virtio-rng, or its host seeds it, before trusting keys found in it"
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+// entropyWarning knows of no bad setups outside Linux, where detecting a VM's RNG would need platform
+// APIs.
+func entropyWarning() string { return "" }
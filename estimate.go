@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// estimate implements the estimate subcommand, which prints the expected cost of a search without running it.
+func estimate(args []string) int {
+	fs := flag.NewFlagSet("estimate", flag.ExitOnError)
+	var (
+		prefix      *string        = fs.String("p", "", "address prefix (excluding 0x)")
+		suffix      *string        = fs.String("s", "", "address suffix")
+		insensitive *bool          = fs.Bool("i", false, "accept case-insensitive solutions")
+		useFast     *bool          = fs.Bool("f", false, "shorthand for -backend fast")
+		backendName *string        = fs.String("backend", string(vanity.Geth), "key generation backend to measure: "+backendNames())
+		rate        *float64       = fs.Float64("rate", 0, "key rate in keys/second; measured if not set")
+		measure     *time.Duration = fs.Duration("measure", time.Second, "how long to measure the key rate for")
+		workers     *int           = fs.Int("workers", runtime.GOMAXPROCS(0), "number of workers to measure the key rate with")
+		profile     *string        = fs.String("profile", defaultCalibrationPath(), "calibration profile saved by 'bench -save' to take the key rate from, if it was measured with the same number of workers")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s estimate [flags]\n\nprints the expected number of attempts and time needed to find a matching address.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *prefix == "" && *suffix == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := vanity.ValidatePattern(*prefix + *suffix); err != nil {
+		fatal(err)
+	}
+
+	d := vanity.Difficulty(*prefix, *suffix, !*insensitive)
+	fmt.Printf("expected attempts: %.0f\n", d)
+
+	backend, err := selectBackend(*backendName, *useFast)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	var cal *calibration
+	if *rate <= 0 && *profile != "" {
+		c, err := loadCalibration(*profile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fatal(err)
+		}
+		if c != nil && c.Workers == *workers && c.Rates[string(backend)] > 0 {
+			cal = c
+		}
+	}
+	switch {
+	case cal != nil:
+		*rate = cal.Rates[string(backend)]
+		fmt.Printf("key rate:          %.0f keys/s (from the calibration profile of %s)\n", *rate, cal.Measured.Local().Format(time.DateTime))
+	case *rate <= 0:
+		if *workers < 1 {
+			fatal(usageError{fmt.Errorf("-workers must be at least 1")})
+		}
+		if *rate, err = vanity.MeasureRate(context.Background(), backend, len(*prefix), *insensitive, *measure, *workers); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("key rate:          %.0f keys/s (measured over %s with %d workers)\n", *rate, *measure, *workers)
+	default:
+		fmt.Printf("key rate:          %.0f keys/s\n", *rate)
+	}
+	fmt.Printf("expected time:     %s\n", fmtSeconds(d / *rate))
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		fmt.Printf("%2.0f%% chance within: %s\n", 100*p, fmtSeconds(attemptsFor(p, d) / *rate))
+	}
+	return exitOK
+}
+
+// attemptsFor returns the number of attempts after which the chance of success reaches p when the
+// expected number of attempts is d.
+func attemptsFor(p, d float64) float64 {
+	return math.Log1p(-p) / math.Log1p(-1/d)
+}
+
+// expectLong returns vanity.ErrTooLong, with the expected time, if d expected attempts with backend and
+// workers are expected to take longer than vanity.LongSearch. the key rate comes from the calibration
+// profile if it has one for them, and is otherwise measured briefly. searches of at most 2^24 attempts,
+// which any backend does within the hour even on one slow core, are never measured.
+func expectLong(d float64, backend vanity.Backend, workers int, insensitive bool) error {
+	if d <= 1<<24 {
+		return nil
+	}
+	rate, err := keyRate(backend, workers, insensitive)
+	if err != nil {
+		return err
+	}
+	if err = vanity.CheckDuration(d, rate); err != nil {
+		return fmt.Errorf("%w (about %s at %.0f keys/s)", err, fmtSeconds(d/rate), rate)
+	}
+	return nil
+}
+
+// checkLong is expectLong with the advice for generate, which asks for -l, -t, -until or -max-attempts
+// before a long search.
+func checkLong(d float64, backend vanity.Backend, workers int, insensitive bool) error {
+	err := expectLong(d, backend, workers, insensitive)
+	if errors.Is(err, vanity.ErrTooLong) {
+		return fmt.Errorf("%w; re-run with the -l flag or set a timeout with the -t flag if you wish to continue", err)
+	}
+	return err
+}
+
+// rateKey identifies a key rate measured by keyRate.
+type rateKey struct {
+	backend     vanity.Backend
+	workers     int
+	insensitive bool
+}
+
+// keyRates holds the key rates keyRate has found, so that a process that starts many searches, such as
+// one for each pattern read from stdin, measures each rate only once.
+var keyRates struct {
+	sync.Mutex
+	m map[rateKey]float64
+}
+
+// keyRate returns the key rate of backend with workers from the default calibration profile, or
+// measures it for a quarter of a second if the profile has none.
+func keyRate(backend vanity.Backend, workers int, insensitive bool) (float64, error) {
+	k := rateKey{backend, workers, insensitive}
+	keyRates.Lock()
+	defer keyRates.Unlock()
+	if r, ok := keyRates.m[k]; ok {
+		return r, nil
+	}
+	rate := profileRate(backend, workers)
+	if rate == 0 {
+		slog.Debug("measuring the key rate to estimate the search time", "backend", backend, "workers", workers)
+		var err error
+		if rate, err = vanity.MeasureRate(context.Background(), backend, 0, insensitive, time.Second/4, workers); err != nil {
+			return 0, err
+		}
+	}
+	if keyRates.m == nil {
+		keyRates.m = make(map[rateKey]float64)
+	}
+	keyRates.m[k] = rate
+	return rate, nil
+}
+
+// profileRate returns the key rate of backend with workers from the default calibration profile, or 0
+// if it has none.
+func profileRate(backend vanity.Backend, workers int) float64 {
+	if p := defaultCalibrationPath(); p != "" {
+		if c, err := loadCalibration(p); err == nil && c.Workers == workers {
+			return c.Rates[string(backend)]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// eventStream writes newline-delimited JSON events describing a search, for programs that display or
+// orchestrate searches. every event has an "event" field naming its type and a "time" field. the methods
+// do nothing on a nil *eventStream, so callers needn't check whether -progress-json was given.
+type eventStream struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// openEventStream returns an eventStream writing to stderr if dest is "-", and otherwise to the file or
+// named pipe at dest.
+func openEventStream(dest string) (*eventStream, error) {
+	var w io.Writer = os.Stderr
+	if dest != "-" {
+		// O_APPEND rather than O_TRUNC, which is meaningless for a pipe and loses history for a file
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	return &eventStream{w: w, enc: json.NewEncoder(w)}, nil
+}
+
+type event struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+}
+
+func (e *eventStream) emit(v any) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.enc.Encode(v) // a reader going away must not stop the search
+}
+
+// close closes the destination if it is a file.
+func (e *eventStream) close() {
+	if e == nil {
+		return
+	}
+	if f, ok := e.w.(*os.File); ok && f != os.Stderr {
+		f.Close()
+	}
+}
+
+func (e *eventStream) start(prefix, suffix string, caseSensitive bool, d float64, workers int, deadline time.Time) {
+	ev := struct {
+		event
+		Prefix           string     `json:"prefix,omitempty"`
+		Suffix           string     `json:"suffix,omitempty"`
+		CaseSensitive    bool       `json:"case_sensitive"`
+		ExpectedAttempts float64    `json:"expected_attempts"`
+		Workers          int        `json:"workers"`
+		Deadline         *time.Time `json:"deadline,omitempty"`
+	}{event{"start", time.Now()}, prefix, suffix, caseSensitive, math.Round(d), workers, nil}
+	if !deadline.IsZero() {
+		ev.Deadline = &deadline
+	}
+	e.emit(ev)
+}
+
+func (e *eventStream) found(addr common.Address, n int, attempts uint64, att *attestation) {
+	e.emit(struct {
+		event
+		Address     string       `json:"address"`
+		N           int          `json:"n"`
+		Attempts    uint64       `json:"attempts"`
+		Attestation *attestation `json:"attestation,omitempty"`
+	}{event{"found", time.Now()}, addr.Hex(), n, attempts, att})
+}
+
+// done reports the end of the search. reason is empty if every key was found.
+func (e *eventStream) done(code int, reason string, found int, attempts uint64, elapsed time.Duration) {
+	e.emit(struct {
+		event
+		ExitCode       int     `json:"exit_code"`
+		Reason         string  `json:"reason,omitempty"`
+		Found          int     `json:"found"`
+		Attempts       uint64  `json:"attempts"`
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+	}{event{"done", time.Now()}, code, reason, found, attempts, elapsed.Seconds()})
+}
+
+type bestEvent struct {
+	Address       string `json:"address"`
+	Matched       int    `json:"matched"`
+	PatternLength int    `json:"pattern_length"`
+}
+
+// reportProgressJSON emits a progress event for search every interval until ctx is done. the fields
+// mirror the ones reportProgress logs; rates are in keys per second and times in seconds.
+func (e *eventStream) reportProgressJSON(ctx context.Context, search *vanity.Searcher, interval time.Duration, deadline, start time.Time, rangeSize uint64) {
+	if e == nil || interval <= 0 {
+		return
+	}
+	d, patternLen := search.Difficulty(), len(search.Prefix)+len(search.Suffix)
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	last, lastN := time.Now(), search.Attempts()
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+		now, n := time.Now(), search.Attempts()
+		rate := float64(n) / now.Sub(start).Seconds()
+		ev := struct {
+			event
+			Attempts         uint64     `json:"attempts"`
+			ElapsedSeconds   float64    `json:"elapsed_seconds"`
+			Rate             float64    `json:"rate"`
+			OverallRate      float64    `json:"overall_rate"`
+			ETASeconds       float64    `json:"eta_seconds"`
+			Chance           *float64   `json:"chance,omitempty"` // of a match before the deadline
+			RemainingSeconds *float64   `json:"remaining_seconds,omitempty"`
+			Covered          *float64   `json:"covered,omitempty"` // share of the -range searched, from 0 to 1
+			Best             *bestEvent `json:"best,omitempty"`
+			Invalid          uint64     `json:"invalid,omitempty"` // candidates skipped as invalid
+		}{
+			event:          event{"progress", now},
+			Attempts:       n,
+			ElapsedSeconds: now.Sub(start).Seconds(),
+			Rate:           float64(n-lastN) / now.Sub(last).Seconds(),
+			OverallRate:    rate,
+			ETASeconds:     d / rate,
+			Invalid:        search.Invalid(),
+		}
+		if math.IsInf(ev.ETASeconds, 0) {
+			ev.ETASeconds = -1 // JSON has no infinity
+		}
+		if !deadline.IsZero() {
+			left := max(deadline.Sub(now), 0).Seconds()
+			chance := successProbability(rate*left, d)
+			ev.Chance, ev.RemainingSeconds = &chance, &left
+		}
+		if rangeSize > 0 {
+			c := covered(search, rangeSize)
+			ev.Covered = &c
+		}
+		if res, score := search.Best(); score > 0 {
+			ev.Best = &bestEvent{res.Address.Hex(), score, patternLen}
+		}
+		e.emit(ev)
+		last, lastN = now, n
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// exit statuses. scripts depend on these, so existing ones must never be renumbered.
+const (
+	exitOK          = 0   // every requested key was found, or the command succeeded
+	exitFailure     = 1   // an error not covered below, or a key that failed verification
+	exitUsage       = 2   // invalid flags or arguments, as for the flag package
+	exitPattern     = 3   // the pattern is invalid, or too long to search without -l, -t or -max-attempts
+	exitIO          = 4   // a file could not be read or written
+	exitGaveUp      = 5   // the search timed out or reached -max-attempts before finding every key
+	exitInterrupted = 130 // the search was stopped by SIGINT or SIGTERM (128 + SIGINT, as shells report it)
+)
+
+// usageError marks an error as caused by invalid flags or arguments.
+type usageError struct{ error }
+
+func (e usageError) Unwrap() error { return e.error }
+
+// exitCode returns the exit status for a command that failed with err.
+func exitCode(err error) int {
+	var (
+		pathErr  *fs.PathError
+		usageErr usageError
+	)
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, vanity.ErrInvalid), errors.Is(err, vanity.ErrTooLong), errors.Is(err, vanity.ErrTooLongInvalid):
+		return exitPattern
+	case errors.As(err, &pathErr):
+		return exitIO
+	}
+	return exitFailure
+}
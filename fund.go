@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// fundTemplate is what the fund subcommand writes.
+type fundTemplate struct {
+	Transaction unsignedTx    `json:"transaction"`
+	Unsigned    hexutil.Bytes `json:"unsigned"`     // 0x02 || rlp([chainId, nonce, tip, feeCap, gas, to, value, data, accessList])
+	SigningHash common.Hash   `json:"signing_hash"` // keccak-256 of Unsigned, which the signer signs
+	Cast        string        `json:"cast"`
+}
+
+// parseAddress parses an address given on the command line. one in mixed case must have a valid
+// EIP-55 checksum, which catches the typos a funding transaction cannot be taken back from.
+func parseAddress(flagName, s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("-%s: invalid address %q", flagName, s)
+	}
+	m, err := common.NewMixedcaseAddressFromString(s)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("-%s: %w", flagName, err)
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && !m.ValidChecksum() {
+		return common.Address{}, fmt.Errorf("-%s: %s has an invalid EIP-55 checksum; check it for typos", flagName, s)
+	}
+	return m.Address(), nil
+}
+
+// parseUnits parses a decimal amount of a unit worth 10^decimals of the smallest one, such as ether
+// or gwei, exactly.
+func parseUnits(s string, decimals int) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 || strings.ContainsAny(s, "eE/") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("%q has more than %d decimal places", s, decimals)
+	}
+	return r.Num(), nil
+}
+
+// formatEther formats wei as ether, without trailing zeros.
+func formatEther(wei *big.Int) string {
+	s := new(big.Rat).SetFrac(wei, big.NewInt(1e18)).FloatString(18)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// fundingTemplate returns the unsigned EIP-1559 transaction that sends value wei from from to to.
+func fundingTemplate(from, to common.Address, value *big.Int, nonce, gas uint64, tip, feeCap *big.Int, chainID uint64) (*fundTemplate, error) {
+	id := new(big.Int).SetUint64(chainID)
+	payload, err := rlp.EncodeToBytes([]any{id, nonce, tip, feeCap, gas, to, value, []byte{}, types.AccessList{}})
+	if err != nil {
+		return nil, err
+	}
+	unsigned := append([]byte{types.DynamicFeeTxType}, payload...)
+	typ := hexutil.Uint64(types.DynamicFeeTxType)
+	n, g := hexutil.Uint64(nonce), hexutil.Uint64(gas)
+	t := &fundTemplate{
+		Transaction: unsignedTx{
+			Description:          fmt.Sprintf("send %s ether from %s to %s", formatEther(value), from.Hex(), to.Hex()),
+			Type:                 &typ,
+			From:                 from,
+			To:                   to,
+			Value:                (*hexutil.Big)(value),
+			Data:                 hexutil.Bytes{},
+			Nonce:                &n,
+			Gas:                  &g,
+			MaxFeePerGas:         (*hexutil.Big)(feeCap),
+			MaxPriorityFeePerGas: (*hexutil.Big)(tip),
+			ChainID:              (*hexutil.Big)(id),
+		},
+		Unsigned:    unsigned,
+		SigningHash: crypto.Keccak256Hash(unsigned),
+		Cast:        fmt.Sprintf("cast send %s --value %swei --from %s --nonce %d --gas-limit %d --gas-price %s --priority-gas-price %s --chain %d --rpc-url \"$ETH_RPC_URL\"", to.Hex(), value, from.Hex(), nonce, gas, feeCap, tip, chainID),
+	}
+	return t, nil
+}
+
+// fund implements the fund subcommand, which writes the unsigned transaction that funds a new address
+// from an existing one, to be signed elsewhere, so that the new address need not be copied by hand.
+func fund(args []string) int {
+	fs := flag.NewFlagSet("fund", flag.ExitOnError)
+	var (
+		from    *string = fs.String("from", "", "address to send the funds from")
+		to      *string = fs.String("to", "", "address to fund")
+		keyPath *string = fs.String("k", "", "or the key file of the address to fund: hex, keystore (v3 or eip2335) or mnemonic")
+		inPass  *string = fs.String("in-pass", "", "file containing the passphrase of an encrypted key file (defaults to $VANITY_PASSPHRASE), or the BIP-39 passphrase for mnemonics")
+		hdPath  *string = fs.String("path", accounts.DefaultBaseDerivationPath.String(), "BIP-32 derivation path for mnemonics")
+		amount  *string = fs.String("amount", "", "amount to send, in ether, such as 0.05")
+		nonce   *int64  = fs.Int64("nonce", -1, "nonce of the -from address's next transaction")
+		gas     *uint64 = fs.Uint64("gas", 21000, "gas limit")
+		maxFee  *string = fs.String("max-fee", "", "maximum fee per gas, in gwei")
+		tip     *string = fs.String("priority-fee", "", "maximum priority fee per gas, in gwei")
+		chainID *uint64 = fs.Uint64("chain-id", 1, "ID of the chain the transaction is for")
+		out     *string = fs.String("o", "", "write the template to this file instead of stdout")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s fund [flags]\n\nwrites the unsigned EIP-1559 transaction, as JSON and as the payload a signer signs, that sends\nether to an address. it can be run for each key found with generate -on-success '%[1]s fund -to {addr} ...'.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *from == "" || (*to == "") == (*keyPath == "") || *amount == "" || *nonce < 0 || *maxFee == "" || *tip == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	src, err := parseAddress("from", *from)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	var dst common.Address
+	if *to != "" {
+		if dst, err = parseAddress("to", *to); err != nil {
+			fatal(usageError{err})
+		}
+	} else {
+		data, err := os.ReadFile(*keyPath)
+		if err != nil {
+			fatal(err)
+		}
+		key, err := loadKey(data, *inPass, *hdPath)
+		clear(data)
+		if err != nil {
+			fatal(err)
+		}
+		dst = crypto.PubkeyToAddress(key.PublicKey)
+		vanity.ZeroKey(key)
+	}
+	if src == dst {
+		fatal(usageError{errors.New("-from and -to are the same address")})
+	}
+	value, err := parseUnits(*amount, 18)
+	if err != nil {
+		fatal(usageError{fmt.Errorf("-amount: %w", err)})
+	}
+	feeCap, err := parseUnits(*maxFee, 9)
+	if err != nil {
+		fatal(usageError{fmt.Errorf("-max-fee: %w", err)})
+	}
+	tipCap, err := parseUnits(*tip, 9)
+	if err != nil {
+		fatal(usageError{fmt.Errorf("-priority-fee: %w", err)})
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		fatal(usageError{errors.New("-priority-fee must not be more than -max-fee")})
+	}
+
+	t, err := fundingTemplate(src, dst, value, uint64(*nonce), *gas, tipCap, feeCap, *chainID)
+	if err != nil {
+		fatal(err)
+	}
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	b = append(b, '\n')
+	if *out == "" {
+		os.Stdout.Write(b)
+	} else if err = os.WriteFile(*out, b, 0644); err != nil {
+		fatal(err)
+	}
+	return exitOK
+}
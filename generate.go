@@ -0,0 +1,1317 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// generateOptions holds generate's flags.
+type generateOptions struct {
+	prefix, suffix, path   *string
+	patternFile            *string
+	prefixFile             *string
+	insensitive, longOk    *bool
+	useFast                *bool
+	backendName            *string
+	keyRange, part, until  *string
+	progress               *time.Duration
+	bundle                 *string
+	vaultPath, vaultMount  *string
+	pmCLI, pmVault, pmColl *string
+	passFile, format, kdf  *string
+	withdrawal             *bool
+	count                  *int
+	keepBest               *bool
+	cpPath                 *string
+	cpInterval             *time.Duration
+	keepCps                *bool
+	resumePath, storeURL   *string
+	numWorkers, batchSize  *int
+	pinSpec                *string
+	tune                   *time.Duration
+	maxAttempts            *uint64
+	noColor, tui           *bool
+	onSuccess, webhook     *string
+	showURI                *bool
+	uriChain               *uint64
+	uriQR                  *string
+	checkRPC, watchRPC     *string
+	watchEvery             *time.Duration
+	hookKey                *bool
+	maxTemp, coolTemp      *float64
+	auditPath, eventsPath  *string
+	dryRun                 *bool
+	tgChat, slackChan      *string
+	notify, riskyOut       *bool
+	offline, mlock         *bool
+	logOpts                *logOptions
+	profOpts               *profileOptions
+	configPath             *string
+	timeOut                timeoutFlag
+	cpuLimit               percentFlag
+	quiet                  quietFlag
+}
+
+func addGenerateFlags(fs *flag.FlagSet) *generateOptions {
+	o := &generateOptions{
+		prefix:      fs.String("p", "", "output address prefix (excluding 0x); - reads one per line from stdin and searches for each in turn"),
+		suffix:      fs.String("s", "", "output address suffix; - reads one per line from stdin (a prefix and suffix per line if -p is also -)"),
+		path:        fs.String("o", "priv.key", "private key file output path; {addr} and {n} are replaced by the address and result number"),
+		patternFile: fs.String("patterns", "", "search for an address that contains any of the patterns in this file, one per line, anywhere in it, instead of -p and -s"),
+		prefixFile:  fs.String("prefixes", "", "search for an address that begins with any of the prefixes in this file, one per line, instead of -p and -s; suits lists of millions"),
+		insensitive: fs.Bool("i", false, "accept case-insensitive solutions"),
+		longOk:      fs.Bool("l", false, "accept long prefixes"),
+		useFast:     fs.Bool("f", false, "shorthand for -backend fast"),
+		backendName: fs.String("backend", string(vanity.Geth), "key generation backend: "+backendNames()+"; see 'bench' for their speed and -dry-run for their security notes"),
+		keyRange:    fs.String("range", "", "search exactly the keys START:SIZE, START in hex and SIZE in decimal or as 2^N, with the walk backend; searches given disjoint ranges never repeat each other's work"),
+		part:        fs.String("part", "", "search only part I/N of -range, numbered from 1, so that N machines can share it"),
+		until:       fs.String("until", "", "stop searching at this local time (15:04 or 15:04:05) or RFC 3339 timestamp"),
+		progress:    fs.Duration("progress", 30*time.Second, "interval between progress reports; 0 disables them (send SIGUSR1 for a report on demand)"),
+		bundle:      fs.String("bundle", "", "also write an encrypted archive of the key, metadata and QR codes to this path"),
+		vaultPath:   fs.String("vault", "", "write each key to this path in a HashiCorp Vault KV v2 engine instead of a file, and print the path; {addr} and {n} are replaced by the address and result number. Vault is reached at $VAULT_ADDR, with $VAULT_TOKEN or the AppRole $VAULT_ROLE_ID and $VAULT_SECRET_ID, in $VAULT_NAMESPACE"),
+		vaultMount:  fs.String("vault-mount", "secret", "mount path of the KV v2 engine that -vault writes to"),
+		pmCLI:       fs.String("password-manager", "", "hand each key to the 1Password (op) or Bitwarden (bw) CLI as a new item instead of writing a file, and print the item's reference; the CLI must be signed in, for bw with $BW_SESSION set. if it fails, the key is saved to a keystore encrypted with the -pass passphrase instead"),
+		pmVault:     fs.String("pm-vault", "", "1Password vault, or Bitwarden organization ID, to create -password-manager items in"),
+		pmColl:      fs.String("pm-collection", "", "Bitwarden collection ID to put -password-manager items in"),
+		passFile:    fs.String("pass", "", "file containing the passphrase used to encrypt output (defaults to $VANITY_PASSPHRASE)"),
+		format:      fs.String("format", "hex", "private key file format: hex, eip2335, or foundry, a keystore in Foundry's keystore directory (~/.foundry/keystores/{addr} unless -o is given) for 'forge script --account'"),
+		withdrawal:  fs.Bool("withdrawal", false, "search for a validator's withdrawal address: print each address's 0x01 withdrawal credentials, for the deposit, after it and save its key as an EIP-2335 keystore unless -format is given"),
+		kdf:         fs.String("kdf", "scrypt", "key derivation function for eip2335 keystores: scrypt or pbkdf2"),
+		count:       fs.Int("n", 1, "number of distinct matching keys to generate; only 1 with the fast and walk backends"),
+		keepBest:    fs.Bool("best", false, "if the search times out or hits -max-attempts, save the closest match found instead of exiting with an error; not with the fast and walk backends"),
+		cpPath:      fs.String("checkpoint", "", "periodically save the search state, encrypted with the -pass passphrase, to this path; it is overwritten and removed once every key is found"),
+		cpInterval:  fs.Duration("checkpoint-interval", 5*time.Minute, "interval between checkpoints"),
+		keepCps:     fs.Bool("keep-checkpoints", false, "keep the -checkpoint file and -checkpoint-store object once every key is found"),
+		resumePath:  fs.String("resume", "", "resume the search saved in this checkpoint file"),
+		storeURL:    fs.String("checkpoint-store", "", "also upload checkpoints, encrypted with the -pass passphrase, to s3://bucket/prefix, gs://bucket/prefix or an Azure container URL with a SAS token, and resume from the one there at startup. S3 and gs:// credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and AWS_REGION and AWS_ENDPOINT_URL are honoured"),
+		numWorkers:  fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines"),
+		batchSize:   fs.Int("batch-size", 0, "number of candidates each worker takes at a time (0 sizes batches to the CPU cache)"),
+		pinSpec:     fs.String("pin", "", "pin each worker to one CPU in turn (Linux only): all for every CPU, cores for one hardware thread per physical core, or a list such as 0-3,8; -workers defaults to the number of CPUs"),
+		tune:        fs.Duration("tune", 0, "before searching, measure several worker counts and batch sizes for this long each, e.g. 2s, and search with the fastest, overriding -workers and -batch-size"),
+		maxAttempts: fs.Uint64("max-attempts", 0, "stop after roughly this many attempts (0 means no limit)"),
+		noColor:     fs.Bool("no-color", false, "never color the matched part of found addresses (also disabled by NO_COLOR)"),
+		tui:         fs.Bool("tui", false, "show a live dashboard that can pause the search and change the number of workers"),
+		onSuccess:   fs.String("on-success", "", "shell command to run for each key found; {addr} and {path} are replaced by the quoted address and output path"),
+		webhook:     fs.String("webhook", "", "URL to POST a JSON description of each key found to"),
+		showURI:     fs.Bool("uri", false, "print an EIP-681 payment URI (ethereum:ADDRESS) after each address found, and a QR code of it on a terminal, for mobile wallets to fund it from"),
+		uriChain:    fs.Uint64("uri-chain-id", 0, "chain ID to put in -uri URIs (0 leaves it out)"),
+		uriQR:       fs.String("uri-qr", "", "also write a PNG QR code of the -uri URI to this path; {addr} and {n} are replaced by the address and result number"),
+		checkRPC:    fs.String("check-rpc", "", "after each key is found, check with this Ethereum JSON-RPC endpoint, or Etherscan API URL with an apikey, that its address has no balance, transactions or code"),
+		watchRPC:    fs.String("watch-rpc", "", "once the search is done, keep running and report when each address found first receives funds, as seen by this Ethereum JSON-RPC endpoint or Etherscan API URL; reports are logged, sent to -webhook and shown with -notify"),
+		watchEvery:  fs.Duration("watch-interval", 30*time.Second, "interval between the balance checks of -watch-rpc"),
+		hookKey:     fs.Bool("webhook-include-key", false, "include the raw private key in -webhook payloads"),
+		maxTemp:     fs.Float64("max-temp", 0, "pause the search while the CPU is hotter than this many degrees Celsius (0 disables; Linux only)"),
+		coolTemp:    fs.Float64("cool-temp", 0, "resume a search paused by -max-temp once the CPU has cooled to this temperature (default 10 below -max-temp)"),
+		auditPath:   fs.String("audit-log", "", "append a JSON line to this file when the search starts, finds a key and ends, as an audit trail of what was generated; no key material is written"),
+		eventsPath:  fs.String("progress-json", "", "also write newline-delimited JSON events to this file or named pipe, or to stderr if -; progress events are sent every -progress"),
+		dryRun:      fs.Bool("dry-run", false, "check the flags, print what the search would do and exit without searching"),
+		tgChat:      fs.String("telegram-chat", "", "send a message with the address, attempts and elapsed time to this Telegram chat ID when the search finishes or gives up, through the bot whose token is in $TELEGRAM_BOT_TOKEN"),
+		slackChan:   fs.String("slack-channel", "", "likewise for this Slack channel ID, through the bot whose token is in $SLACK_BOT_TOKEN"),
+		notify:      fs.Bool("notify", false, "show a desktop notification when the search finishes or gives up"),
+		riskyOut:    fs.Bool("risky-output", false, "write plaintext keys to a terminal, a directory every user can write to, or a network or cloud-synced folder without asking first"),
+		offline:     fs.Bool("offline", false, "refuse the flags that use the network (-checkpoint-store, -webhook, -check-rpc, -watch-rpc, -vault, -password-manager, -telegram-chat, -slack-channel, -pprof and -notify) and, on Linux, stop the process and its hooks from making sockets"),
+		mlock:       fs.Bool("mlock", false, "lock the process's memory so that keys are never written to swap (Linux only); the search goes on with a warning if it cannot"),
+		logOpts:     addLogFlags(fs),
+		profOpts:    addProfileFlags(fs),
+		configPath:  fs.String("config", defaultConfigPath(), "TOML file of default flag values; VANITY_<FLAG> environment variables take precedence over it"),
+	}
+	fs.Var(&o.timeOut, "t", "maximum acceptable search time, as a duration (90m, 2h30m) or a number of seconds")
+	fs.Var(&o.cpuLimit, "cpu-limit", "use only about this share of the machine's CPU time, e.g. 50%, by pausing the workers periodically")
+	fs.Var(&o.quiet, "q", "print nothing but one line per result: the address, or the output path with -q=path. errors are still reported")
+	return o
+}
+
+// generator is a search set up from generate's flags, and what it does with the keys it finds.
+type generator struct {
+	*generateOptions
+	fs     *flag.FlagSet
+	listed patternList // for -patterns or -prefixes
+
+	// set by loadCheckpoint
+	cp     *checkpoint
+	store  *checkpointStore
+	cpPass []byte // encrypts checkpoints
+
+	// set by checkFlags
+	deadline  time.Time
+	cpus      []int
+	backend   vanity.Backend
+	ranges    []vanity.KeyRange
+	rangeSize uint64 // of the whole assignment, which a resumed search only has part of left
+
+	// set by setupOutputs
+	keyTmpl, bundleTmpl, uriQRTmpl, vaultTmpl string
+	writeKey                                  bool
+	pass                                      []byte
+	rpc, watcher                              *rpcClient
+	vault                                     *vaultClient
+	chat                                      *chatNotifier
+	pm                                        *passwordManager
+
+	// set by generate and run
+	events              *eventStream
+	audit               *auditLog
+	search              *vanity.Searcher
+	runID               string
+	start, sessionStart time.Time
+	found               map[common.Address]bool
+	foundOrder          []common.Address
+	cpFlags             map[string]string
+	patternLen          int
+	target              string
+	dash                *dashboard
+	resultOut           io.Writer
+	color               bool
+	watched             map[common.Address]metadata // for -watch-rpc's webhooks
+	interrupted         chan os.Signal
+}
+
+// generate implements the generate subcommand, which searches for keys whose addresses match a pattern.
+// it returns the exit status.
+func generate(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	g := &generator{generateOptions: addGenerateFlags(fs), fs: fs}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s generate [flags]\n\nsearches for a key whose address begins with -p and/or ends with -s, or contains one of -patterns.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	var err error
+
+	if *g.offline {
+		if err = offlineConflicts(fs); err != nil {
+			fatal(usageError{err})
+		}
+	}
+	g.loadCheckpoint()
+	configGiven := false
+	fs.Visit(func(f *flag.Flag) { configGiven = configGiven || f.Name == "config" })
+	if err = applyDefaults(fs, *g.configPath, configGiven); err != nil {
+		fatal(err)
+	}
+	if err = g.logOpts.setup(os.Stderr, g.quiet != ""); err != nil {
+		fatal(usageError{err})
+	}
+	defer g.logOpts.close()
+	if *g.offline {
+		// again, for flags the config file set
+		if err = offlineConflicts(fs); err != nil {
+			fatal(usageError{err})
+		}
+	}
+	if *g.prefix == "" && *g.suffix == "" && *g.patternFile == "" && *g.prefixFile == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	if *g.patternFile != "" || *g.prefixFile != "" {
+		if *g.prefix != "" || *g.suffix != "" || *g.patternFile != "" && *g.prefixFile != "" {
+			fatal(usageError{errors.New("-patterns, -prefixes and -p or -s cannot be used together")})
+		}
+		if g.listed, err = readPatternList(*g.patternFile, *g.prefixFile, *g.insensitive); err != nil {
+			fatal(err)
+		}
+	}
+	if *g.prefix == "-" || *g.suffix == "-" {
+		if *g.cpPath != "" || g.store != nil {
+			fatal(usageError{errors.New("searches for patterns read from stdin cannot be checkpointed")})
+		}
+		// give every result its own file, since the ones for different patterns would share names
+		var extra []string
+		if !hasPlaceholder(*g.path) && (*g.bundle == "" || isSet(fs, "o")) {
+			extra = append(extra, "-o", addrPath(*g.path))
+		}
+		if *g.bundle != "" && !hasPlaceholder(*g.bundle) {
+			extra = append(extra, "-bundle", addrPath(*g.bundle))
+		}
+		return generateEach(append(args, extra...), os.Stdin, *g.prefix == "-", *g.suffix == "-")
+	}
+
+	g.checkFlags()
+	if g.rangeSize > 0 && len(g.ranges) == 0 {
+		slog.Error("every key in the range has already been searched", "checkpoint", *g.resumePath)
+		return exitGaveUp
+	}
+	if !*g.longOk && g.deadline.IsZero() && *g.maxAttempts == 0 {
+		if err = checkLong(g.difficulty(), g.backend, *g.numWorkers, *g.insensitive); err != nil {
+			// not fatal, so that generateEach goes on to the next pattern
+			slog.Error(err.Error())
+			return exitCode(err)
+		}
+	}
+	g.setupOutputs()
+	if *g.dryRun {
+		g.plan().print(os.Stdout)
+		return exitOK
+	}
+
+	if *g.mlock {
+		if err = lockMemory(); err != nil {
+			slog.Warn("could not lock memory; keys may be written to swap", "err", err)
+		}
+	}
+	if *g.offline {
+		if err = blockSockets(); errors.Is(err, errNoSocketBlocking) {
+			slog.Warn("network features are refused, but sockets cannot be blocked", "err", err)
+		} else if err != nil {
+			fatal(err) // where sockets can be blocked, nothing runs unless they are
+		}
+	}
+	if err = g.profOpts.start(); err != nil {
+		fatal(err)
+	}
+	defer g.profOpts.stop()
+	if *g.eventsPath != "" {
+		if g.events, err = openEventStream(*g.eventsPath); err != nil {
+			fatal(err)
+		}
+		defer g.events.close()
+	}
+	if *g.auditPath != "" {
+		if g.audit, err = openAuditLog(*g.auditPath); err != nil {
+			fatal(err)
+		}
+		defer g.audit.close()
+	}
+	g.newSearch()
+	return g.run()
+}
+
+// loadCheckpoint loads the checkpoint to resume from, given by -resume or found in -checkpoint-store, and
+// sets the flags saved in it that were not given again.
+func (g *generator) loadCheckpoint() {
+	var err error
+	if *g.storeURL != "" || *g.resumePath != "" {
+		if g.cpPass, err = readPassphrase(*g.passFile); err != nil {
+			fatal(err)
+		}
+	}
+	if *g.storeURL != "" {
+		if g.store, err = openCheckpointStore(*g.storeURL, g.cpPass); err != nil {
+			fatal(usageError{err})
+		}
+		if *g.resumePath == "" {
+			g.cp, err = g.store.get()
+			switch {
+			case err == nil:
+				slog.Info("found a checkpoint in the store", "url", g.store)
+			case errors.Is(err, errNoRemoteCheckpoint):
+			default:
+				fatal(err) // rather than start over and overwrite it
+			}
+		}
+	}
+	if *g.resumePath != "" {
+		if g.cp, err = loadCheckpoint(*g.resumePath, g.cpPass); err != nil {
+			fatal(err)
+		}
+	}
+	if g.cp == nil {
+		return
+	}
+	// flags given now take precedence over the saved ones
+	set := make(map[string]bool)
+	g.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, val := range g.cp.Flags {
+		if set[name] {
+			continue
+		}
+		if err = g.fs.Set(name, val); err != nil {
+			fatal(err)
+		}
+	}
+	if *g.cpPath == "" {
+		*g.cpPath = *g.resumePath
+	}
+}
+
+// checkFlags checks the flags that shape the search, and works out its deadline, CPUs, backend and key
+// ranges from them.
+func (g *generator) checkFlags() {
+	var err error
+	if g.timeOut > 0 {
+		g.deadline = time.Now().Add(time.Duration(g.timeOut))
+	}
+	if *g.until != "" {
+		u, err := parseUntil(*g.until, time.Now())
+		if err != nil {
+			fatal(usageError{err})
+		}
+		if g.deadline.IsZero() || u.Before(g.deadline) {
+			g.deadline = u
+		}
+	}
+
+	if *g.numWorkers < 1 {
+		fatal(usageError{fmt.Errorf("-workers must be at least 1")})
+	}
+	if g.cpus, err = pinCPUs(*g.pinSpec); err != nil {
+		fatal(usageError{err})
+	}
+	if g.cpus != nil && !isSet(g.fs, "workers") {
+		*g.numWorkers = len(g.cpus)
+	}
+	if *g.batchSize < 0 {
+		fatal(usageError{fmt.Errorf("-batch-size must not be negative")})
+	}
+	if *g.count < 1 {
+		fatal(usageError{fmt.Errorf("-n must be at least 1")})
+	}
+	if *g.withdrawal && !isSet(g.fs, "format") {
+		*g.format = "eip2335"
+	}
+	switch *g.format {
+	case "hex", "foundry":
+	case "eip2335":
+		if *g.kdf != "scrypt" && *g.kdf != "pbkdf2" {
+			fatal(usageError{fmt.Errorf("unsupported kdf %q", *g.kdf)})
+		}
+	default:
+		fatal(usageError{fmt.Errorf("unknown key format %q", *g.format)})
+	}
+	if g.listed == nil {
+		if err = vanity.ValidatePattern(*g.prefix + *g.suffix); err != nil {
+			fatal(err)
+		}
+	}
+	if *g.maxTemp > 0 {
+		if *g.coolTemp == 0 {
+			*g.coolTemp = *g.maxTemp - 10
+		}
+		if *g.coolTemp >= *g.maxTemp {
+			fatal(usageError{errors.New("-cool-temp must be below -max-temp")})
+		}
+		if _, err = cpuTemp(); err != nil {
+			fatal(err) // rather than silently running unprotected
+		}
+	}
+	if g.quiet != "" && *g.tui {
+		fatal(usageError{errors.New("-q and -tui cannot be used together")})
+	}
+
+	if g.backend, err = selectBackend(*g.backendName, *g.useFast); err != nil {
+		fatal(usageError{err})
+	}
+	g.selectRanges()
+	if err = checkOneResult(g.backend, *g.count, *g.keepBest); err != nil {
+		fatal(usageError{err})
+	}
+}
+
+// selectRanges sets the key ranges to search, from the checkpoint being resumed or -range and -part.
+// searching ranges takes the walk backend.
+func (g *generator) selectRanges() {
+	switch {
+	case g.cp != nil && g.cp.RangeSize > 0:
+		for _, s := range g.cp.Ranges {
+			r, err := vanity.ParseKeyRange(s)
+			if err != nil {
+				fatal(fmt.Errorf("%s: %w", *g.resumePath, err))
+			}
+			g.ranges = append(g.ranges, r)
+		}
+		g.rangeSize = g.cp.RangeSize
+	case *g.keyRange != "":
+		r, err := vanity.ParseKeyRange(*g.keyRange)
+		if err == nil && *g.part != "" {
+			r, err = selectPart(r, *g.part)
+		}
+		if err != nil {
+			fatal(usageError{err})
+		}
+		g.ranges, g.rangeSize = []vanity.KeyRange{r}, r.Size
+	case *g.part != "":
+		fatal(usageError{errors.New("-part requires -range")})
+	}
+	if g.ranges != nil {
+		if g.backend != vanity.Geth && g.backend != vanity.Walk {
+			fatal(usageError{errors.New("-range always uses the walk backend")})
+		}
+		g.backend = vanity.Walk
+	}
+}
+
+// difficulty returns the expected number of attempts to find a match.
+func (g *generator) difficulty() float64 {
+	if g.listed != nil {
+		d, _ := new(big.Float).SetInt(g.listed.Difficulty()).Float64()
+		return d
+	}
+	return vanity.Difficulty(*g.prefix, *g.suffix, !*g.insensitive)
+}
+
+// setupOutputs works out where keys go, opens the services they are handed to and reads the passphrases
+// that outputs and checkpoints are encrypted with, so that none of it fails once a key is found.
+func (g *generator) setupOutputs() {
+	var err error
+	g.keyTmpl, g.bundleTmpl, g.uriQRTmpl, g.vaultTmpl = *g.path, *g.bundle, *g.uriQR, *g.vaultPath
+	if *g.format == "foundry" && !isSet(g.fs, "o") {
+		dir, err := foundryKeystores()
+		if err != nil {
+			fatal(err)
+		}
+		g.keyTmpl = filepath.Join(dir, "{addr}") // the account name, which must be unique
+	}
+	if *g.count > 1 {
+		// never overwrite one result with the next
+		g.keyTmpl, g.bundleTmpl, g.uriQRTmpl, g.vaultTmpl = numberPath(g.keyTmpl), numberPath(g.bundleTmpl), numberPath(g.uriQRTmpl), numberPath(g.vaultTmpl)
+	}
+
+	// with -bundle, -vault or -password-manager, only write the loose key file if -o was given explicitly.
+	g.writeKey = (*g.bundle == "" && *g.vaultPath == "" && *g.pmCLI == "") || isSet(g.fs, "o")
+	if *g.cpPath != "" && g.cpPass == nil {
+		if g.cpPass, err = readPassphrase(*g.passFile); err != nil {
+			fatal(err)
+		}
+	}
+	// likewise for output directories that don't exist
+	for _, p := range []string{g.keyTmpl, g.bundleTmpl, g.uriQRTmpl, *g.cpPath} {
+		if dir := filepath.Dir(p); p != "" && !strings.ContainsAny(dir, "{}") {
+			if _, err = os.Stat(dir); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if g.writeKey && *g.format == "hex" {
+		// prompts cannot share stdin with patterns read from it
+		if err = confirmDestination(g.keyTmpl, *g.riskyOut, *g.prefix != "-" && *g.suffix != "-"); err != nil {
+			fatal(usageError{err})
+		}
+	}
+	if *g.checkRPC != "" {
+		if g.rpc, err = newRPCClient(*g.checkRPC); err != nil {
+			fatal(usageError{fmt.Errorf("-check-rpc: %w", err)})
+		}
+	}
+	if *g.watchRPC != "" {
+		if g.watcher, err = newRPCClient(*g.watchRPC); err != nil {
+			fatal(usageError{fmt.Errorf("-watch-rpc: %w", err)})
+		}
+		if *g.watchEvery <= 0 {
+			fatal(usageError{errors.New("-watch-interval must be positive")})
+		}
+	}
+	if *g.vaultPath != "" {
+		if g.vault, err = openVault(*g.vaultMount); err != nil {
+			fatal(err)
+		}
+	}
+	if g.chat, err = newChatNotifier(*g.tgChat, *g.slackChan); err != nil {
+		fatal(usageError{err})
+	}
+	if *g.pmCLI != "" {
+		if g.pm, err = newPasswordManager(*g.pmCLI, *g.pmVault, *g.pmColl); err != nil {
+			fatal(usageError{fmt.Errorf("-password-manager: %w", err)})
+		}
+	}
+	if (g.writeKey && (*g.format == "eip2335" || *g.format == "foundry")) || *g.bundle != "" || g.pm != nil {
+		// fail before searching rather than after
+		if g.pass, err = readPassphrase(*g.passFile); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// plan describes the search for -dry-run.
+func (g *generator) plan() plan {
+	p := plan{
+		prefix:        *g.prefix,
+		suffix:        *g.suffix,
+		caseSensitive: !*g.insensitive,
+		backend:       g.backend,
+		workers:       *g.numWorkers,
+		count:         *g.count,
+		difficulty:    g.difficulty(),
+		deadline:      g.deadline,
+		maxAttempts:   *g.maxAttempts,
+		format:        *g.format,
+		bundlePath:    g.bundleTmpl,
+		checkpoint:    *g.cpPath,
+		resumed:       g.cp,
+		ranges:        g.ranges,
+	}
+	if *g.insensitive {
+		p.prefix, p.suffix = strings.ToLower(p.prefix), strings.ToLower(p.suffix)
+	}
+	if g.listed != nil {
+		p.listed, p.anchored = g.listed.Patterns(), *g.prefixFile != ""
+	}
+	if g.writeKey {
+		p.keyPath = g.keyTmpl
+	} else if g.vault != nil {
+		p.keyPath = g.vault.name(g.vaultTmpl)
+	} else if g.pm != nil {
+		p.keyPath = "a new " + *g.pmCLI + " item"
+	}
+	return p
+}
+
+// newSearch sets up the search, tuning it first with -tune.
+func (g *generator) newSearch() {
+	if *g.tune > 0 {
+		slog.Info("tuning the number of workers and batch size", "backend", g.backend)
+		t, err := vanity.Tune(context.Background(), g.backend, len(*g.prefix), *g.insensitive, *g.tune, func(t vanity.Tuning) {
+			slog.Debug("measured", "workers", t.Workers, "batch_size", t.BatchSize, "keys_per_second", math.Round(t.Rate))
+		})
+		if err != nil {
+			fatal(err)
+		}
+		*g.numWorkers, *g.batchSize = t.Workers, t.BatchSize
+		slog.Info("tuned; pin this with the flags shown", "keys_per_second", math.Round(t.Rate), "flags", fmt.Sprintf("-workers %d -batch-size %d", t.Workers, t.BatchSize))
+	}
+
+	// with -tui, extra workers are started so they can be enabled later
+	maxWorkers := *g.numWorkers
+	if *g.tui {
+		if !isTerminal(os.Stderr) {
+			fatal(usageError{errors.New("-tui requires stderr to be a terminal")})
+		}
+		maxWorkers = max(*g.numWorkers, 2*runtime.NumCPU())
+	}
+	if g.ranges != nil {
+		maxWorkers = *g.numWorkers // idle workers would hold back their share of the range
+	}
+	if err := checkEntropy(); err != nil {
+		fatal(err)
+	}
+	opts := []vanity.Option{
+		vanity.WithPrefix(*g.prefix),
+		vanity.WithSuffix(*g.suffix),
+		vanity.WithCaseInsensitive(*g.insensitive),
+		vanity.WithBackend(g.backend),
+		vanity.WithWorkers(*g.numWorkers),
+		vanity.WithMaxWorkers(maxWorkers),
+		vanity.WithBatchSize(*g.batchSize),
+		vanity.WithCPUs(g.cpus...),
+		vanity.WithTrackBest(*g.keepBest || *g.tui || g.events != nil),
+		vanity.WithMaxAttempts(*g.maxAttempts),
+		vanity.WithRanges(g.ranges...),
+	}
+	if g.listed != nil {
+		opts = append(opts, vanity.WithMatcher(g.listed))
+	}
+	var err error
+	if g.search, err = vanity.New(opts...); err != nil {
+		fatal(err)
+	}
+}
+
+// run runs the search and saves the keys it finds, and returns the exit status.
+func (g *generator) run() int {
+	d := g.search.Difficulty()
+	slog.Info("generating keys. this may take awhile...", "expected_attempts", math.Round(d))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if !g.deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, g.deadline)
+	}
+	defer cancel()
+
+	g.interrupted = make(chan os.Signal, 1)
+	signal.Notify(g.interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(g.interrupted)
+
+	g.start = time.Now()
+	g.sessionStart = g.start
+	g.found = make(map[common.Address]bool, *g.count)
+	if g.cp != nil {
+		g.start = g.start.Add(-g.cp.Elapsed)
+		g.search.AddAttempts(g.cp.Attempts)
+		for _, a := range g.cp.Found {
+			g.found[a] = true
+		}
+		g.foundOrder = g.cp.Found
+		slog.Info("resuming search", "attempts", g.cp.Attempts, "elapsed", g.cp.Elapsed.Round(time.Second), "found", len(g.found), "wanted", *g.count)
+	}
+	g.events.start(*g.prefix, *g.suffix, !*g.insensitive, d, *g.numWorkers, g.deadline)
+	g.runID = newRunID()
+	as := auditStart{
+		Command:       "generate",
+		Prefix:        *g.prefix,
+		Suffix:        *g.suffix,
+		CaseSensitive: !*g.insensitive,
+		Chain:         string(vanity.Ethereum),
+		Backend:       string(g.backend),
+		Flags:         auditFlags(g.fs),
+	}
+	if g.listed != nil {
+		as.Patterns = len(g.listed.Patterns())
+	}
+	g.audit.start(g.runID, as)
+
+	var cpTick <-chan time.Time
+	g.cpFlags = make(map[string]string)
+	if *g.cpPath != "" || g.store != nil {
+		cpTick = time.NewTicker(*g.cpInterval).C
+		g.fs.Visit(func(f *flag.Flag) {
+			if f.Name != "checkpoint" && f.Name != "resume" && f.Name != "checkpoint-store" {
+				g.cpFlags[f.Name] = f.Value.String()
+			}
+		})
+	}
+
+	g.patternLen, g.target = len(*g.prefix)+len(*g.suffix), fmt.Sprintf("0x%s...%s", *g.prefix, *g.suffix)
+	if g.listed != nil {
+		g.patternLen, g.target = len(longest(g.listed.Patterns())), fmt.Sprintf("any of %d patterns", len(g.listed.Patterns()))
+	}
+	g.resultOut = os.Stdout
+	if *g.tui {
+		g.dash = &dashboard{
+			term:       os.Stderr,
+			title:      fmt.Sprintf("searching for %s (case-sensitive: %t)", g.target, !*g.insensitive),
+			search:     g.search,
+			patternLen: g.patternLen,
+			d:          d,
+			start:      g.start,
+			deadline:   g.deadline,
+			count:      *g.count,
+			found:      append([]common.Address(nil), g.foundOrder...),
+		}
+		if err := g.logOpts.setup(g.dash, false); err != nil {
+			fatal(err)
+		}
+		g.resultOut = dashboardWriter{g.dash, os.Stdout}
+		go g.dash.run(ctx, 500*time.Millisecond)
+		go g.dash.readCommands(os.Stdin, g.interrupted)
+	} else {
+		go reportProgress(ctx, g.search, *g.progress, g.deadline, g.start, g.rangeSize)
+	}
+	if g.cpuLimit > 0 {
+		go cpuThrottle(ctx, g.search, float64(g.cpuLimit))
+	}
+	if *g.maxTemp > 0 {
+		go thermalThrottle(ctx, g.search, *g.maxTemp, *g.coolTemp)
+	}
+	go g.events.reportProgressJSON(ctx, g.search, *g.progress, g.deadline, g.start, g.rangeSize)
+
+	// the search goes on while results are being saved
+	stream, err := g.search.Stream(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	g.color = useColor(os.Stdout, *g.noColor)
+	g.watched = make(map[common.Address]metadata)
+	return g.collect(stream, cpTick, func() {
+		cancel()
+		for range stream.C {
+		}
+		if g.dash != nil {
+			g.dash.close()
+		}
+	})
+}
+
+// collect saves the results of stream until -n keys are found, the search ends or it is interrupted, and
+// returns the exit status. stop stops the search and waits until it has.
+func (g *generator) collect(stream *vanity.Stream, cpTick <-chan time.Time, stop func()) int {
+	var giveUp string // why the search ended early
+	for len(g.found) < *g.count && giveUp == "" {
+		select {
+		case res, ok := <-stream.C:
+			if !ok {
+				switch err := stream.Err(); {
+				case errors.Is(err, vanity.ErrMaxAttempts):
+					giveUp = fmt.Sprintf("stopped after reaching the limit of %d attempts", *g.maxAttempts)
+				case errors.Is(err, vanity.ErrExhausted):
+					giveUp = "searched every key in the range"
+				default:
+					giveUp = fmt.Sprintf("timed out after %s", time.Since(g.sessionStart).Round(time.Second))
+				}
+				continue
+			}
+			if g.found[res.Address] {
+				continue // possible with -f, since its keys overlap
+			}
+			g.found[res.Address] = true
+			g.foundOrder = append(g.foundOrder, res.Address)
+			if g.dash != nil {
+				g.dash.addFound(res.Address)
+			}
+			att := g.save(res)
+			g.events.found(res.Address, len(g.found), g.search.Attempts(), att)
+			if len(g.found) < *g.count || *g.keepCps {
+				g.saveCheckpoint()
+			}
+			if len(g.found) == *g.count {
+				stop()
+				if !*g.keepCps {
+					g.removeCheckpoints()
+				}
+				summarize(g.search, time.Since(g.start))
+				g.done(exitOK, "")
+				stats := fmt.Sprintf("(%d attempts in %s)", g.search.Attempts(), time.Since(g.start).Round(time.Second))
+				if *g.count == 1 {
+					g.notifyDone("found " + res.Address.Hex() + " " + stats)
+				} else {
+					g.notifyDone(fmt.Sprintf("found %d keys %s", *g.count, stats))
+				}
+			}
+		case <-cpTick:
+			g.saveCheckpoint()
+		case sig := <-g.interrupted:
+			stop()
+			g.saveCheckpoint()
+			slog.Info("stopping", "signal", sig.String())
+			summarize(g.search, time.Since(g.start))
+			if *g.keepBest {
+				if res, score := g.search.Best(); score > 0 && !g.found[res.Address] {
+					g.found[res.Address] = true
+					g.save(res)
+					slog.Info("saved the closest match", "matched", score, "pattern_length", g.patternLen)
+				}
+			}
+			g.done(exitInterrupted, "received "+sig.String())
+			return exitInterrupted
+		}
+	}
+	if giveUp == "" {
+		g.watch()
+		return exitOK
+	}
+
+	stop()
+	g.saveCheckpoint()
+	summarize(g.search, time.Since(g.start))
+	g.notifyDone(fmt.Sprintf("%s (%d attempts)", giveUp, g.search.Attempts()))
+	if *g.keepBest {
+		if res, score := g.search.Best(); score > 0 && !g.found[res.Address] {
+			g.found[res.Address] = true
+			g.save(res)
+			slog.Info("saved the closest match", "reason", giveUp, "matched", score, "pattern_length", g.patternLen)
+			g.done(exitOK, giveUp)
+			g.watch()
+			return exitOK
+		}
+	}
+	if *g.count > 1 {
+		giveUp = fmt.Sprintf("%s with %d of %d keys found", giveUp, len(g.found), *g.count)
+	}
+	slog.Error(giveUp)
+	g.done(exitGaveUp, giveUp)
+	return exitGaveUp
+}
+
+// done reports the end of the search to -progress-json and -audit-log.
+func (g *generator) done(code int, reason string) {
+	g.events.done(code, reason, len(g.foundOrder), g.search.Attempts(), time.Since(g.start))
+	status := jobFailed
+	switch code {
+	case exitOK:
+		status = jobDone
+	case exitInterrupted:
+		status = jobCanceled
+	}
+	g.audit.end(g.runID, status, reason, len(g.found), g.search.Attempts(), g.search.Invalid(), time.Since(g.sessionStart))
+}
+
+// saveCheckpoint saves the search state to -checkpoint and -checkpoint-store, if either is set.
+func (g *generator) saveCheckpoint() {
+	if *g.cpPath == "" && g.store == nil {
+		return
+	}
+	c := checkpoint{
+		Version:   version,
+		Flags:     g.cpFlags,
+		Found:     g.foundOrder,
+		Attempts:  g.search.Attempts(),
+		Elapsed:   time.Since(g.start),
+		RangeSize: g.rangeSize,
+	}
+	if g.rangeSize > 0 {
+		// an empty list, once the range is exhausted, is told apart from no -range by RangeSize
+		for _, r := range g.search.Remaining() {
+			c.Ranges = append(c.Ranges, r.String())
+		}
+	}
+	if *g.cpPath != "" {
+		if err := c.write(*g.cpPath, g.cpPass); err != nil {
+			slog.Warn("could not save checkpoint", "err", err) // not worth abandoning the search over
+		}
+	}
+	if g.store != nil {
+		if err := g.store.put(&c); err != nil {
+			slog.Warn("could not upload checkpoint", "err", err)
+		}
+	}
+}
+
+// removeCheckpoints removes the checkpoints of a finished search, which are no use for resuming it, but
+// still narrow down where its keys lie.
+func (g *generator) removeCheckpoints() {
+	if *g.cpPath != "" {
+		if err := removeCheckpoint(*g.cpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("could not remove checkpoint", "path", *g.cpPath, "err", err)
+		}
+	}
+	if g.store != nil {
+		if err := g.store.remove(); err != nil {
+			slog.Warn("could not remove the uploaded checkpoint", "err", err)
+		}
+	}
+}
+
+// save writes res out and then clears its key, which nothing needs afterwards. it returns the key's
+// attestation, if it could be signed.
+func (g *generator) save(res vanity.Result) *attestation {
+	defer vanity.ZeroKey(res.Key)
+	n := len(g.found)
+	outPath, keyPath := g.storeKey(res, n)
+	meta := metadata{
+		Address:       res.Address.Hex(),
+		Prefix:        *g.prefix,
+		Suffix:        *g.suffix,
+		CaseSensitive: !*g.insensitive,
+		Chain:         string(vanity.Ethereum),
+		Attempts:      g.search.Attempts(),
+		Timestamp:     time.Now().UTC(),
+		Version:       version,
+		Entropy:       entropySource(),
+	}
+	var wc common.Hash
+	if *g.withdrawal {
+		wc = withdrawalCredentials(res.Address)
+		if err := checkWithdrawalCredentials(wc[:], res.Address); err != nil {
+			fatal(err)
+		}
+		meta.WithdrawalCredentials = wc.Hex()
+	}
+	att, err := attest(res.Key, claimFor(meta))
+	if err != nil {
+		slog.Warn("could not sign an attestation of the key", "err", err)
+	}
+	meta.Attestation = att
+	var bundlePath string
+	if *g.bundle != "" {
+		bundlePath = saveChecked(expandPath(g.bundleTmpl, res.Address, n), func(path string) error {
+			if err := writeBundle(path, g.pass, res.Key, meta); err != nil {
+				return err
+			}
+			return checkBundle(path, g.pass, res.Address)
+		})
+		if !g.writeKey && g.vault == nil && g.pm == nil {
+			outPath = bundlePath
+		}
+	}
+	if !g.writeKey && g.vault == nil && g.pm == nil {
+		keyPath = ""
+	}
+	g.audit.found(g.runID, res.Address.Hex(), keyPath, bundlePath, g.search.Attempts())
+	if *g.withdrawal {
+		fmt.Fprintln(g.resultOut, wc.Hex())
+	}
+	g.announce(res, meta, outPath)
+	return att
+}
+
+// storeKey prints the address of res, result number n, and writes its key to the key file, Vault and
+// password manager that are set. it returns the path printed for the result, and where the key went.
+func (g *generator) storeKey(res vanity.Result, n int) (outPath, keyPath string) {
+	outPath = expandPath(g.keyTmpl, res.Address, n)
+	switch {
+	case g.writeKey:
+	case g.vault != nil:
+		outPath = g.vault.name(expandPath(g.vaultTmpl, res.Address, n))
+	default:
+		outPath = expandPath(g.bundleTmpl, res.Address, n)
+	}
+	// print the result first in case the path is /dev/stdout
+	switch {
+	case g.pm != nil && !g.writeKey:
+		// printed once the item is created, as its ID is not known until then
+	case g.quiet == "path", g.vault != nil && !g.writeKey: // -vault prints only where the key went
+		fmt.Fprintln(g.resultOut, outPath)
+	case g.color && g.listed == nil: // only prefixes and suffixes are highlighted
+		fmt.Fprintln(g.resultOut, highlight(res.Address, *g.prefix, *g.suffix, *g.insensitive))
+	default:
+		fmt.Fprintln(g.resultOut, res.Address)
+	}
+	if g.writeKey {
+		outPath = saveChecked(outPath, func(path string) error {
+			var err error
+			switch *g.format {
+			case "eip2335":
+				err = saveV4(path, res.Key, g.pass, *g.kdf, "vanity address "+res.Address.Hex())
+			case "foundry":
+				err = saveFoundry(path, res.Key, g.pass)
+			default:
+				err = saveHex(path, res.Key)
+			}
+			if err != nil {
+				return err
+			}
+			return checkKeyFile(path, *g.format, g.pass, res.Address)
+		})
+		if *g.format == "foundry" {
+			slog.Info("saved the key as a Foundry account", "path", outPath, "use", "--account "+filepath.Base(outPath))
+		}
+	}
+	keyPath = outPath
+	if g.vault != nil {
+		keyPath = g.vault.name(saveChecked(expandPath(g.vaultTmpl, res.Address, n), func(path string) error {
+			if err := g.vault.putKey(path, res.Key); err != nil {
+				return err
+			}
+			return g.vault.checkKey(path, res.Address)
+		}))
+		if !g.writeKey {
+			outPath = keyPath
+		}
+	}
+	if g.pm != nil {
+		ref, err := g.pm.store(res.Key)
+		if err != nil && ref == "" {
+			// nothing was created, so trying again cannot leave a second item behind
+			slog.Error("could not save the key", "password_manager", *g.pmCLI, "err", err)
+			ref, err = g.pm.store(res.Key)
+		}
+		if err != nil {
+			g.rescuePM(res, n, ref, err)
+		}
+		keyPath = ref
+		if !g.writeKey {
+			outPath = ref
+			fmt.Fprintln(g.resultOut, ref)
+		}
+	}
+	return outPath, keyPath
+}
+
+// rescuePM handles the password manager's failure to take the key of res, result number n. unless -o
+// or -vault has saved it already, the key is saved to a keystore encrypted with the -pass passphrase,
+// through saveChecked, so that it is not lost with the process. it then exits, as the search cannot go
+// on delivering keys to where they were asked for. ref is the item that was created but could not be
+// checked, if any.
+func (g *generator) rescuePM(res vanity.Result, n int, ref string, err error) {
+	if ref != "" {
+		slog.Error("created an item but could not read it back; check it by hand before relying on it", "item", ref, "err", err)
+	} else {
+		slog.Error("could not save the key", "password_manager", *g.pmCLI, "err", err)
+	}
+	if !g.writeKey && g.vault == nil {
+		path := saveChecked(expandPath(g.keyTmpl, res.Address, n)+".json", func(path string) error {
+			if err := saveV4(path, res.Key, g.pass, *g.kdf, "vanity address "+res.Address.Hex()); err != nil {
+				return err
+			}
+			return checkKeyFile(path, "eip2335", g.pass, res.Address)
+		})
+		slog.Warn("saved the key to a keystore encrypted with the -pass passphrase instead", "path", path)
+	}
+	fatal(err)
+}
+
+// announce prints and writes the payment URI of res, checks its address is unused, and runs the hooks,
+// once its key is saved.
+func (g *generator) announce(res vanity.Result, meta metadata, outPath string) {
+	// only once the key is safely saved, as the URI is an invitation to fund the address
+	if *g.showURI || *g.uriQR != "" {
+		uri := paymentURI(res.Address, *g.uriChain)
+		if *g.showURI {
+			fmt.Fprintln(g.resultOut, uri)
+			if g.dash == nil && isTerminal(os.Stderr) {
+				if q, err := qrEncode([]byte(uri)); err == nil {
+					q.WriteText(os.Stderr)
+				}
+			}
+		}
+		if *g.uriQR != "" {
+			if err := writeURIQR(expandPath(g.uriQRTmpl, res.Address, len(g.found)), uri); err != nil {
+				slog.Warn("could not write the URI's QR code", "err", err)
+			}
+		}
+	}
+	if g.rpc != nil {
+		checkUnused(g.rpc, res.Address)
+	}
+	if g.watcher != nil {
+		g.watched[res.Address] = meta
+	}
+
+	// hooks must not cost us the search, so their failures are only logged
+	if *g.onSuccess != "" {
+		if err := runCommandHook(*g.onSuccess, res.Address.Hex(), outPath); err != nil {
+			slog.Warn("hook failed", "err", err)
+		}
+	}
+	if *g.webhook != "" {
+		p := hookPayload{Event: "found", metadata: meta, Path: outPath}
+		if *g.hookKey {
+			p.PrivateKey = hex.EncodeToString(crypto.FromECDSA(res.Key))
+		}
+		if err := postWebhook(*g.webhook, p); err != nil {
+			slog.Warn("webhook failed", "err", err)
+		}
+	}
+}
+
+// notifyDone sends body to the -telegram-chat and -slack-channel chats, and shows it with -notify.
+func (g *generator) notifyDone(body string) {
+	if err := g.chat.send("vanity: " + g.target + "\n" + body); err != nil {
+		slog.Warn("could not send a chat message", "err", err)
+	}
+	if !*g.notify {
+		return
+	}
+	if err := desktopNotify("vanity: "+g.target, body); err != nil {
+		slog.Warn("could not notify", "err", err)
+	}
+}
+
+// watch reports when the addresses found first receive funds, until they all have or the process is
+// interrupted.
+func (g *generator) watch() {
+	if g.watcher == nil || len(g.watched) == 0 {
+		return
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		select {
+		case sig := <-g.interrupted:
+			slog.Info("stopping", "signal", sig.String())
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+	addrs := make([]common.Address, 0, len(g.watched))
+	for _, addr := range g.foundOrder {
+		if _, ok := g.watched[addr]; ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	slog.Info("watching for funds", "addresses", len(addrs), "rpc", g.watcher, "interval", *g.watchEvery)
+	watchFunds(ctx, g.watcher, addrs, *g.watchEvery, func(addr common.Address, bal *big.Int) {
+		slog.Info("the address received funds", "address", addr.Hex(), "balance_wei", bal)
+		if *g.webhook != "" {
+			p := hookPayload{Event: "funded", metadata: g.watched[addr], BalanceWei: bal.String()}
+			if err := postWebhook(*g.webhook, p); err != nil {
+				slog.Warn("webhook failed", "err", err)
+			}
+		}
+		g.notifyDone(addr.Hex() + " received funds")
+	})
+}
+
+// summarize logs the amount of work done by a search, and any invalid candidates it skipped, which
+// point to a broken source of randomness.
+func summarize(search *vanity.Searcher, elapsed time.Duration) {
+	attempts := search.Attempts()
+	slog.Info("search finished", "attempts", attempts, "elapsed", elapsed.Round(time.Millisecond), "keys_per_sec", math.Round(float64(attempts)/elapsed.Seconds()))
+	if invalid := search.Invalid(); invalid > 0 {
+		slog.Warn("skipped invalid candidates; random scalars are almost never invalid, so the key source may be broken", "invalid", invalid)
+	}
+}
+
+// checkOneResult refuses -n above 1, and -best, with the backends whose keys from one run can be guessed
+// from each other, so that a run of them never keeps more than the one key it was asked for.
+func checkOneResult(b vanity.Backend, count int, best bool) error {
+	if b != vanity.Fast && b != vanity.Walk {
+		return nil
+	}
+	switch {
+	case count > 1:
+		return fmt.Errorf("-n %d cannot be used with the %s backend, whose keys from one run can be guessed from each other; run it once per key", count, b)
+	case best:
+		return fmt.Errorf("-best cannot be used with the %s backend, whose keys from one run can be guessed from each other", b)
+	}
+	return nil
+}
+
+// expandPath replaces {addr} in tmpl with the address and {n} with the 1-based index of the result.
+func expandPath(tmpl string, addr common.Address, n int) string {
+	return strings.NewReplacer("{addr}", addr.Hex(), "{n}", strconv.Itoa(n)).Replace(tmpl)
+}
+
+// numberPath makes path a template by inserting "-{n}" before its extension, unless it already contains
+// a placeholder.
+func numberPath(path string) string {
+	if hasPlaceholder(path) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-{n}" + ext
+}
+
+// addrPath is like numberPath, but inserts "-{addr}".
+func addrPath(path string) string {
+	if hasPlaceholder(path) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-{addr}" + ext
+}
+
+func hasPlaceholder(path string) bool {
+	return strings.Contains(path, "{addr}") || strings.Contains(path, "{n}")
+}
+
+// isSet reports whether the flag with the given name was set on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// pinCPUs returns the CPUs named by -pin, or nil if it is empty.
+func pinCPUs(spec string) ([]int, error) {
+	switch spec {
+	case "":
+		return nil, nil
+	case "all":
+		return vanity.AllowedCPUs()
+	case "cores":
+		return vanity.CoreCPUs()
+	}
+	return vanity.ParseCPUList(spec)
+}
+
+// selectPart returns part I/N of r, as given to -part.
+func selectPart(r vanity.KeyRange, part string) (vanity.KeyRange, error) {
+	var i, n int
+	if _, err := fmt.Sscanf(part, "%d/%d", &i, &n); err != nil || n < 1 || i < 1 || i > n {
+		return vanity.KeyRange{}, fmt.Errorf("invalid -part %q: want I/N with 1 <= I <= N", part)
+	}
+	return r.Split(n)[i-1], nil
+}
+
+// generateEach runs generate with args once for every pattern read from r, one per line, until r is
+// exhausted or a search is interrupted. the patterns are prefixes, suffixes or, if both are set, a prefix
+// and a suffix separated by whitespace. blank lines and lines starting with # are skipped. lines are read
+// as they are needed, so r can be fed by a program that is still running. patterns that would take too
+// long are skipped by generate itself. the exit status is that of the last search that did not succeed,
+// or exitOK.
+func generateEach(args []string, r io.Reader, prefixes, suffixes bool) int {
+	status := exitOK
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var pArgs []string
+		switch {
+		case prefixes && suffixes && len(fields) == 2:
+			pArgs = []string{"-p", fields[0], "-s", fields[1]}
+		case prefixes && !suffixes && len(fields) == 1:
+			pArgs = []string{"-p", fields[0]}
+		case suffixes && !prefixes && len(fields) == 1:
+			pArgs = []string{"-s", fields[0]}
+		default:
+			slog.Error("skipping malformed pattern line", "line", line)
+			status = exitPattern
+			continue
+		}
+		if err := vanity.ValidatePattern(strings.Join(fields, "")); err != nil {
+			slog.Error("skipping invalid pattern", "line", line, "err", err)
+			status = exitPattern
+			continue
+		}
+		// later flags override earlier ones
+		switch code := generate(append(args[:len(args):len(args)], pArgs...)); code {
+		case exitOK:
+		case exitInterrupted:
+			return code
+		default:
+			status = code
+		}
+	}
+	if err := sc.Err(); err != nil {
+		slog.Error("reading patterns", "err", err)
+		return exitIO
+	}
+	return status
+}
+
+// patternList is the matcher for -patterns or -prefixes.
+type patternList interface {
+	vanity.Matcher
+	Patterns() []string
+}
+
+// readPatternList returns the matcher for the patterns in patternFile or the prefixes in prefixFile,
+// whichever is set.
+func readPatternList(patternFile, prefixFile string, insensitive bool) (patternList, error) {
+	if patternFile != "" {
+		pats, err := readPatterns(patternFile)
+		if err != nil {
+			return nil, err
+		}
+		m := vanity.NewSubstringMatcher(pats, insensitive)
+		return m, m.Validate()
+	}
+	pats, err := readPatterns(prefixFile)
+	if err != nil {
+		return nil, err
+	}
+	m := vanity.NewPrefixSetMatcher(pats, insensitive)
+	if err = m.Validate(); err != nil {
+		return nil, err
+	}
+	slog.Debug("built the prefix filter", "prefixes", len(m.Patterns()), "bytes", m.FilterSize(), "false_positive_rate", m.FalsePositiveRate())
+	return m, nil
+}
+
+// readPatterns reads the patterns for -patterns or -prefixes from path, one per line, skipping blank lines and lines
+// starting with #.
+func readPatterns(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var pats []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			pats = append(pats, line)
+		}
+	}
+	return pats, sc.Err()
+}
+
+// longest returns the longest of pats, which is as many characters as a partial match can have.
+func longest(pats []string) string {
+	return slices.MaxFunc(pats, func(a, b string) int { return len(a) - len(b) })
+}
+
+// quietFlag is the value of -q: empty when quiet mode is off, otherwise "addr" or "path" for what to print.
+// it is a boolean flag, so a bare -q means -q=addr.
+type quietFlag string
+
+func (q *quietFlag) String() string { return string(*q) }
+
+func (q *quietFlag) IsBoolFlag() bool { return true }
+
+func (q *quietFlag) Set(s string) error {
+	switch s {
+	case "true", "addr":
+		*q = "addr"
+	case "false":
+		*q = ""
+	case "path":
+		*q = "path"
+	default:
+		return fmt.Errorf("invalid -q value %q; use -q, -q=addr or -q=path", s)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+func TestCheckOneResult(t *testing.T) {
+	tests := []struct {
+		backend vanity.Backend
+		count   int
+		best    bool
+		ok      bool
+	}{
+		{vanity.Geth, 5, true, true},
+		{vanity.Dcrd, 5, false, true},
+		{vanity.Fast, 1, false, true},
+		{vanity.Walk, 1, false, true},
+		{vanity.Fast, 5, false, false},
+		{vanity.Walk, 2, false, false},
+		{vanity.Fast, 1, true, false},
+		{vanity.Walk, 1, true, false},
+	}
+	for _, tt := range tests {
+		err := checkOneResult(tt.backend, tt.count, tt.best)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkOneResult(%s, %d, %t) = %v, want ok %t", tt.backend, tt.count, tt.best, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanitypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer serves the vanitypb.Vanity service from the same jobs as the REST API.
+type grpcServer struct {
+	vanitypb.UnimplementedVanityServer
+	s *server
+}
+
+// newGRPCServer returns a grpc.Server for s that authenticates callers as the REST API does and passes
+// them to the methods in their contexts.
+func newGRPCServer(s *server) *grpc.Server {
+	auth := func(ctx context.Context) (context.Context, error) {
+		var header string
+		md, _ := grpcmd.FromIncomingContext(ctx)
+		if v := md.Get("authorization"); len(v) == 1 {
+			header = v[0]
+		}
+		c, ok := s.authenticate(header)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+		}
+		return withCaller(ctx, c), nil
+	}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			ctx, err := auth(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return h(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			ctx, err := auth(ss.Context())
+			if err != nil {
+				return err
+			}
+			return h(srv, callerStream{ss, ctx})
+		}),
+	)
+	vanitypb.RegisterVanityServer(srv, grpcServer{s: s})
+	return srv
+}
+
+// callerStream is a grpc.ServerStream whose context carries its caller.
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s callerStream) Context() context.Context { return s.ctx }
+
+func (g grpcServer) SubmitJob(ctx context.Context, req *vanitypb.SubmitJobRequest) (*vanitypb.Job, error) {
+	r := jobRequest{
+		Prefix:          req.Prefix,
+		Suffix:          req.Suffix,
+		CaseInsensitive: req.CaseInsensitive,
+		Backend:         req.Backend,
+		MaxAttempts:     req.MaxAttempts,
+		TimeoutSeconds:  req.Timeout.AsDuration().Seconds(),
+		OneTime:         req.OneTime,
+	}
+	if len(req.PublicKey) > 0 {
+		r.PublicKey = hex.EncodeToString(req.PublicKey)
+	}
+	c := callerFrom(ctx)
+	j, err := g.s.newJob(r, c)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err = g.s.add(j); err != nil {
+		return nil, grpcError(err)
+	}
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+	slog.Info("job queued", "id", j.id, "tenant", j.tenant, "remote", remote)
+	return jobProto(j.snapshot()), nil
+}
+
+func (g grpcServer) GetJob(ctx context.Context, req *vanitypb.GetJobRequest) (*vanitypb.Job, error) {
+	c := callerFrom(ctx)
+	j, err := g.s.get(req.Id, c.tenant)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	st, err := j.view(c)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return jobProto(st), nil
+}
+
+func (g grpcServer) ListJobs(ctx context.Context, _ *vanitypb.ListJobsRequest) (*vanitypb.ListJobsResponse, error) {
+	list := g.s.list(callerFrom(ctx).tenant)
+	resp := &vanitypb.ListJobsResponse{Jobs: make([]*vanitypb.Job, len(list))}
+	for i, st := range list {
+		resp.Jobs[i] = jobProto(st)
+	}
+	return resp, nil
+}
+
+func (g grpcServer) CancelJob(ctx context.Context, req *vanitypb.CancelJobRequest) (*vanitypb.Job, error) {
+	j, err := g.s.cancel(req.Id, callerFrom(ctx).tenant)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return jobProto(j.snapshot()), nil
+}
+
+func (g grpcServer) WatchJob(req *vanitypb.WatchJobRequest, stream vanitypb.Vanity_WatchJobServer) error {
+	c := callerFrom(stream.Context())
+	j, err := g.s.get(req.Id, c.tenant)
+	if err != nil {
+		return grpcError(err)
+	}
+	interval := time.Second
+	if req.Interval != nil {
+		interval = max(req.Interval.AsDuration(), 100*time.Millisecond)
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		if err = stream.Send(jobProto(j.snapshot())); err != nil {
+			return err
+		}
+		select {
+		case <-tick.C:
+		case <-j.done:
+			st, err := j.view(c)
+			if err != nil {
+				return grpcError(err)
+			}
+			return stream.Send(jobProto(st))
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+var jobStates = map[string]vanitypb.Job_State{
+	jobQueued:   vanitypb.Job_STATE_QUEUED,
+	jobRunning:  vanitypb.Job_STATE_RUNNING,
+	jobDone:     vanitypb.Job_STATE_DONE,
+	jobFailed:   vanitypb.Job_STATE_FAILED,
+	jobCanceled: vanitypb.Job_STATE_CANCELED,
+}
+
+// jobProto converts st to its protobuf form.
+func jobProto(st jobStatus) *vanitypb.Job {
+	j := &vanitypb.Job{
+		Id:               st.ID,
+		State:            jobStates[st.Status],
+		Prefix:           st.Prefix,
+		Suffix:           st.Suffix,
+		CaseInsensitive:  st.CaseInsensitive,
+		Backend:          st.Backend,
+		SplitKey:         st.SplitKey,
+		Created:          timestamppb.New(st.Created),
+		Attempts:         st.Attempts,
+		Rate:             st.Rate,
+		ExpectedAttempts: st.ExpectedAttempts,
+		Error:            st.Error,
+	}
+	if st.Started != nil {
+		j.Started = timestamppb.New(*st.Started)
+	}
+	if st.Finished != nil {
+		j.Finished = timestamppb.New(*st.Finished)
+	}
+	if st.ETASeconds != nil {
+		j.Eta = durationpb.New(time.Duration(min(*st.ETASeconds, 1<<62/1e9) * float64(time.Second)))
+	}
+	if st.Best != nil {
+		j.Best = &vanitypb.BestMatch{Address: st.Best.Address, Matched: int32(st.Best.Matched), PatternLength: int32(st.Best.PatternLength)}
+	}
+	if st.Result != nil {
+		j.Result = &vanitypb.Result{Address: st.Result.Address}
+		j.Result.PrivateKey, _ = hex.DecodeString(st.Result.PrivateKey)
+		j.Result.Offset, _ = hex.DecodeString(st.Result.Offset)
+		j.Result.Downloaded = st.Result.Downloaded
+	}
+	return j
+}
+
+// grpcError converts an error from the job code to a gRPC status.
+func grpcError(err error) error {
+	var (
+		he httpError
+		qe quotaError
+	)
+	if errors.As(err, &qe) {
+		return status.Error(codes.ResourceExhausted, qe.Error())
+	}
+	if !errors.As(err, &he) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	code := codes.Unknown
+	switch he.code {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, he.Error())
+}
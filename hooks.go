@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// hookTimeout bounds how long a command hook or webhook may hold up the search.
+const hookTimeout = 30 * time.Second
+
+// hookPayload is the JSON body posted to -webhook when a key is found, or, with -watch-rpc, when its
+// address is funded.
+type hookPayload struct {
+	Event string `json:"event"` // found or funded
+	metadata
+	Path       string `json:"path,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"` // only with -webhook-include-key
+	BalanceWei string `json:"balance_wei,omitempty"` // for funded events
+}
+
+// runCommandHook runs tmpl through the shell after replacing {addr} and {path} with the quoted address
+// and key path. the same values are available to the command as $VANITY_ADDRESS and $VANITY_PATH.
+func runCommandHook(tmpl, addr, path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", strings.NewReplacer("{addr}", addr, "{path}", `"`+path+`"`).Replace(tmpl))
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", strings.NewReplacer("{addr}", shellQuote(addr), "{path}", shellQuote(path)).Replace(tmpl))
+	}
+	cmd.Env = append(os.Environ(), "VANITY_ADDRESS="+addr, "VANITY_PATH="+path)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr // stdout is reserved for results
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("-on-success command: %w", err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// postWebhook posts p to url as JSON.
+func postWebhook(url string, p hookPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vanity/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// desktopNotify shows a desktop notification using the platform's notifyCommand.
+func desktopNotify(title, body string) error {
+	cmd := notifyCommand(title, body)
+	out, err := cmd.CombinedOutput()
+	if out = bytes.TrimSpace(out); err != nil && len(out) > 0 {
+		return fmt.Errorf("desktop notification: %w: %s", err, out)
+	} else if err != nil {
+		return fmt.Errorf("desktop notification: %w", err)
+	}
+	return nil
+}
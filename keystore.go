@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+// EIP-2335 (version 4) keystores, as used by consensus layer tooling.
+// see https://eips.ethereum.org/EIPS/eip-2335
+
+type keystoreModule struct {
+	Function string         `json:"function"`
+	Params   map[string]any `json:"params"`
+	Message  string         `json:"message"`
+}
+
+type keystoreV4 struct {
+	Crypto struct {
+		KDF      keystoreModule `json:"kdf"`
+		Checksum keystoreModule `json:"checksum"`
+		Cipher   keystoreModule `json:"cipher"`
+	} `json:"crypto"`
+	Description string `json:"description"`
+	Pubkey      string `json:"pubkey"`
+	Path        string `json:"path"`
+	UUID        string `json:"uuid"`
+	Version     int    `json:"version"`
+}
+
+// the scrypt parameters are the ones given in the EIP's test vectors, which match geth's standard V3 parameters.
+const (
+	v4ScryptN = 1 << 18
+	v4ScryptR = 8
+	v4ScryptP = 1
+	v4PBKDF2C = 1 << 18
+)
+
+// normalizePassword applies the EIP-2335 password processing: NFKD normalization followed by
+// the removal of C0, C1 and Delete control codes.
+func normalizePassword(pass []byte) []byte {
+	return []byte(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) { // exactly C0, C1 and Delete
+			return -1
+		}
+		return r
+	}, norm.NFKD.String(string(pass))))
+}
+
+// encryptV4 encrypts key into an EIP-2335 keystore. kdf must be either "scrypt" or "pbkdf2".
+func encryptV4(key *ecdsa.PrivateKey, pass []byte, kdf, description string) (*keystoreV4, error) {
+	salt := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	ks := new(keystoreV4)
+	ks.Crypto.KDF = keystoreModule{Function: kdf}
+	var (
+		dk  []byte
+		err error
+	)
+	pass = normalizePassword(pass)
+	switch kdf {
+	case "scrypt":
+		ks.Crypto.KDF.Params = map[string]any{"dklen": 32, "n": v4ScryptN, "r": v4ScryptR, "p": v4ScryptP, "salt": hex.EncodeToString(salt)}
+		dk, err = scrypt.Key(pass, salt, v4ScryptN, v4ScryptR, v4ScryptP, 32)
+		if err != nil {
+			return nil, err
+		}
+	case "pbkdf2":
+		ks.Crypto.KDF.Params = map[string]any{"dklen": 32, "c": v4PBKDF2C, "prf": "hmac-sha256", "salt": hex.EncodeToString(salt)}
+		dk = pbkdf2.Key(pass, salt, v4PBKDF2C, 32, sha256.New)
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf)
+	}
+
+	block, err := aes.NewCipher(dk[:16])
+	if err != nil {
+		return nil, err
+	}
+	secret := crypto.FromECDSA(key)
+	ct := make([]byte, len(secret))
+	cipher.NewCTR(block, iv).XORKeyStream(ct, secret)
+	clear(secret)
+	sum := sha256.Sum256(append(dk[16:32:32], ct...))
+	clear(dk)
+
+	ks.Crypto.Checksum = keystoreModule{Function: "sha256", Params: map[string]any{}, Message: hex.EncodeToString(sum[:])}
+	ks.Crypto.Cipher = keystoreModule{Function: "aes-128-ctr", Params: map[string]any{"iv": hex.EncodeToString(iv)}, Message: hex.EncodeToString(ct)}
+	ks.Description = description
+	ks.Pubkey = hex.EncodeToString(crypto.CompressPubkey(&key.PublicKey))
+	ks.UUID = uuid.NewString()
+	ks.Version = 4
+	return ks, nil
+}
+
+// saveV4 writes key to path as an EIP-2335 keystore.
+func saveV4(path string, key *ecdsa.PrivateKey, pass []byte, kdf, description string) error {
+	ks, err := encryptV4(key, pass, kdf, description)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0600)
+}
+
+// saveHex writes key to path in hex, as crypto.SaveECDSA does, but without leaving copies of it in the
+// heap: the buffers it encodes the key into are cleared once they have been written.
+func saveHex(path string, key *ecdsa.PrivateKey) error {
+	secret := crypto.FromECDSA(key)
+	defer clear(secret)
+	buf := make([]byte, hex.EncodedLen(len(secret)))
+	defer clear(buf)
+	hex.Encode(buf, secret)
+	return os.WriteFile(path, buf, 0600)
+}
+
+// saveFoundry writes key to path as a version 3 keystore, the format of Foundry's keystore directory,
+// which 'cast wallet' and 'forge script --account NAME' read from NAME in it. like 'cast wallet import',
+// it never replaces an existing account.
+func saveFoundry(path string, key *ecdsa.PrivateKey, pass []byte) error {
+	ks, err := keystore.EncryptKey(&keystore.Key{Id: uuid.New(), Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key}, string(pass), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(ks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// foundryKeystores returns Foundry's keystore directory, ~/.foundry/keystores, creating it if need be.
+func foundryKeystores() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".foundry", "keystores")
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+var errWrongPassphrase = errors.New("could not decrypt key with the given passphrase")
+
+// decryptV4 decrypts an EIP-2335 keystore.
+func decryptV4(data, pass []byte) (*ecdsa.PrivateKey, error) {
+	var ks keystoreV4
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, err
+	}
+	if ks.Version != 4 {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	kp := ks.Crypto.KDF.Params
+	salt, err := hexParam(kp, "salt")
+	if err != nil {
+		return nil, err
+	}
+	dklen, err := intParam(kp, "dklen")
+	if err != nil {
+		return nil, err
+	}
+	if dklen < 32 {
+		return nil, fmt.Errorf("kdf dklen %d is too short", dklen)
+	}
+
+	var dk []byte
+	pass = normalizePassword(pass)
+	switch ks.Crypto.KDF.Function {
+	case "scrypt":
+		var n, r, p int
+		if n, err = intParam(kp, "n"); err != nil {
+			return nil, err
+		}
+		if r, err = intParam(kp, "r"); err != nil {
+			return nil, err
+		}
+		if p, err = intParam(kp, "p"); err != nil {
+			return nil, err
+		}
+		if dk, err = scrypt.Key(pass, salt, n, r, p, dklen); err != nil {
+			return nil, err
+		}
+	case "pbkdf2":
+		if prf, _ := kp["prf"].(string); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", prf)
+		}
+		c, err := intParam(kp, "c")
+		if err != nil {
+			return nil, err
+		}
+		dk = pbkdf2.Key(pass, salt, c, dklen, sha256.New)
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", ks.Crypto.KDF.Function)
+	}
+
+	ct, err := hex.DecodeString(ks.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, err
+	}
+	want, err := hex.DecodeString(ks.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Crypto.Checksum.Function != "sha256" {
+		return nil, fmt.Errorf("unsupported checksum function %q", ks.Crypto.Checksum.Function)
+	}
+	if sum := sha256.Sum256(append(dk[16:32:32], ct...)); !bytes.Equal(sum[:], want) {
+		return nil, errWrongPassphrase
+	}
+
+	if ks.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher %q", ks.Crypto.Cipher.Function)
+	}
+	iv, err := hexParam(ks.Crypto.Cipher.Params, "iv")
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("cipher iv is %d bytes, not %d", len(iv), aes.BlockSize)
+	}
+	block, err := aes.NewCipher(dk[:16])
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, len(ct))
+	cipher.NewCTR(block, iv).XORKeyStream(secret, ct)
+	return crypto.ToECDSA(secret)
+}
+
+func intParam(params map[string]any, name string) (int, error) {
+	f, ok := params[name].(float64)
+	if !ok || f != math.Trunc(f) || f < 1 || f > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid keystore parameter %q", name)
+	}
+	return int(f), nil
+}
+
+func hexParam(params map[string]any, name string) ([]byte, error) {
+	s, ok := params[name].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid keystore parameter %q", name)
+	}
+	return hex.DecodeString(s)
+}
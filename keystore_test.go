@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// the test vectors of EIP-2335, whose secret happens to be a valid secp256k1 key as well as a BLS one.
+const (
+	v4TestPassword = "𝔱𝔢𝔰𝔱𝔭𝔞𝔰𝔰𝔴𝔬𝔯𝔡🔑"
+	v4TestSecret   = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+)
+
+var v4TestVectors = map[string]string{
+	"scrypt": `{
+		"crypto": {
+			"kdf": {
+				"function": "scrypt",
+				"params": {"dklen": 32, "n": 262144, "p": 1, "r": 8, "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"},
+				"message": ""
+			},
+			"checksum": {"function": "sha256", "params": {}, "message": "d2217fe5f3e9a1e34581ef8a78f7c9928e436d36dacc5e846690a5581e8ea484"},
+			"cipher": {
+				"function": "aes-128-ctr",
+				"params": {"iv": "264daa3f303d7259501c93d997d84fe6"},
+				"message": "06ae90d55fe0a6e9c5c3bc5b170827b2e5cce3929ed3f116c2811e6366dfe20f"
+			}
+		},
+		"description": "This is a test keystore that uses scrypt to secure the secret.",
+		"pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+		"path": "m/12381/60/3141592653/589793238",
+		"uuid": "1d85ae20-35c5-4611-98e8-aa14a633906f",
+		"version": 4
+	}`,
+	"pbkdf2": `{
+		"crypto": {
+			"kdf": {
+				"function": "pbkdf2",
+				"params": {"dklen": 32, "c": 262144, "prf": "hmac-sha256", "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"},
+				"message": ""
+			},
+			"checksum": {"function": "sha256", "params": {}, "message": "8a9f5d9912ed7e75ea794bc5a89bca5f193721d30868ade6f73043c6ea6febf1"},
+			"cipher": {
+				"function": "aes-128-ctr",
+				"params": {"iv": "264daa3f303d7259501c93d997d84fe6"},
+				"message": "cee03fde2af33149775b7223e7845e4fb2c8ae1792e5f99fe9ecf474cc8c16ad"
+			}
+		},
+		"description": "This is a test keystore that uses PBKDF2 to secure the secret.",
+		"pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+		"path": "m/12381/60/0/0",
+		"uuid": "64625def-3331-4eea-ab6f-782f3ed16a83",
+		"version": 4
+	}`,
+}
+
+func TestDecryptV4Vectors(t *testing.T) {
+	for kdf, data := range v4TestVectors {
+		t.Run(kdf, func(t *testing.T) {
+			key, err := decryptV4([]byte(data), []byte(v4TestPassword))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(crypto.FromECDSA(key)); got != v4TestSecret {
+				t.Errorf("secret = %s, want %s", got, v4TestSecret)
+			}
+			if _, err = decryptV4([]byte(data), []byte("wrong")); !errors.Is(err, errWrongPassphrase) {
+				t.Errorf("wrong passphrase: err = %v, want %v", err, errWrongPassphrase)
+			}
+		})
+	}
+}
+
+func TestV4RoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass := []byte("correct horse battery staple")
+	for _, kdf := range []string{"scrypt", "pbkdf2"} {
+		t.Run(kdf, func(t *testing.T) {
+			ks, err := encryptV4(key, pass, kdf, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(ks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decryptV4(data, pass)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(key) {
+				t.Error("decrypted key differs from the one encrypted")
+			}
+		})
+	}
+}
+
+func TestDecryptV4BadIV(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := encryptV4(key, nil, "pbkdf2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the checksum does not cover the iv, so a truncated one gets as far as the cipher
+	ks.Crypto.Cipher.Params["iv"] = "264daa3f"
+	data, err := json.Marshal(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = decryptV4(data, nil); err == nil {
+		t.Error("decrypted a keystore with a 4-byte iv")
+	}
+}
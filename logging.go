@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// logOptions holds the flags that configure the default slog logger.
+type logOptions struct {
+	format *string
+	level  *string
+	file   *string
+	f      *os.File // the opened -log-file
+}
+
+func addLogFlags(fs *flag.FlagSet) *logOptions {
+	return &logOptions{
+		format: fs.String("log-format", "plain", "log format: plain, text (key=value pairs) or json"),
+		level:  fs.String("log-level", "info", "minimum level of logged messages: debug, info, warn or error"),
+		file:   fs.String("log-file", "", "append log messages to this file instead of writing them to stderr"),
+	}
+}
+
+// setup configures the default logger to write to w, or to the -log-file if one was given. with quiet,
+// only errors are written to w; a log file still receives everything at the configured level. setup may
+// be called again to change w.
+func (o *logOptions) setup(w io.Writer, quiet bool) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*o.level)); err != nil {
+		return fmt.Errorf("invalid log level %q", *o.level)
+	}
+	if *o.file != "" {
+		if o.f == nil {
+			f, err := os.OpenFile(*o.file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+			if err != nil {
+				return err
+			}
+			o.f = f
+		}
+		w = o.f
+	} else if quiet {
+		level = max(level, slog.LevelError)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch *o.format {
+	case "plain":
+		// the default handler, which writes through the log package
+		log.SetOutput(w)
+		slog.SetLogLoggerLevel(level)
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	default:
+		return fmt.Errorf("unknown log format %q", *o.format)
+	}
+	return nil
+}
+
+// close closes the -log-file, if one was opened.
+func (o *logOptions) close() {
+	if o.f != nil {
+		o.f.Close()
+	}
+}
+
+// fatal logs err and exits with the status exitCode gives for it.
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(exitCode(err))
+}
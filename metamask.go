@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// MetaMask imports accounts either from a raw private key, 64 hex digits with or without 0x, or from a
+// "JSON File": a version 3 keystore with the aes-128-ctr cipher and the scrypt or pbkdf2 (hmac-sha256)
+// kdf, whose MAC is keccak-256 of the derived key's second half and the ciphertext. version 4 keystores
+// (eip2335) are not accepted.
+
+// metamaskImport is the JSON sidecar written next to the keystore, telling how to import it.
+type metamaskImport struct {
+	Address  string   `json:"address"`
+	File     string   `json:"file"`
+	Type     string   `json:"type"` // as named in MetaMask's "Select type" menu
+	Format   string   `json:"format"`
+	Steps    []string `json:"steps"`
+	Version  string   `json:"version"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// metamask implements the metamask subcommand, which exports a key in the form MetaMask imports.
+func metamask(args []string) int {
+	fs := flag.NewFlagSet("metamask", flag.ExitOnError)
+	var (
+		in       *string = fs.String("k", "priv.key", "path of the key file to export: hex, keystore (v3 or eip2335) or mnemonic")
+		out      *string = fs.String("o", ".", "directory to write ADDRESS.json, the keystore, and ADDRESS.metamask.json, the import steps, to")
+		inPass   *string = fs.String("in-pass", "", "file containing the passphrase of an encrypted input key (defaults to $VANITY_PASSPHRASE), or the BIP-39 passphrase for mnemonics")
+		passFile *string = fs.String("pass", "", "file containing the passphrase to encrypt the keystore with, which MetaMask asks for on import (defaults to $VANITY_PASSPHRASE)")
+		hdPath   *string = fs.String("path", accounts.DefaultBaseDerivationPath.String(), "BIP-32 derivation path for mnemonics")
+		raw      *bool   = fs.Bool("raw", false, "print the raw private key for MetaMask's \"Private Key\" import instead, after confirming on a terminal")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s metamask [flags]\n\nexports a key file as a keystore that MetaMask's \"Import account\" accepts, with the steps to import it.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fatal(err)
+	}
+	key, err := loadKey(data, *inPass, *hdPath)
+	clear(data)
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(key)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	if *raw {
+		if err = printRawKey(key, addr); err != nil {
+			fatal(usageError{err})
+		}
+		return exitOK
+	}
+
+	pass, err := readPassphrase(*passFile)
+	if err != nil {
+		fatal(err)
+	}
+	if err = os.MkdirAll(*out, 0700); err != nil {
+		fatal(err)
+	}
+	name := addr.Hex() + ".json"
+	ksPath := filepath.Join(*out, name)
+	ks, err := keystore.EncryptKey(&keystore.Key{Id: uuid.New(), Address: addr, PrivateKey: key}, string(pass), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		fatal(err)
+	}
+	if err = os.WriteFile(ksPath, ks, 0600); err != nil {
+		fatal(err)
+	}
+	if err = checkMetaMaskKeystore(ksPath, pass, addr); err != nil {
+		fatal(err)
+	}
+
+	steps := metamaskImport{
+		Address: addr.Hex(),
+		File:    name,
+		Type:    "JSON File",
+		Format:  "keystore v3, scrypt, aes-128-ctr",
+		Steps: []string{
+			"Open MetaMask and unlock it.",
+			"Click the account selector at the top, then \"Add account or hardware wallet\", then \"Import account\".",
+			"Under \"Select type\", choose \"JSON File\".",
+			fmt.Sprintf("Click \"Choose file\" and select %s.", name),
+			"Enter the passphrase the keystore was encrypted with, and click \"Import\". Decrypting it can take a minute.",
+			fmt.Sprintf("Check that the imported account's address is %s before sending funds to it.", addr.Hex()),
+		},
+		Version: version,
+	}
+	if len(pass) < 8 {
+		steps.Warnings = append(steps.Warnings, "the passphrase is shorter than 8 characters; anyone who gets the keystore can guess it")
+	}
+	b, err := json.MarshalIndent(steps, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(*out, addr.Hex()+".metamask.json"), append(b, '\n'), 0600); err != nil {
+		fatal(err)
+	}
+	for _, w := range steps.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	fmt.Println(ksPath)
+	return exitOK
+}
+
+// checkMetaMaskKeystore reads back the keystore at path and checks that MetaMask can import it as the key
+// of addr: that it has the version, cipher and kdf MetaMask accepts, and that it decrypts to that key.
+func checkMetaMaskKeystore(path string, pass []byte, addr common.Address) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var ks struct {
+		Version int    `json:"version"`
+		Address string `json:"address"`
+		Crypto  struct {
+			Cipher    string         `json:"cipher"`
+			KDF       string         `json:"kdf"`
+			KDFParams map[string]any `json:"kdfparams"`
+		} `json:"crypto"`
+	}
+	if err = json.Unmarshal(data, &ks); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case ks.Version != 3:
+		return fmt.Errorf("%s: MetaMask only imports version 3 keystores, not version %d", path, ks.Version)
+	case ks.Crypto.Cipher != "aes-128-ctr":
+		return fmt.Errorf("%s: MetaMask does not support the %q cipher", path, ks.Crypto.Cipher)
+	case ks.Crypto.KDF == "pbkdf2" && ks.Crypto.KDFParams["prf"] != "hmac-sha256":
+		return fmt.Errorf("%s: MetaMask only supports pbkdf2 with hmac-sha256", path)
+	case ks.Crypto.KDF != "scrypt" && ks.Crypto.KDF != "pbkdf2":
+		return fmt.Errorf("%s: MetaMask does not support the %q kdf", path, ks.Crypto.KDF)
+	case !strings.EqualFold(ks.Address, hex.EncodeToString(addr[:])):
+		return fmt.Errorf("%s: the keystore is for 0x%s, not %s", path, ks.Address, addr.Hex())
+	}
+	k, err := keystore.DecryptKey(data, string(pass))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer vanity.ZeroKey(k.PrivateKey)
+	return checkScalar(crypto.FromECDSA(k.PrivateKey), addr)
+}
+
+var errRawKeyNotConfirmed = errors.New("not printing the raw key")
+
+// printRawKey prints key as MetaMask's "Private Key" import takes it, once the user has confirmed on a
+// terminal that they mean to see it: it is then only as safe as the terminal's scrollback.
+func printRawKey(key *ecdsa.PrivateKey, addr common.Address) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("%w: -raw needs a terminal to confirm on and print to", errRawKeyNotConfirmed)
+	}
+	fmt.Fprintf(os.Stderr, "the raw private key of %s will be printed. anyone who sees it, or the terminal's\nscrollback, can take the funds sent to the address. print it? [y/N] ", addr.Hex())
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(line)); a != "y" && a != "yes" {
+		return errRawKeyNotConfirmed
+	}
+	d := crypto.FromECDSA(key)
+	defer clear(d)
+	buf := make([]byte, hex.EncodedLen(len(d)))
+	defer clear(buf)
+	hex.Encode(buf, d)
+	fmt.Fprintf(os.Stdout, "%s\n", buf)
+	fmt.Fprintln(os.Stderr, "in MetaMask: account selector > \"Add account or hardware wallet\" > \"Import account\" > \"Private Key\", paste the key and click \"Import\"; then clear the terminal.")
+	return nil
+}
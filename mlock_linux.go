@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockMemory locks the process's memory, now and as it grows, so that no copy of a key it makes is
+// ever written to swap. the Go runtime cannot survive failing to map memory, which locked memory does
+// once it reaches the memlock limit, so it refuses unless the limit is unlimited or the process is
+// root, which is exempt.
+func lockMemory() error {
+	var lim unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &lim); err != nil {
+		return err
+	}
+	if lim.Cur != unix.RLIM_INFINITY && os.Geteuid() != 0 {
+		return fmt.Errorf("the memlock limit is %d bytes; raise it with ulimit -l unlimited or run as root", lim.Cur)
+	}
+	// pages are locked as they are first touched rather than all at once, which would make resident the
+	// whole of the runtime's reservations and every thread's stack
+	return unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE | unix.MCL_ONFAULT)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// locking the whole process elsewhere needs mlockall's MCL_FUTURE, which other platforms lack or, like
+// VirtualLock on Windows, only offer per range.
+func lockMemory() error {
+	return fmt.Errorf("locking memory is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// in a two-party search, the host and the joiner each generate a share of the key, d1 and d2, and
+// exchange only their public keys, P1 and P2. both then run a split-key search against P1 + P2, and the
+// offset k that one of them finds is added to the host's share, so that the key of the address found is
+// d1 + k + d2 and neither machine ever holds it. the shares are combined later with
+// 'splitkey -k HOST_SHARE -offset JOIN_SHARE'.
+//
+// the public keys are committed to before either is revealed, so that neither party can choose its own
+// to cancel out the other's, and each is revealed with a signature proving its sender holds its share.
+// the channel is not authenticated: both parties print a fingerprint of the exchange, which they must
+// compare out of band to rule out anyone in the middle.
+
+// mpcHandshakeTimeout bounds the exchange of shares, before the search starts.
+const mpcHandshakeTimeout = time.Minute
+
+// mpcMessage is a message of the two-party protocol, sent as a line of JSON.
+type mpcMessage struct {
+	Type        string        `json:"type"` // hello, commit, reveal, found, final or error
+	Version     string        `json:"version,omitempty"`
+	Prefix      string        `json:"prefix,omitempty"`
+	Suffix      string        `json:"suffix,omitempty"`
+	Insensitive bool          `json:"insensitive,omitempty"`
+	Commitment  hexutil.Bytes `json:"commitment,omitempty"`
+	Nonce       hexutil.Bytes `json:"nonce,omitempty"`
+	PublicKey   hexutil.Bytes `json:"public_key,omitempty"` // compressed
+	Proof       hexutil.Bytes `json:"proof,omitempty"`
+	Offset      hexutil.Bytes `json:"offset,omitempty"`
+	Address     string        `json:"address,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// mpcPeer is the connection to the other party.
+type mpcPeer struct {
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+func newMPCPeer(conn net.Conn) *mpcPeer {
+	return &mpcPeer{conn: conn, enc: json.NewEncoder(conn), dec: json.NewDecoder(bufio.NewReader(conn))}
+}
+
+func (p *mpcPeer) send(m mpcMessage) error { return p.enc.Encode(m) }
+
+// recv returns the next message, which must be of type want.
+func (p *mpcPeer) recv(want string) (mpcMessage, error) {
+	var m mpcMessage
+	if err := p.dec.Decode(&m); err != nil {
+		return m, fmt.Errorf("reading from the other party: %w", err)
+	}
+	switch m.Type {
+	case want:
+		return m, nil
+	case "error":
+		return m, fmt.Errorf("the other party stopped: %s", m.Error)
+	}
+	return m, fmt.Errorf("expected a %s message from the other party, got %q", want, m.Type)
+}
+
+// exchange sends m and receives the other party's message of the same type, sending first if first is
+// set.
+func (p *mpcPeer) exchange(m mpcMessage, first bool) (mpcMessage, error) {
+	if first {
+		if err := p.send(m); err != nil {
+			return mpcMessage{}, err
+		}
+		return p.recv(m.Type)
+	}
+	got, err := p.recv(m.Type)
+	if err != nil {
+		return got, err
+	}
+	return got, p.send(m)
+}
+
+// fail tells the other party why the session is over, as well as it can.
+func (p *mpcPeer) fail(err error) {
+	p.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	p.send(mpcMessage{Type: "error", Error: err.Error()})
+}
+
+// mpcShare is one party's share of the key.
+type mpcShare struct {
+	key   *ecdsa.PrivateKey
+	nonce []byte // hides the public key in the commitment
+}
+
+func newMPCShare() (*mpcShare, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 32)
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &mpcShare{key: key, nonce: nonce}, nil
+}
+
+func (s *mpcShare) public() []byte { return crypto.CompressPubkey(&s.key.PublicKey) }
+
+func mpcCommitment(nonce, pub []byte) []byte { return crypto.Keccak256(nonce, pub) }
+
+// proofHash is what a party signs with its share to prove it holds it, bound to both commitments so
+// that it cannot be replayed in another session.
+func proofHash(hostCommit, joinCommit, pub []byte) []byte {
+	return crypto.Keccak256([]byte("vanity two-party share"), hostCommit, joinCommit, pub)
+}
+
+// mpcHandshake exchanges commitments to the two shares and then the shares' public keys, and returns
+// the combined public key and the fingerprint of the exchange.
+func mpcHandshake(p *mpcPeer, own *mpcShare, host bool) (*ecdsa.PublicKey, string, error) {
+	p.conn.SetDeadline(time.Now().Add(mpcHandshakeTimeout))
+	defer p.conn.SetDeadline(time.Time{})
+	pub := own.public()
+	commit := mpcCommitment(own.nonce, pub)
+	theirs, err := p.exchange(mpcMessage{Type: "commit", Commitment: commit}, host)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(theirs.Commitment) != 32 {
+		return nil, "", errors.New("the other party sent an invalid commitment")
+	}
+	hostCommit, joinCommit := commit, []byte(theirs.Commitment)
+	if !host {
+		hostCommit, joinCommit = joinCommit, hostCommit
+	}
+	proof, err := crypto.Sign(proofHash(hostCommit, joinCommit, pub), own.key)
+	if err != nil {
+		return nil, "", err
+	}
+	reveal, err := p.exchange(mpcMessage{Type: "reveal", Nonce: own.nonce, PublicKey: pub, Proof: proof}, host)
+	if err != nil {
+		return nil, "", err
+	}
+	if !bytes.Equal(mpcCommitment(reveal.Nonce, reveal.PublicKey), theirs.Commitment) {
+		return nil, "", errors.New("the other party's public key does not match its commitment")
+	}
+	other, err := crypto.DecompressPubkey(reveal.PublicKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("the other party's public key: %w", err)
+	}
+	signer, err := crypto.SigToPub(proofHash(hostCommit, joinCommit, reveal.PublicKey), reveal.Proof)
+	if err != nil || !signer.Equal(other) {
+		return nil, "", errors.New("the other party did not prove that it holds its share")
+	}
+	combined, err := addPublicKeys(&own.key.PublicKey, other)
+	if err != nil {
+		return nil, "", err
+	}
+	hostPub, joinPub := pub, []byte(reveal.PublicKey)
+	if !host {
+		hostPub, joinPub = joinPub, hostPub
+	}
+	fp := crypto.Keccak256(hostCommit, joinCommit, hostPub, joinPub)
+	return combined, fmt.Sprintf("%x-%x-%x", fp[0:2], fp[2:4], fp[4:6]), nil
+}
+
+// addPublicKeys returns a + b.
+func addPublicKeys(a, b *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+	var pa, pb secp256k1.JacobianPoint
+	for _, k := range []struct {
+		pub *ecdsa.PublicKey
+		p   *secp256k1.JacobianPoint
+	}{{a, &pa}, {b, &pb}} {
+		parsed, err := secp256k1.ParsePubKey(crypto.FromECDSAPub(k.pub))
+		if err != nil {
+			return nil, err
+		}
+		parsed.AsJacobian(k.p)
+	}
+	secp256k1.AddNonConst(&pa, &pb, &pa)
+	if (pa.X.IsZero() && pa.Y.IsZero()) || pa.Z.IsZero() {
+		return nil, errors.New("the shares cancel out")
+	}
+	pa.ToAffine()
+	return secp256k1.NewPublicKey(&pa.X, &pa.Y).ToECDSA(), nil
+}
+
+// splitSearch returns a split-key search against the public key pub.
+func splitSearch(pub *ecdsa.PublicKey, prefix, suffix string, insensitive bool, workers int) (*vanity.Searcher, error) {
+	src, err := vanity.SplitKeySource(pub)
+	if err != nil {
+		return nil, err
+	}
+	return vanity.New(
+		vanity.WithPrefix(prefix),
+		vanity.WithSuffix(suffix),
+		vanity.WithCaseInsensitive(insensitive),
+		vanity.WithWorkers(workers),
+		vanity.WithKeySource(src),
+	)
+}
+
+// checkOffset checks that offset gives a matching address with the combined public key, and returns
+// the address.
+func checkOffset(search *vanity.Searcher, combined *ecdsa.PublicKey, offset []byte) (common.Address, error) {
+	addr, err := vanity.SplitKeyAddress(combined, offset)
+	if err != nil {
+		return addr, err
+	}
+	if !search.Matches(addr) {
+		return addr, fmt.Errorf("the offset gives %s, which does not match", addr.Hex())
+	}
+	return addr, nil
+}
+
+// saveShare writes share to path as hex and checks it.
+func saveShare(path string, share *ecdsa.PrivateKey) string {
+	return saveChecked(path, func(path string) error {
+		if err := saveHex(path, share); err != nil {
+			return err
+		}
+		return checkKeyFile(path, "hex", nil, crypto.PubkeyToAddress(share.PublicKey))
+	})
+}
+
+// mpcFlags are the flags that host and join share.
+type mpcFlags struct {
+	out     *string
+	workers *int
+}
+
+func addMPCFlags(fs *flag.FlagSet, out string) mpcFlags {
+	return mpcFlags{
+		out:     fs.String("o", out, "output path of this party's share of the key"),
+		workers: fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines"),
+	}
+}
+
+// host implements the host subcommand, the side of a two-party search that sets the pattern and
+// waits for the other party to join.
+func host(args []string) int {
+	fs := flag.NewFlagSet("host", flag.ExitOnError)
+	var (
+		listen      *string = fs.String("listen", ":9735", "address to listen for the other party on")
+		prefix      *string = fs.String("p", "", "output address prefix (excluding 0x)")
+		suffix      *string = fs.String("s", "", "output address suffix")
+		insensitive *bool   = fs.Bool("i", false, "accept case-insensitive solutions")
+		mf                  = addMPCFlags(fs, "host.share")
+	)
+	var timeOut timeoutFlag
+	fs.Var(&timeOut, "t", "maximum acceptable search time, as a duration (90m, 2h30m) or a number of seconds")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s host [flags]\n\nsearches for a key together with another machine running '%[1]s join', so that neither ever\nholds the whole key: each keeps a share, and the shares are combined with\n'%[1]s splitkey -k HOST_SHARE -offset JOIN_SHARE'.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if err := vanity.ValidatePattern(*prefix + *suffix); err != nil {
+		fatal(err)
+	}
+	if timeOut == 0 {
+		// split-key searches walk from the offset, as the walk backend does
+		err := expectLong(vanity.Difficulty(*prefix, *suffix, !*insensitive), vanity.Walk, *mf.workers, *insensitive)
+		if errors.Is(err, vanity.ErrTooLong) {
+			err = fmt.Errorf("%w; set a timeout with the -t flag if you wish to continue", err)
+		}
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if err := confirmDestination(*mf.out, false, true); err != nil {
+		fatal(usageError{err})
+	}
+	share, err := newMPCShare()
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(share.key)
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fatal(err)
+	}
+	slog.Info("waiting for the other party", "listen", ln.Addr().String())
+	conn, err := ln.Accept()
+	ln.Close() // one party only
+	if err != nil {
+		fatal(err)
+	}
+	defer conn.Close()
+	p := newMPCPeer(conn)
+	slog.Info("the other party connected", "from", conn.RemoteAddr().String())
+	if err = p.send(mpcMessage{Type: "hello", Version: version, Prefix: *prefix, Suffix: *suffix, Insensitive: *insensitive}); err != nil {
+		fatal(err)
+	}
+	combined, fp, err := mpcHandshake(p, share, true)
+	if err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+	slog.Info("shares exchanged; check that the other party sees the same fingerprint", "fingerprint", fp)
+	search, err := splitSearch(combined, *prefix, *suffix, *insensitive, *mf.workers)
+	if err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeOut > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeOut))
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// the offset is the first found, here or by the other party
+	offsets := make(chan []byte, 2)
+	errc := make(chan error, 2)
+	go func() {
+		res, err := search.Run(ctx)
+		if err != nil {
+			errc <- err
+			return
+		}
+		offsets <- math.PaddedBigBytes(res.Key.D, 32)
+	}()
+	go func() {
+		for {
+			m, err := p.recv("found")
+			if err != nil {
+				errc <- err
+				return
+			}
+			if _, err = checkOffset(search, combined, m.Offset); err != nil {
+				slog.Warn("the other party sent a bad offset", "err", err)
+				continue
+			}
+			offsets <- m.Offset
+		}
+	}()
+	slog.Info("searching", "prefix", *prefix, "suffix", *suffix)
+	var offset []byte
+	select {
+	case offset = <-offsets:
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	cancel()
+	if err != nil {
+		p.fail(err)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			slog.Error("timed out", "attempts", search.Attempts())
+			return exitGaveUp
+		case errors.Is(err, context.Canceled):
+			slog.Info("interrupted", "attempts", search.Attempts())
+			return exitInterrupted
+		}
+		fatal(err)
+	}
+	addr, err := checkOffset(search, combined, offset)
+	if err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+	ownShare, err := vanity.CombineSplitKey(share.key, offset)
+	if err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+	defer vanity.ZeroKey(ownShare)
+	if err = p.send(mpcMessage{Type: "final", Offset: offset, Address: addr.Hex()}); err != nil {
+		fatal(fmt.Errorf("could not tell the other party the result: %w", err))
+	}
+	path := saveShare(*mf.out, ownShare)
+	fmt.Println(addr.Hex())
+	slog.Info("saved this party's share of the key", "path", path, "attempts", search.Attempts(), "combine", fmt.Sprintf("%s splitkey -k %s -offset JOIN_SHARE -expect %s", os.Args[0], path, addr.Hex()))
+	return exitOK
+}
+
+// join implements the join subcommand, the side of a two-party search that connects to the host.
+func join(args []string) int {
+	fs := flag.NewFlagSet("join", flag.ExitOnError)
+	var (
+		hostAddr *string = fs.String("host", "", "address of the machine running 'host', such as 192.0.2.1:9735")
+		mf               = addMPCFlags(fs, "join.share")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s join -host ADDRESS [flags]\n\njoins a two-party search started with '%[1]s host', which sets the pattern. this machine keeps a\nshare of the key, which the host's share is combined with.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *hostAddr == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := confirmDestination(*mf.out, false, true); err != nil {
+		fatal(usageError{err})
+	}
+	share, err := newMPCShare()
+	if err != nil {
+		fatal(err)
+	}
+	defer vanity.ZeroKey(share.key)
+
+	conn, err := net.DialTimeout("tcp", *hostAddr, mpcHandshakeTimeout)
+	if err != nil {
+		fatal(err)
+	}
+	defer conn.Close()
+	p := newMPCPeer(conn)
+	hello, err := p.recv("hello")
+	if err != nil {
+		fatal(err)
+	}
+	if err = vanity.ValidatePattern(hello.Prefix + hello.Suffix); err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+	slog.Info("joined", "host", conn.RemoteAddr().String(), "version", hello.Version, "prefix", hello.Prefix, "suffix", hello.Suffix, "case_sensitive", !hello.Insensitive)
+	combined, fp, err := mpcHandshake(p, share, false)
+	if err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+	slog.Info("shares exchanged; check that the other party sees the same fingerprint", "fingerprint", fp)
+	search, err := splitSearch(combined, hello.Prefix, hello.Suffix, hello.Insensitive, *mf.workers)
+	if err != nil {
+		p.fail(err)
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan []byte, 1)
+	go func() {
+		if res, err := search.Run(ctx); err == nil {
+			found <- math.PaddedBigBytes(res.Key.D, 32)
+		}
+	}()
+	finals := make(chan mpcMessage, 1)
+	errc := make(chan error, 1)
+	go func() {
+		m, err := p.recv("final")
+		if err != nil {
+			errc <- err
+			return
+		}
+		finals <- m
+	}()
+	var final mpcMessage
+	for final.Type == "" && err == nil {
+		select {
+		case offset := <-found:
+			// the host decides, as it may have found one too
+			slog.Info("found an offset; waiting for the host")
+			err = p.send(mpcMessage{Type: "found", Offset: offset})
+		case final = <-finals:
+		case err = <-errc:
+		case <-ctx.Done():
+			p.fail(errors.New("interrupted"))
+			slog.Info("interrupted", "attempts", search.Attempts())
+			return exitInterrupted
+		}
+	}
+	cancel()
+	if err != nil {
+		fatal(err)
+	}
+	addr, err := checkOffset(search, combined, final.Offset)
+	if err != nil {
+		fatal(fmt.Errorf("the host's result: %w", err))
+	}
+	if final.Address != addr.Hex() {
+		fatal(fmt.Errorf("the host reported %s, but the offset gives %s", final.Address, addr.Hex()))
+	}
+	path := saveShare(*mf.out, share.key)
+	fmt.Println(addr.Hex())
+	slog.Info("saved this party's share of the key", "path", path, "attempts", search.Attempts(), "combine", fmt.Sprintf("%s splitkey -k HOST_SHARE -offset \"$(cat %s)\" -expect %s", os.Args[0], path, addr.Hex()))
+	return exitOK
+}
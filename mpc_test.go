@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestMPCHandshake runs a host against a joiner that follows the protocol, or deviates from it as
+// cheat says, and checks that the host rejects every deviation.
+func TestMPCHandshake(t *testing.T) {
+	tests := []struct {
+		name  string
+		cheat func(j *mpcShare, hostCommit []byte, commit, reveal *mpcMessage)
+		err   string // in the host's error, or empty if the handshake must succeed
+	}{
+		{"honest", func(*mpcShare, []byte, *mpcMessage, *mpcMessage) {}, ""},
+		{"short commitment", func(_ *mpcShare, _ []byte, commit, _ *mpcMessage) {
+			commit.Commitment = commit.Commitment[:31]
+		}, "invalid commitment"},
+		{"other public key", func(_ *mpcShare, _ []byte, _, reveal *mpcMessage) {
+			// a key chosen after seeing the host's, as one cancelling it out would be
+			other, _ := newMPCShare()
+			reveal.PublicKey = other.public()
+		}, "does not match its commitment"},
+		{"other nonce", func(_ *mpcShare, _ []byte, _, reveal *mpcMessage) {
+			reveal.Nonce = append([]byte{reveal.Nonce[0] ^ 1}, reveal.Nonce[1:]...)
+		}, "does not match its commitment"},
+		{"key not held", func(_ *mpcShare, hostCommit []byte, commit, reveal *mpcMessage) {
+			// commits to and reveals a key, but signs with another
+			other, _ := newMPCShare()
+			reveal.Proof, _ = crypto.Sign(proofHash(hostCommit, commit.Commitment, reveal.PublicKey), other.key)
+		}, "did not prove"},
+		{"proof from another session", func(j *mpcShare, _ []byte, commit, reveal *mpcMessage) {
+			reveal.Proof, _ = crypto.Sign(proofHash(make([]byte, 32), commit.Commitment, reveal.PublicKey), j.key)
+		}, "did not prove"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostConn, joinConn := net.Pipe()
+			defer hostConn.Close()
+			defer joinConn.Close()
+			host, err := newMPCShare()
+			if err != nil {
+				t.Fatal(err)
+			}
+			join, err := newMPCShare()
+			if err != nil {
+				t.Fatal(err)
+			}
+			go func() {
+				// the joiner's side of mpcHandshake, with the messages changed by cheat before sending
+				p := newMPCPeer(joinConn)
+				theirs, err := p.recv("commit")
+				if err != nil {
+					return
+				}
+				pub := join.public()
+				commit := mpcMessage{Type: "commit", Commitment: mpcCommitment(join.nonce, pub)}
+				proof, _ := crypto.Sign(proofHash(theirs.Commitment, commit.Commitment, pub), join.key)
+				reveal := mpcMessage{Type: "reveal", Nonce: join.nonce, PublicKey: pub, Proof: proof}
+				tt.cheat(join, theirs.Commitment, &commit, &reveal)
+				if p.send(commit) != nil {
+					return
+				}
+				if _, err = p.recv("reveal"); err != nil {
+					return
+				}
+				p.send(reveal)
+			}()
+			combined, _, err := mpcHandshake(newMPCPeer(hostConn), host, true)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := addPublicKeys(&host.key.PublicKey, &join.key.PublicKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !combined.Equal(want) {
+					t.Error("the combined key is not the sum of the shares' public keys")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want one saying %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "os/exec"
+
+// notifyCommand returns the command that posts to Notification Center. the text is passed as arguments
+// rather than spliced into the script so it needs no quoting.
+func notifyCommand(title, body string) *exec.Cmd {
+	return exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, body)
+}
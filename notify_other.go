@@ -0,0 +1,10 @@
+//go:build !darwin && !windows
+
+package main
+
+import "os/exec"
+
+// notifyCommand returns the command that shows a desktop notification on freedesktop.org desktops.
+func notifyCommand(title, body string) *exec.Cmd {
+	return exec.Command("notify-send", "--app-name=vanity", title, body)
+}
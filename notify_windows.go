@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// toastScript shows a toast with the text in $env:VANITY_TITLE and $env:VANITY_BODY, which avoids
+// having to quote it for PowerShell.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$x = $t.GetElementsByTagName('text')
+$x.Item(0).AppendChild($t.CreateTextNode($env:VANITY_TITLE)) > $null
+$x.Item(1).AppendChild($t.CreateTextNode($env:VANITY_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('vanity').Show([Windows.UI.Notifications.ToastNotification]::new($t))
+`
+
+// notifyCommand returns the command that shows a toast notification.
+func notifyCommand(title, body string) *exec.Cmd {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "VANITY_TITLE="+title, "VANITY_BODY="+body)
+	return cmd
+}
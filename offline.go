@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// errNoSocketBlocking is returned by blockSockets on platforms where it cannot block sockets.
+var errNoSocketBlocking = errors.New("blocking sockets is not supported")
+
+// networkFlags are the flags of generate that use the network, or, for -notify, the desktop's D-Bus
+// socket, which -offline refuses.
+var networkFlags = []string{"checkpoint-store", "webhook", "check-rpc", "watch-rpc", "vault", "password-manager", "telegram-chat", "slack-channel", "pprof", "notify"}
+
+// offlineConflicts returns an error naming the networkFlags that are set in fs to anything but their
+// zero value.
+func offlineConflicts(fs *flag.FlagSet) error {
+	var set []string
+	for _, name := range networkFlags {
+		if f := fs.Lookup(name); f != nil {
+			if v := f.Value.String(); v != "" && v != "false" {
+				set = append(set, "-"+name)
+			}
+		}
+	}
+	if len(set) > 0 {
+		return fmt.Errorf("-offline cannot be used with %s", strings.Join(set, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// auditArchs are the seccomp architecture values of the platforms blockSockets supports: those that
+// make sockets with socket(2) rather than socketcall(2).
+var auditArchs = map[string]uint32{
+	"amd64":   unix.AUDIT_ARCH_X86_64,
+	"arm64":   unix.AUDIT_ARCH_AARCH64,
+	"riscv64": unix.AUDIT_ARCH_RISCV64,
+}
+
+// x32Bit marks the syscalls of amd64's x32 ABI, which share its architecture value.
+const x32Bit = 0x40000000
+
+// blockSockets installs a seccomp filter on every thread of the process, and every process it starts,
+// that fails with EPERM any attempt to make a socket: socket(2), io_uring_setup(2), whose rings can
+// make sockets without a syscall, and every syscall of another ABI, such as the 32-bit socketcall(2),
+// which Go never makes. it cannot be undone.
+func blockSockets() error {
+	arch, ok := auditArchs[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("%w on linux/%s", errNoSocketBlocking, runtime.GOARCH)
+	}
+	const (
+		deny  = unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+		allow = unix.SECCOMP_RET_ALLOW
+	)
+	// offsets in struct seccomp_data
+	const (
+		nrOffset   = 0
+		archOffset = 4
+	)
+	filter := []unix.SockFilter{
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: archOffset},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: arch},
+		{Code: unix.BPF_RET | unix.BPF_K, K: deny},
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: nrOffset},
+		{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, Jt: 3, K: x32Bit},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 2, K: unix.SYS_SOCKET},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: unix.SYS_IO_URING_SETUP},
+		{Code: unix.BPF_RET | unix.BPF_K, K: allow},
+		{Code: unix.BPF_RET | unix.BPF_K, K: deny},
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	// no_new_privs, which a filter needs without CAP_SYS_ADMIN, is set on this thread and passed on to
+	// the others by TSYNC, so both must happen on the same one
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+	r, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(filter)
+	switch {
+	case errno != 0:
+		return fmt.Errorf("installing the seccomp filter: %w", errno)
+	case r != 0:
+		return fmt.Errorf("installing the seccomp filter: thread %d could not take it", r)
+	}
+	return nil
+}
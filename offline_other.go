@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// blocking sockets needs seccomp, which only Linux has.
+func blockSockets() error {
+	return fmt.Errorf("%w on %s", errNoSocketBlocking, runtime.GOOS)
+}
@@ -0,0 +1,70 @@
+package vanity
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// AllowedCPUs returns the CPUs this process may run on.
+func AllowedCPUs() ([]int, error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return nil, err
+	}
+	var cpus []int
+	for c := 0; len(cpus) < set.Count(); c++ {
+		if set.IsSet(c) {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
+
+// CoreCPUs returns one of the CPUs this process may run on from each physical core, skipping the
+// cores' other hardware threads, which share their execution units.
+func CoreCPUs() ([]int, error) {
+	allowed, err := AllowedCPUs()
+	if err != nil {
+		return nil, err
+	}
+	var cpus []int
+	for _, c := range allowed {
+		b, err := os.ReadFile(fmt.Sprintf("/sys/devices/system/cpu/cpu%d/topology/thread_siblings_list", c))
+		if err != nil {
+			return allowed, nil // no topology to go by, so every CPU is taken to be a core
+		}
+		siblings, err := ParseCPUList(string(b))
+		if err != nil {
+			return nil, err
+		}
+		// the first of the siblings this process may use stands for the core
+		if i := slices.IndexFunc(siblings, func(s int) bool { return slices.Contains(allowed, s) }); siblings[i] == c {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
+
+// checkCPUs returns an error if the process may not run on every one of cpus.
+func checkCPUs(cpus []int) error {
+	allowed, err := AllowedCPUs()
+	if err != nil {
+		return err
+	}
+	for _, c := range cpus {
+		if !slices.Contains(allowed, c) {
+			return fmt.Errorf("CPU %d is not available to this process", c)
+		}
+	}
+	return nil
+}
+
+// pin restricts the calling thread to cpu.
+func pin(cpu int) error {
+	var set unix.CPUSet
+	set.Set(cpu)
+	return unix.SchedSetaffinity(0, &set)
+}
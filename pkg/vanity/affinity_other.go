@@ -0,0 +1,17 @@
+//go:build !linux
+
+package vanity
+
+import "errors"
+
+var errPinning = errors.New("pinning workers to CPUs is only supported on Linux")
+
+// AllowedCPUs is only supported on Linux.
+func AllowedCPUs() ([]int, error) { return nil, errPinning }
+
+// CoreCPUs is only supported on Linux.
+func CoreCPUs() ([]int, error) { return nil, errPinning }
+
+func checkCPUs(cpus []int) error { return errPinning }
+
+func pin(cpu int) error { return errPinning }
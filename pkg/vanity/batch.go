@@ -0,0 +1,186 @@
+package vanity
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// workers take candidates in batches, so that their public keys are serialized and hashed over
+// contiguous buffers, and so that sources can share work across a batch, as the walk does with its
+// field inversions.
+
+// defaultL2CacheSize is assumed where the L2 cache size cannot be found.
+const defaultL2CacheSize = 256 << 10
+
+// candidateSize is the space a batch takes per candidate: its public key and hash, and the walk's scalar,
+// Jacobian point and running product.
+const candidateSize = 64 + 32 + 32 + 3*40 + 40
+
+// DefaultBatchSize returns the batch size that keeps a worker's batch within half of the L2 cache,
+// between 16 and 1024 candidates.
+func DefaultBatchSize() int {
+	return min(max(l2CacheSize()/2/candidateSize, 16), 1024)
+}
+
+// batchSource is implemented by KeySources that can produce public keys in batches without allocating,
+// so that private keys need only be built for candidates worth keeping.
+type batchSource interface {
+	KeySource
+	// nextBatch writes the public keys of the next candidates, as X followed by Y, to pubs, and returns
+	// how many it wrote, which is fewer than len(pubs) only when it runs out of keys.
+	nextBatch(pubs [][64]byte) (int, error)
+	// key returns the private key of candidate i of the last batch, whose public key is pub.
+	key(i int, pub *[64]byte) *ecdsa.PrivateKey
+}
+
+// offsetSource is implemented by sources whose public keys are a base point plus the public key of
+// their scalar, as split-key searches' are.
+type offsetSource interface {
+	// basePoint returns the base point, or nils if there is none.
+	basePoint() (x, y *big.Int)
+}
+
+// invalidCounter is implemented by sources that skip scalars that are not private keys: zero, or not
+// below the group order. random scalars are invalid with a probability of about 2^-128, so any at all
+// point to a broken source of randomness rather than bad luck.
+type invalidCounter interface {
+	// invalidScalars returns the number of scalars skipped so far.
+	invalidScalars() uint64
+}
+
+// BatchHasher computes the keccak-256 hashes of batches of public keys.
+type BatchHasher interface {
+	Hash(pubs [][64]byte, sums [][32]byte)
+}
+
+// NewBatchHasher returns a BatchHasher for a single worker. on amd64 CPUs with AVX2 it hashes four keys
+// at once, unless built with the purego tag; elsewhere it hashes them one at a time with go-ethereum's
+// keccak. it is a variable so that other implementations can replace it, before any search starts.
+var NewBatchHasher = func() BatchHasher { return &keccakHasher{crypto.NewKeccakState()} }
+
+// keccakHasher hashes one key at a time.
+type keccakHasher struct {
+	h crypto.KeccakState
+}
+
+func (k *keccakHasher) Hash(pubs [][64]byte, sums [][32]byte) {
+	for i := range pubs {
+		k.h.Reset()
+		k.h.Write(pubs[i][:])
+		k.h.Read(sums[i][:])
+	}
+}
+
+// batch is a worker's batch of candidates.
+type batch struct {
+	src     KeySource
+	bs      batchSource // src, if it is one
+	h       BatchHasher
+	pubs    [][64]byte
+	sums    [][32]byte
+	keys    []*ecdsa.PrivateKey // for sources that are not batchSources
+	taken   int                 // keys taken from src for the last batch, including those that failed
+	invalid uint64              // candidates src has skipped or failed to produce, so far
+}
+
+// batches keeps the buffers of finished searches' batches for later ones, so that searches run one
+// after another, as by a server or with -n, do not each allocate their own.
+var batches sync.Pool
+
+// getBatch returns a batch of size candidates from src, reusing a pooled one if it is big enough.
+func getBatch(src KeySource, size int) *batch {
+	b, _ := batches.Get().(*batch)
+	if b == nil || cap(b.pubs) < size {
+		b = &batch{h: NewBatchHasher(), pubs: make([][64]byte, size), sums: make([][32]byte, size)}
+	}
+	b.src, b.pubs, b.sums, b.invalid = src, b.pubs[:size], b.sums[:size], 0
+	if bs, ok := src.(batchSource); ok {
+		b.bs = bs
+	} else if cap(b.keys) < size {
+		b.keys = make([]*ecdsa.PrivateKey, size)
+	} else {
+		b.keys = b.keys[:size]
+	}
+	return b
+}
+
+// putBatch returns b to the pool, dropping its private keys and source, and releases the source's own
+// scratch space if it has any. neither may be used afterwards.
+func putBatch(b *batch) {
+	if r, ok := b.src.(interface{ release() }); ok {
+		r.release()
+	}
+	clear(b.keys)
+	b.src, b.bs = nil, nil
+	batches.Put(b)
+}
+
+// fill takes the next batch of candidates from the source and hashes their public keys, returning
+// how many there are and how many invalid candidates the source skipped or failed to produce while
+// making them. keys the source fails to produce are counted in taken but otherwise skipped.
+func (b *batch) fill() (int, uint64, error) {
+	var (
+		n       int
+		err     error
+		invalid = b.invalid
+	)
+	if b.bs != nil {
+		n, err = b.bs.nextBatch(b.pubs)
+		b.taken = n
+		if ic, ok := b.bs.(invalidCounter); ok {
+			b.invalid = ic.invalidScalars()
+		}
+	} else {
+		for b.taken = 0; b.taken < len(b.pubs); b.taken++ {
+			k, kerr := b.src.Next()
+			if kerr == ErrExhausted {
+				err = kerr
+				break
+			} else if kerr != nil {
+				b.invalid++
+				continue
+			}
+			b.keys[n] = k
+			k.X.FillBytes(b.pubs[n][:32])
+			k.Y.FillBytes(b.pubs[n][32:])
+			n++
+		}
+		if n > 0 {
+			err = nil
+		}
+	}
+	b.h.Hash(b.pubs[:n], b.sums[:n])
+	return n, b.invalid - invalid, err
+}
+
+// key returns the private key of candidate i, or nil if the source got it wrong. keys from batchSources
+// are put together from a scalar and a public key computed separately, so their public key is derived
+// again from the scalar, plus the base point of split-key searches, and checked against the candidate's
+// hash: a broken source then costs matches rather than handing out addresses that nobody holds the key to.
+func (b *batch) key(i int) *ecdsa.PrivateKey {
+	if b.bs == nil {
+		return b.keys[i]
+	}
+	k := b.bs.key(i, &b.pubs[i])
+	var d [32]byte
+	c, err := crypto.ToECDSA(k.D.FillBytes(d[:]))
+	clear(d[:])
+	if err != nil {
+		return nil
+	}
+	pub := c.PublicKey
+	if src, ok := b.bs.(offsetSource); ok {
+		if x, y := src.basePoint(); x != nil {
+			pub.X, pub.Y = pub.Curve.Add(pub.X, pub.Y, x, y)
+		}
+	}
+	ZeroKey(c)
+	if !bytes.Equal(crypto.PubkeyToAddress(pub).Bytes(), b.sums[i][12:]) {
+		return nil
+	}
+	return k
+}
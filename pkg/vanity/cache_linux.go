@@ -0,0 +1,33 @@
+package vanity
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// l2CacheSize returns the size in bytes of the first CPU's level 2 cache, as sysfs reports it, or a
+// typical size if it does not.
+func l2CacheSize() int {
+	dirs, _ := filepath.Glob("/sys/devices/system/cpu/cpu0/cache/index*")
+	for _, dir := range dirs {
+		level, _ := os.ReadFile(filepath.Join(dir, "level"))
+		size, _ := os.ReadFile(filepath.Join(dir, "size"))
+		if strings.TrimSpace(string(level)) != "2" {
+			continue
+		}
+		s := strings.TrimSpace(string(size))
+		mult := 1
+		switch {
+		case strings.HasSuffix(s, "K"):
+			s, mult = s[:len(s)-1], 1<<10
+		case strings.HasSuffix(s, "M"):
+			s, mult = s[:len(s)-1], 1<<20
+		}
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n * mult
+		}
+	}
+	return defaultL2CacheSize
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package vanity
+
+// l2CacheSize returns a typical L2 cache size; other platforms need their own APIs to ask.
+func l2CacheSize() int {
+	return defaultL2CacheSize
+}
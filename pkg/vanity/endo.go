@@ -0,0 +1,78 @@
+package vanity
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// secp256k1 has an endomorphism: multiplying a point (x, y) by lambda gives (beta*x, y), where
+// lambda^3 = 1 mod N and beta^3 = 1 mod P. so every point the walk reaches with key k also gives the
+// keys lambda*k and lambda^2*k for a field multiplication each, rather than a point addition.
+var (
+	lambda  = modNScalar("5363ad4cc05c30e0a5261c028812645a122e22ea20816678df02967c1b23bd72")
+	lambda2 = new(secp256k1.ModNScalar).SquareVal(&lambda)
+	beta    = fieldVal("7ae96a2b657c07106e64479eac3434e99cf0497512f58995c1396c28719501ee")
+)
+
+func modNScalar(s string) (k secp256k1.ModNScalar) {
+	b, _ := hex.DecodeString(s)
+	k.SetByteSlice(b)
+	return k
+}
+
+func fieldVal(s string) (f secp256k1.FieldVal) {
+	b, _ := hex.DecodeString(s)
+	f.SetByteSlice(b)
+	return f
+}
+
+// endoSource is a walk that tries each point's three keys: k, lambda*k and lambda^2*k. it only suits
+// plain walks; ranges must try exactly their own keys, and the offsets of split-key searches cannot be
+// mapped without the base key.
+type endoSource struct {
+	walkSource
+}
+
+func newEndoSource() (*endoSource, error) {
+	e := new(endoSource)
+	return e, e.seed()
+}
+
+func (e *endoSource) Next() (*ecdsa.PrivateKey, error) { return nextOne(e) }
+
+// nextBatch walks a third as many points as pubs has room for, and fills pubs with the three public
+// keys of each in turn. if len(pubs) is not a multiple of three, the last point's remaining keys are
+// skipped.
+func (e *endoSource) nextBatch(pubs [][64]byte) (int, error) {
+	n := len(pubs)
+	m := (n + 2) / 3
+	e.grow(m)
+	if err := e.walk(pubs[:m], e.ks[:m]); err != nil {
+		return 0, err
+	}
+	// spread the points out from the end, so that each is read before its slot is overwritten
+	var x secp256k1.FieldVal
+	for j := m - 1; j >= 0; j-- {
+		pubs[3*j] = pubs[j]
+		x.Set(&e.pts[j].X)
+		for i := 3*j + 1; i < min(3*j+3, n); i++ {
+			x.Mul(&beta).Normalize()
+			x.PutBytesUnchecked(pubs[i][:32])
+			copy(pubs[i][32:], pubs[3*j][32:])
+		}
+	}
+	return n, nil
+}
+
+func (e *endoSource) key(i int, pub *[64]byte) *ecdsa.PrivateKey {
+	k := e.ks[i/3]
+	switch i % 3 {
+	case 1:
+		k.Mul(&lambda)
+	case 2:
+		k.Mul(lambda2)
+	}
+	return privateKey(&k, pub)
+}
@@ -0,0 +1,99 @@
+package vanity
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+)
+
+// entropyRegion is the size of the regions of an entropyPool that workers take in turn. a region lasts
+// a Fast worker for about as many keys as it has bytes.
+const entropyRegion = 64 << 10
+
+// entropyPool is the random data a search's Fast workers share: a buffer, mapped outside the Go heap
+// where the platform allows, split into regions that a goroutine refills from crypto/rand in the
+// background. there are more regions than workers, so a worker that has used up its region usually swaps
+// it for a fresh one; should the refill fall behind, the worker refills its own rather than wait.
+type entropyPool struct {
+	mem   []byte
+	full  chan []byte // regions of fresh data; closed if crypto/rand fails
+	empty chan []byte // regions the workers have used up
+	done  chan struct{}
+	wg    sync.WaitGroup
+	err   error // why full was closed
+}
+
+func newEntropyPool(workers int) (*entropyPool, error) {
+	n := 2*workers + 2
+	mem, err := mapEntropy(n * entropyRegion)
+	if err != nil {
+		return nil, err
+	}
+	p := &entropyPool{mem: mem, full: make(chan []byte, n), empty: make(chan []byte, n), done: make(chan struct{})}
+	for i := 0; i < n; i++ {
+		p.empty <- mem[i*entropyRegion : (i+1)*entropyRegion : (i+1)*entropyRegion]
+	}
+	p.wg.Add(1)
+	go p.refill()
+	return p, nil
+}
+
+// refill fills used regions until close is called. neither channel can ever be full, so it only
+// waits for regions to fill.
+func (p *entropyPool) refill() {
+	defer p.wg.Done()
+	for {
+		select {
+		case r := <-p.empty:
+			if _, err := rand.Read(r); err != nil {
+				p.err = err
+				close(p.full)
+				return
+			}
+			p.full <- r
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// swap hands back the used region r, if not nil, and returns a fresh one. it never waits for the refill:
+// if no region is fresh, the worker refills r itself from crypto/rand.
+func (p *entropyPool) swap(r []byte) ([]byte, error) {
+	select {
+	case f, ok := <-p.full:
+		return p.take(r, f, ok)
+	default:
+	}
+	if r == nil {
+		// the workers hold fewer regions than there are, so one is either fresh or waiting to be
+		select {
+		case f, ok := <-p.full:
+			return p.take(r, f, ok)
+		case r = <-p.empty:
+		}
+	}
+	if _, err := rand.Read(r); err != nil {
+		return nil, fmt.Errorf("refilling the entropy pool: %w", err)
+	}
+	return r, nil
+}
+
+// take hands back r, if not nil, for the fresh region f, received from full with ok.
+func (p *entropyPool) take(r, f []byte, ok bool) ([]byte, error) {
+	if !ok {
+		return nil, fmt.Errorf("refilling the entropy pool: %w", p.err)
+	}
+	if r != nil {
+		p.empty <- r
+	}
+	return f, nil
+}
+
+// close stops the refills and frees the buffer, once the workers have stopped.
+func (p *entropyPool) close() {
+	close(p.done)
+	p.wg.Wait()
+	clear(p.mem)
+	unmapEntropy(p.mem)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package vanity
+
+// mapEntropy allocates n bytes for an entropyPool on the heap, where there is no mmap.
+func mapEntropy(n int) ([]byte, error) {
+	return make([]byte, n), nil
+}
+
+func unmapEntropy(b []byte) {}
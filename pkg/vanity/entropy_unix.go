@@ -0,0 +1,14 @@
+//go:build unix
+
+package vanity
+
+import "golang.org/x/sys/unix"
+
+// mapEntropy maps n bytes of anonymous memory for an entropyPool.
+func mapEntropy(n int) ([]byte, error) {
+	return unix.Mmap(-1, 0, n, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+}
+
+func unmapEntropy(b []byte) {
+	unix.Munmap(b)
+}
@@ -0,0 +1,93 @@
+package vanity
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// gate decides which workers may run. workers are numbered from 0, and only those whose index is below
+// the active count run; the others, and all of them while the gate is paused or held, wait. workers call
+// wait between batches of attempts.
+type gate struct {
+	mu     sync.Mutex
+	cond   sync.Cond
+	active int
+	paused bool // by the user
+	holds  uint // bitmask of the throttles currently holding the workers
+
+	running atomic.Int64 // active, or 0 while paused or held; read by workers without the lock
+}
+
+func newGate(active int) *gate {
+	g := &gate{active: active}
+	g.cond.L = &g.mu
+	g.running.Store(int64(active))
+	return g
+}
+
+// wait blocks while worker i should not run. it returns false once ctx is done, at which point the
+// worker should exit. whoever cancels ctx must call wake for blocked workers to notice.
+func (g *gate) wait(ctx context.Context, i int) bool {
+	if int64(i) < g.running.Load() {
+		return ctx.Err() == nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for ctx.Err() == nil && (g.paused || g.holds != 0 || i >= g.active) {
+		g.cond.Wait()
+	}
+	return ctx.Err() == nil
+}
+
+// wake makes blocked workers check their context.
+func (g *gate) wake() {
+	g.mu.Lock()
+	g.cond.Broadcast()
+	g.mu.Unlock()
+}
+
+// setActive sets the number of workers that may run.
+func (g *gate) setActive(n int) {
+	g.mu.Lock()
+	g.active = max(n, 0)
+	g.update()
+	g.mu.Unlock()
+}
+
+func (g *gate) setPaused(paused bool) {
+	g.mu.Lock()
+	g.paused = paused
+	g.update()
+	g.mu.Unlock()
+}
+
+// setHold holds or releases the workers on behalf of the throttles in the bitmask h.
+func (g *gate) setHold(h uint, on bool) {
+	g.mu.Lock()
+	if on {
+		g.holds |= h
+	} else {
+		g.holds &^= h
+	}
+	g.update()
+	g.mu.Unlock()
+}
+
+// state returns the number of active workers and whether the gate is paused.
+func (g *gate) state() (active int, paused bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.active, g.paused
+}
+
+// update must be called with g.mu held.
+func (g *gate) update() {
+	switch {
+	case g.paused || g.holds != 0:
+		g.running.Store(0)
+	default:
+		g.running.Store(int64(g.active))
+	}
+	g.cond.Broadcast()
+}
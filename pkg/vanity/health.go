@@ -0,0 +1,52 @@
+package vanity
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrBadEntropy is returned by CheckEntropy when crypto/rand's output is plainly not random.
+var ErrBadEntropy = errors.New("crypto/rand returned data that is not random")
+
+// CheckEntropy reads a sample from crypto/rand, which every backend takes its keys from, and checks it
+// for the ways a broken source fails in practice: reads that fail, and output that repeats, as a stuck
+// or uninitialized generator's does. it cannot tell a good generator from a merely plausible one; it
+// only catches the failures that would make every key found with it guessable. random data fails it
+// with a probability of less than 2^-30.
+func CheckEntropy() error {
+	var b [512]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Errorf("reading from crypto/rand: %w", err)
+	}
+	defer clear(b[:])
+	// a run of 6 equal bytes, as in SP 800-90B's repetition count test
+	run := 1
+	for i := 1; i < len(b); i++ {
+		if b[i] != b[i-1] {
+			run = 1
+		} else if run++; run >= 6 {
+			return fmt.Errorf("%w: a byte repeated %d times", ErrBadEntropy, run)
+		}
+	}
+	// a repeated 16-byte block, as from a generator that was reset or forked with its state
+	for i := 0; i < len(b); i += 16 {
+		if bytes.Contains(b[i+16:], b[i:i+16]) {
+			return fmt.Errorf("%w: a block repeated", ErrBadEntropy)
+		}
+	}
+	// 512 random bytes take about 221 distinct values
+	var seen [256]bool
+	var distinct int
+	for _, c := range b {
+		if !seen[c] {
+			seen[c] = true
+			distinct++
+		}
+	}
+	if distinct < 160 {
+		return fmt.Errorf("%w: only %d distinct bytes in %d", ErrBadEntropy, distinct, len(b))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+//go:build !purego
+
+package vanity
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/sys/cpu"
+)
+
+//go:generate go run keccak_gen.go
+
+// keccakF1600x4 applies the Keccak-f[1600] permutation to four states, where a[i][k] is lane i of state k.
+//
+//go:noescape
+func keccakF1600x4(a *[25][4]uint64)
+
+func init() {
+	if cpu.X86.HasAVX2 {
+		NewBatchHasher = func() BatchHasher { return &keccakHasherX4{keccakHasher: keccakHasher{crypto.NewKeccakState()}} }
+	}
+}
+
+// keccakHasherX4 hashes four keys at a time with AVX2, and any left over one at a time.
+type keccakHasherX4 struct {
+	keccakHasher
+	a [25][4]uint64
+}
+
+func (k *keccakHasherX4) Hash(pubs [][64]byte, sums [][32]byte) {
+	n := len(pubs) &^ 3
+	for i := 0; i < n; i += 4 {
+		// a 64-byte key takes a single block: the key, the padding's first bit at byte 64 and its last
+		// at byte 135, the end of keccak-256's 136-byte rate.
+		k.a = [25][4]uint64{}
+		for j := 0; j < 4; j++ {
+			for l := 0; l < 8; l++ {
+				k.a[l][j] = binary.LittleEndian.Uint64(pubs[i+j][8*l:])
+			}
+			k.a[8][j] = 0x01
+			k.a[16][j] = 0x80 << 56
+		}
+		keccakF1600x4(&k.a)
+		for j := 0; j < 4; j++ {
+			for l := 0; l < 4; l++ {
+				binary.LittleEndian.PutUint64(sums[i+j][8*l:], k.a[l][j])
+			}
+		}
+	}
+	k.keccakHasher.Hash(pubs[n:], sums[n:])
+}
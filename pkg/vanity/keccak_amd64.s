@@ -0,0 +1,338 @@
+// Code generated by keccak_gen.go. DO NOT EDIT.
+
+//go:build !purego
+
+#include "textflag.h"
+
+DATA rc<>+0(SB)/8, $0x0000000000000001
+DATA rc<>+8(SB)/8, $0x0000000000008082
+DATA rc<>+16(SB)/8, $0x800000000000808a
+DATA rc<>+24(SB)/8, $0x8000000080008000
+DATA rc<>+32(SB)/8, $0x000000000000808b
+DATA rc<>+40(SB)/8, $0x0000000080000001
+DATA rc<>+48(SB)/8, $0x8000000080008081
+DATA rc<>+56(SB)/8, $0x8000000000008009
+DATA rc<>+64(SB)/8, $0x000000000000008a
+DATA rc<>+72(SB)/8, $0x0000000000000088
+DATA rc<>+80(SB)/8, $0x0000000080008009
+DATA rc<>+88(SB)/8, $0x000000008000000a
+DATA rc<>+96(SB)/8, $0x000000008000808b
+DATA rc<>+104(SB)/8, $0x800000000000008b
+DATA rc<>+112(SB)/8, $0x8000000000008089
+DATA rc<>+120(SB)/8, $0x8000000000008003
+DATA rc<>+128(SB)/8, $0x8000000000008002
+DATA rc<>+136(SB)/8, $0x8000000000000080
+DATA rc<>+144(SB)/8, $0x000000000000800a
+DATA rc<>+152(SB)/8, $0x800000008000000a
+DATA rc<>+160(SB)/8, $0x8000000080008081
+DATA rc<>+168(SB)/8, $0x8000000000008080
+DATA rc<>+176(SB)/8, $0x0000000080000001
+DATA rc<>+184(SB)/8, $0x8000000080008008
+GLOBL rc<>(SB), RODATA, $192
+
+// func keccakF1600x4(a *[25][4]uint64)
+TEXT ·keccakF1600x4(SB), 0, $800-8
+	MOVQ a+0(FP), DI
+	LEAQ rc<>(SB), SI
+	XORQ CX, CX
+
+round:
+	VMOVDQU 0(DI), Y0
+	VPXOR 160(DI), Y0, Y0
+	VPXOR 320(DI), Y0, Y0
+	VPXOR 480(DI), Y0, Y0
+	VPXOR 640(DI), Y0, Y0
+	VMOVDQU 32(DI), Y1
+	VPXOR 192(DI), Y1, Y1
+	VPXOR 352(DI), Y1, Y1
+	VPXOR 512(DI), Y1, Y1
+	VPXOR 672(DI), Y1, Y1
+	VMOVDQU 64(DI), Y2
+	VPXOR 224(DI), Y2, Y2
+	VPXOR 384(DI), Y2, Y2
+	VPXOR 544(DI), Y2, Y2
+	VPXOR 704(DI), Y2, Y2
+	VMOVDQU 96(DI), Y3
+	VPXOR 256(DI), Y3, Y3
+	VPXOR 416(DI), Y3, Y3
+	VPXOR 576(DI), Y3, Y3
+	VPXOR 736(DI), Y3, Y3
+	VMOVDQU 128(DI), Y4
+	VPXOR 288(DI), Y4, Y4
+	VPXOR 448(DI), Y4, Y4
+	VPXOR 608(DI), Y4, Y4
+	VPXOR 768(DI), Y4, Y4
+	VPSLLQ $1, Y1, Y10
+	VPSRLQ $63, Y1, Y11
+	VPOR Y10, Y11, Y10
+	VPXOR Y4, Y10, Y5
+	VPSLLQ $1, Y2, Y10
+	VPSRLQ $63, Y2, Y11
+	VPOR Y10, Y11, Y10
+	VPXOR Y0, Y10, Y6
+	VPSLLQ $1, Y3, Y10
+	VPSRLQ $63, Y3, Y11
+	VPOR Y10, Y11, Y10
+	VPXOR Y1, Y10, Y7
+	VPSLLQ $1, Y4, Y10
+	VPSRLQ $63, Y4, Y11
+	VPOR Y10, Y11, Y10
+	VPXOR Y2, Y10, Y8
+	VPSLLQ $1, Y0, Y10
+	VPSRLQ $63, Y0, Y11
+	VPOR Y10, Y11, Y10
+	VPXOR Y3, Y10, Y9
+	VMOVDQU 0(DI), Y10
+	VPXOR Y5, Y10, Y10
+	VMOVDQU Y10, 0(SP)
+	VMOVDQU 32(DI), Y10
+	VPXOR Y6, Y10, Y10
+	VPSLLQ $1, Y10, Y11
+	VPSRLQ $63, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 320(SP)
+	VMOVDQU 64(DI), Y10
+	VPXOR Y7, Y10, Y10
+	VPSLLQ $62, Y10, Y11
+	VPSRLQ $2, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 640(SP)
+	VMOVDQU 96(DI), Y10
+	VPXOR Y8, Y10, Y10
+	VPSLLQ $28, Y10, Y11
+	VPSRLQ $36, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 160(SP)
+	VMOVDQU 128(DI), Y10
+	VPXOR Y9, Y10, Y10
+	VPSLLQ $27, Y10, Y11
+	VPSRLQ $37, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 480(SP)
+	VMOVDQU 160(DI), Y10
+	VPXOR Y5, Y10, Y10
+	VPSLLQ $36, Y10, Y11
+	VPSRLQ $28, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 512(SP)
+	VMOVDQU 192(DI), Y10
+	VPXOR Y6, Y10, Y10
+	VPSLLQ $44, Y10, Y11
+	VPSRLQ $20, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 32(SP)
+	VMOVDQU 224(DI), Y10
+	VPXOR Y7, Y10, Y10
+	VPSLLQ $6, Y10, Y11
+	VPSRLQ $58, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 352(SP)
+	VMOVDQU 256(DI), Y10
+	VPXOR Y8, Y10, Y10
+	VPSLLQ $55, Y10, Y11
+	VPSRLQ $9, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 672(SP)
+	VMOVDQU 288(DI), Y10
+	VPXOR Y9, Y10, Y10
+	VPSLLQ $20, Y10, Y11
+	VPSRLQ $44, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 192(SP)
+	VMOVDQU 320(DI), Y10
+	VPXOR Y5, Y10, Y10
+	VPSLLQ $3, Y10, Y11
+	VPSRLQ $61, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 224(SP)
+	VMOVDQU 352(DI), Y10
+	VPXOR Y6, Y10, Y10
+	VPSLLQ $10, Y10, Y11
+	VPSRLQ $54, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 544(SP)
+	VMOVDQU 384(DI), Y10
+	VPXOR Y7, Y10, Y10
+	VPSLLQ $43, Y10, Y11
+	VPSRLQ $21, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 64(SP)
+	VMOVDQU 416(DI), Y10
+	VPXOR Y8, Y10, Y10
+	VPSLLQ $25, Y10, Y11
+	VPSRLQ $39, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 384(SP)
+	VMOVDQU 448(DI), Y10
+	VPXOR Y9, Y10, Y10
+	VPSLLQ $39, Y10, Y11
+	VPSRLQ $25, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 704(SP)
+	VMOVDQU 480(DI), Y10
+	VPXOR Y5, Y10, Y10
+	VPSLLQ $41, Y10, Y11
+	VPSRLQ $23, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 736(SP)
+	VMOVDQU 512(DI), Y10
+	VPXOR Y6, Y10, Y10
+	VPSLLQ $45, Y10, Y11
+	VPSRLQ $19, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 256(SP)
+	VMOVDQU 544(DI), Y10
+	VPXOR Y7, Y10, Y10
+	VPSLLQ $15, Y10, Y11
+	VPSRLQ $49, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 576(SP)
+	VMOVDQU 576(DI), Y10
+	VPXOR Y8, Y10, Y10
+	VPSLLQ $21, Y10, Y11
+	VPSRLQ $43, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 96(SP)
+	VMOVDQU 608(DI), Y10
+	VPXOR Y9, Y10, Y10
+	VPSLLQ $8, Y10, Y11
+	VPSRLQ $56, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 416(SP)
+	VMOVDQU 640(DI), Y10
+	VPXOR Y5, Y10, Y10
+	VPSLLQ $18, Y10, Y11
+	VPSRLQ $46, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 448(SP)
+	VMOVDQU 672(DI), Y10
+	VPXOR Y6, Y10, Y10
+	VPSLLQ $2, Y10, Y11
+	VPSRLQ $62, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 768(SP)
+	VMOVDQU 704(DI), Y10
+	VPXOR Y7, Y10, Y10
+	VPSLLQ $61, Y10, Y11
+	VPSRLQ $3, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 288(SP)
+	VMOVDQU 736(DI), Y10
+	VPXOR Y8, Y10, Y10
+	VPSLLQ $56, Y10, Y11
+	VPSRLQ $8, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 608(SP)
+	VMOVDQU 768(DI), Y10
+	VPXOR Y9, Y10, Y10
+	VPSLLQ $14, Y10, Y11
+	VPSRLQ $50, Y10, Y10
+	VPOR Y11, Y10, Y10
+	VMOVDQU Y10, 128(SP)
+	VPBROADCASTQ (SI)(CX*8), Y12
+	VMOVDQU 0(SP), Y0
+	VMOVDQU 32(SP), Y1
+	VMOVDQU 64(SP), Y2
+	VMOVDQU 96(SP), Y3
+	VMOVDQU 128(SP), Y4
+	VPANDN Y2, Y1, Y10
+	VPXOR Y0, Y10, Y10
+	VPXOR Y12, Y10, Y10
+	VMOVDQU Y10, 0(DI)
+	VPANDN Y3, Y2, Y10
+	VPXOR Y1, Y10, Y10
+	VMOVDQU Y10, 32(DI)
+	VPANDN Y4, Y3, Y10
+	VPXOR Y2, Y10, Y10
+	VMOVDQU Y10, 64(DI)
+	VPANDN Y0, Y4, Y10
+	VPXOR Y3, Y10, Y10
+	VMOVDQU Y10, 96(DI)
+	VPANDN Y1, Y0, Y10
+	VPXOR Y4, Y10, Y10
+	VMOVDQU Y10, 128(DI)
+	VMOVDQU 160(SP), Y0
+	VMOVDQU 192(SP), Y1
+	VMOVDQU 224(SP), Y2
+	VMOVDQU 256(SP), Y3
+	VMOVDQU 288(SP), Y4
+	VPANDN Y2, Y1, Y10
+	VPXOR Y0, Y10, Y10
+	VMOVDQU Y10, 160(DI)
+	VPANDN Y3, Y2, Y10
+	VPXOR Y1, Y10, Y10
+	VMOVDQU Y10, 192(DI)
+	VPANDN Y4, Y3, Y10
+	VPXOR Y2, Y10, Y10
+	VMOVDQU Y10, 224(DI)
+	VPANDN Y0, Y4, Y10
+	VPXOR Y3, Y10, Y10
+	VMOVDQU Y10, 256(DI)
+	VPANDN Y1, Y0, Y10
+	VPXOR Y4, Y10, Y10
+	VMOVDQU Y10, 288(DI)
+	VMOVDQU 320(SP), Y0
+	VMOVDQU 352(SP), Y1
+	VMOVDQU 384(SP), Y2
+	VMOVDQU 416(SP), Y3
+	VMOVDQU 448(SP), Y4
+	VPANDN Y2, Y1, Y10
+	VPXOR Y0, Y10, Y10
+	VMOVDQU Y10, 320(DI)
+	VPANDN Y3, Y2, Y10
+	VPXOR Y1, Y10, Y10
+	VMOVDQU Y10, 352(DI)
+	VPANDN Y4, Y3, Y10
+	VPXOR Y2, Y10, Y10
+	VMOVDQU Y10, 384(DI)
+	VPANDN Y0, Y4, Y10
+	VPXOR Y3, Y10, Y10
+	VMOVDQU Y10, 416(DI)
+	VPANDN Y1, Y0, Y10
+	VPXOR Y4, Y10, Y10
+	VMOVDQU Y10, 448(DI)
+	VMOVDQU 480(SP), Y0
+	VMOVDQU 512(SP), Y1
+	VMOVDQU 544(SP), Y2
+	VMOVDQU 576(SP), Y3
+	VMOVDQU 608(SP), Y4
+	VPANDN Y2, Y1, Y10
+	VPXOR Y0, Y10, Y10
+	VMOVDQU Y10, 480(DI)
+	VPANDN Y3, Y2, Y10
+	VPXOR Y1, Y10, Y10
+	VMOVDQU Y10, 512(DI)
+	VPANDN Y4, Y3, Y10
+	VPXOR Y2, Y10, Y10
+	VMOVDQU Y10, 544(DI)
+	VPANDN Y0, Y4, Y10
+	VPXOR Y3, Y10, Y10
+	VMOVDQU Y10, 576(DI)
+	VPANDN Y1, Y0, Y10
+	VPXOR Y4, Y10, Y10
+	VMOVDQU Y10, 608(DI)
+	VMOVDQU 640(SP), Y0
+	VMOVDQU 672(SP), Y1
+	VMOVDQU 704(SP), Y2
+	VMOVDQU 736(SP), Y3
+	VMOVDQU 768(SP), Y4
+	VPANDN Y2, Y1, Y10
+	VPXOR Y0, Y10, Y10
+	VMOVDQU Y10, 640(DI)
+	VPANDN Y3, Y2, Y10
+	VPXOR Y1, Y10, Y10
+	VMOVDQU Y10, 672(DI)
+	VPANDN Y4, Y3, Y10
+	VPXOR Y2, Y10, Y10
+	VMOVDQU Y10, 704(DI)
+	VPANDN Y0, Y4, Y10
+	VPXOR Y3, Y10, Y10
+	VMOVDQU Y10, 736(DI)
+	VPANDN Y1, Y0, Y10
+	VPXOR Y4, Y10, Y10
+	VMOVDQU Y10, 768(DI)
+	INCQ CX
+	CMPQ CX, $24
+	JNE round
+	VZEROUPPER
+	RET
@@ -0,0 +1,86 @@
+//go:build !purego
+
+package vanity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/sys/cpu"
+)
+
+// keccak256x4 hashes four messages of the same length with keccakF1600x4, absorbing them a 136-byte
+// block at a time, as a keccak-256 sponge does.
+func keccak256x4(msgs [4][]byte) [4][32]byte {
+	const rate = 136
+	var padded [4][]byte
+	for j, m := range msgs {
+		p := make([]byte, (len(m)/rate+1)*rate)
+		copy(p, m)
+		p[len(m)] ^= 0x01
+		p[len(p)-1] ^= 0x80
+		padded[j] = p
+	}
+	var a [25][4]uint64
+	for off := 0; off < len(padded[0]); off += rate {
+		for j := range padded {
+			for l := 0; l < rate/8; l++ {
+				a[l][j] ^= binary.LittleEndian.Uint64(padded[j][off+8*l:])
+			}
+		}
+		keccakF1600x4(&a)
+	}
+	var sums [4][32]byte
+	for j := range sums {
+		for l := 0; l < 4; l++ {
+			binary.LittleEndian.PutUint64(sums[j][8*l:], a[l][j])
+		}
+	}
+	return sums
+}
+
+func TestKeccakF1600x4(t *testing.T) {
+	if !cpu.X86.HasAVX2 {
+		t.Skip("no AVX2")
+	}
+	// empty messages, which are all padding, and ones that take one block, two and many
+	for _, n := range []int{0, 1, 64, 135, 136, 137, 272, 1000} {
+		var msgs [4][]byte
+		for j := range msgs {
+			msgs[j] = make([]byte, n)
+			for i := range msgs[j] {
+				msgs[j][i] = byte(i*7 + j*31)
+			}
+		}
+		got := keccak256x4(msgs)
+		for j, m := range msgs {
+			if want := crypto.Keccak256(m); !bytes.Equal(got[j][:], want) {
+				t.Errorf("%d bytes, state %d: got %x, want %x", n, j, got[j], want)
+			}
+		}
+	}
+}
+
+func TestKeccakHasherX4(t *testing.T) {
+	if !cpu.X86.HasAVX2 {
+		t.Skip("no AVX2")
+	}
+	h := &keccakHasherX4{keccakHasher: keccakHasher{crypto.NewKeccakState()}}
+	// none, fewer than four, whole groups of four and groups with some left over
+	for _, n := range []int{0, 1, 3, 4, 5, 8, 67} {
+		pubs := make([][64]byte, n)
+		for i := range pubs {
+			copy(pubs[i][:], crypto.Keccak256([]byte{byte(n), byte(i)}))
+			copy(pubs[i][32:], crypto.Keccak256(pubs[i][:32]))
+		}
+		sums := make([][32]byte, n)
+		h.Hash(pubs, sums)
+		for i := range pubs {
+			if want := crypto.Keccak256(pubs[i][:]); !bytes.Equal(sums[i][:], want) {
+				t.Errorf("%d keys, key %d: got %x, want %x", n, i, sums[i], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+//go:build ignore
+
+// keccak_gen writes keccak_amd64.s, which applies Keccak-f[1600] to four states at once with AVX2.
+//
+//	go run keccak_gen.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+)
+
+// lane is the index of lane (x, y) in a state.
+func lane(x, y int) int { return x%5 + 5*(y%5) }
+
+// rotations returns the rho rotation of each lane.
+func rotations() [25]int {
+	var r [25]int
+	x, y := 1, 0
+	for t := 0; t < 24; t++ {
+		r[lane(x, y)] = (t + 1) * (t + 2) / 2 % 64
+		x, y = y, (2*x+3*y)%5
+	}
+	return r
+}
+
+// roundConstants returns the iota constants of the 24 rounds.
+func roundConstants() [24]uint64 {
+	var rc [24]uint64
+	lfsr := byte(1)
+	for i := range rc {
+		for j := 0; j < 7; j++ {
+			if lfsr&1 != 0 {
+				rc[i] |= 1 << (1<<j - 1)
+			}
+			if lfsr&0x80 != 0 {
+				lfsr = lfsr<<1 ^ 0x71
+			} else {
+				lfsr <<= 1
+			}
+		}
+	}
+	return rc
+}
+
+func main() {
+	var b bytes.Buffer
+	p := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	p("// Code generated by keccak_gen.go. DO NOT EDIT.")
+	p("")
+	p("//go:build !purego")
+	p("")
+	p("#include \"textflag.h\"")
+	p("")
+	for i, c := range roundConstants() {
+		p("DATA rc<>+%d(SB)/8, $0x%016x", 8*i, c)
+	}
+	p("GLOBL rc<>(SB), RODATA, $%d", 8*24)
+	p("")
+	// each 32-byte word of the state holds one lane of the four states. DI points to the states, SP to
+	// the lanes after rho and pi, SI to the round constants and CX counts the rounds.
+	p("// func keccakF1600x4(a *[25][4]uint64)")
+	p("TEXT ·keccakF1600x4(SB), 0, $%d-8", 25*32)
+	p("\tMOVQ a+0(FP), DI")
+	p("\tLEAQ rc<>(SB), SI")
+	p("\tXORQ CX, CX")
+	p("")
+	p("round:")
+	// theta: the column parities go to Y0-Y4 and what each column is XORed with to Y5-Y9
+	for x := 0; x < 5; x++ {
+		p("\tVMOVDQU %d(DI), Y%d", 32*lane(x, 0), x)
+		for y := 1; y < 5; y++ {
+			p("\tVPXOR %d(DI), Y%d, Y%d", 32*lane(x, y), x, x)
+		}
+	}
+	for x := 0; x < 5; x++ {
+		p("\tVPSLLQ $1, Y%d, Y10", (x+1)%5)
+		p("\tVPSRLQ $63, Y%d, Y11", (x+1)%5)
+		p("\tVPOR Y10, Y11, Y10")
+		p("\tVPXOR Y%d, Y10, Y%d", (x+4)%5, 5+x)
+	}
+	// rho and pi
+	r := rotations()
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			i := lane(x, y)
+			p("\tVMOVDQU %d(DI), Y10", 32*i)
+			p("\tVPXOR Y%d, Y10, Y10", 5+x)
+			if r[i] != 0 {
+				p("\tVPSLLQ $%d, Y10, Y11", r[i])
+				p("\tVPSRLQ $%d, Y10, Y10", 64-r[i])
+				p("\tVPOR Y11, Y10, Y10")
+			}
+			p("\tVMOVDQU Y10, %d(SP)", 32*lane(y, 2*x+3*y))
+		}
+	}
+	// chi and iota
+	p("\tVPBROADCASTQ (SI)(CX*8), Y12")
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			p("\tVMOVDQU %d(SP), Y%d", 32*lane(x, y), x)
+		}
+		for x := 0; x < 5; x++ {
+			p("\tVPANDN Y%d, Y%d, Y10", (x+2)%5, (x+1)%5)
+			p("\tVPXOR Y%d, Y10, Y10", x)
+			if x == 0 && y == 0 {
+				p("\tVPXOR Y12, Y10, Y10")
+			}
+			p("\tVMOVDQU Y10, %d(DI)", 32*lane(x, y))
+		}
+	}
+	p("\tINCQ CX")
+	p("\tCMPQ CX, $24")
+	p("\tJNE round")
+	p("\tVZEROUPPER")
+	p("\tRET")
+
+	if err := os.WriteFile("keccak_amd64.s", b.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,416 @@
+package vanity
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeySource generates candidate keys. each worker has its own, so implementations may keep state
+// without locking.
+type KeySource interface {
+	// Next returns the next candidate. errors are counted as attempts and as invalid candidates, and
+	// otherwise ignored.
+	Next() (*ecdsa.PrivateKey, error)
+}
+
+// Backend names a built-in KeySource.
+type Backend string
+
+const (
+	// Geth generates a fresh crypto/rand key per attempt via go-ethereum, which uses libsecp256k1 when
+	// built with cgo. it is the default.
+	Geth Backend = "geth"
+	// Fast reads overlapping windows of a random buffer.
+	Fast Backend = "fast"
+	// Dcrd generates a fresh crypto/rand key per attempt via decred's pure Go secp256k1.
+	Dcrd Backend = "dcrd"
+	// Walk starts from a random key and adds one to it per point, which turns a scalar multiplication
+	// into a point addition, and tries the two other keys each point gives through the secp256k1
+	// endomorphism.
+	Walk Backend = "walk"
+)
+
+// BackendInfo describes a Backend.
+type BackendInfo struct {
+	Name        Backend
+	Description string
+	Security    string // what the key generation gives away, if anything
+}
+
+// Backends lists the available backends, default first.
+var Backends = []BackendInfo{
+	{Geth, "a fresh crypto/rand key per attempt via go-ethereum", "none: every key is independent"},
+	{Fast, "overlapping windows of a random buffer (-f)",
+		"keys from the same run share most of their bytes, so every other key generated alongside a result is easy to guess from it; keep only one result per run"},
+	{Dcrd, "a fresh crypto/rand key per attempt via decred's secp256k1", "none: every key is independent"},
+	{Walk, "consecutive keys from a random starting point, three per point addition",
+		"keys from the same worker are consecutive, so anyone who learns one result learns the others found by that worker, and anyone who learns the search state (such as a checkpoint) learns the neighbourhood of the results"},
+}
+
+// NewKeySource returns a KeySource for b. prefixLen is the length of the prefix being searched for,
+// which Fast sizes its buffer by at first.
+func NewKeySource(b Backend, prefixLen int) (KeySource, error) {
+	switch b {
+	case Geth, "":
+		return randomSource{}, nil
+	case Fast:
+		// about a byte per key the search is expected to take
+		n := min(max(1<<(4*min(prefixLen, 5)), fastMinBuf), fastMaxBuf)
+		return &fastSource{buf: make([]byte, n)}, nil
+	case Dcrd:
+		return new(dcrdSource), nil
+	case Walk:
+		return newEndoSource()
+	}
+	return nil, fmt.Errorf("unknown backend %q", b)
+}
+
+type randomSource struct{}
+
+func (randomSource) Next() (*ecdsa.PrivateKey, error) { return crypto.GenerateKey() }
+
+// dcrdSource reads the scalars for a batch of keys from crypto/rand at once, and multiplies each by G
+// with decred's precomputed table.
+type dcrdSource struct {
+	buf     []byte
+	invalid uint64 // scalars drawn again for being zero or not below the group order
+	affineBatch
+}
+
+func (s *dcrdSource) Next() (*ecdsa.PrivateKey, error) { return nextOne(s) }
+
+func (s *dcrdSource) invalidScalars() uint64 { return s.invalid }
+
+// release clears the random data the scalars were read from.
+func (s *dcrdSource) release() {
+	clear(s.buf)
+	s.affineBatch.release()
+}
+
+func (s *dcrdSource) nextBatch(pubs [][64]byte) (int, error) {
+	s.grow(len(pubs))
+	if len(s.buf) < 32*len(pubs) {
+		s.buf = make([]byte, 32*len(pubs))
+	}
+	if _, err := rand.Read(s.buf[:32*len(pubs)]); err != nil {
+		return 0, err
+	}
+	for i := range pubs {
+		// as in secp256k1.GeneratePrivateKey, scalars outside [1, N) are drawn again
+		b := s.buf[32*i : 32*i+32]
+		for s.ks[i].SetByteSlice(b) || s.ks[i].IsZero() {
+			s.invalid++
+			if _, err := rand.Read(b); err != nil {
+				return 0, err
+			}
+		}
+		secp256k1.ScalarBaseMultNonConst(&s.ks[i], &s.pts[i])
+	}
+	s.toAffine(pubs)
+	return len(pubs), nil
+}
+
+// fastSource reads random data into buf and then converts slices of this data into private keys.
+// the beginning/end indices of the private key slice are incremented by 1 with each call, so the
+// underlying bytes are reused (until buf is exhausted and refilled), but they are interpreted
+// differently as scalars. In theory, this should greatly reduce the number of syscalls
+// and copies for most prefixes. This would be bad if we were producing multiple private keys,
+// since it could potentially be much easier to guess private keys produced by overlapping data,
+// but, because we are only after 1 key, it is probably fine.
+//
+// within a search, the workers take their buffers in turn from a pool they share; see entropyPool.
+// otherwise, a source doubles its buffer, up to fastMaxBuf, whenever refilling it has taken more than
+// fastRefillShare of its time, so that short searches don't wait on big reads and long ones don't
+// spend their time in syscalls.
+type fastSource struct {
+	n       int
+	buf     []byte
+	pool    *entropyPool  // if not nil, buf is one of its regions
+	read    time.Duration // how long the last refill took
+	filled  time.Time     // when it finished
+	invalid uint64        // scalars skipped for being zero or not below the group order
+	affineBatch
+}
+
+const (
+	fastMinBuf      = 4 << 10 // 4 KiB
+	fastMaxBuf      = 1 << 20 // 1 MiB
+	fastRefillShare = 0.01
+)
+
+func (s *fastSource) Next() (*ecdsa.PrivateKey, error) { return nextOne(s) }
+
+func (s *fastSource) nextBatch(pubs [][64]byte) (int, error) {
+	s.grow(len(pubs))
+	for i := 0; i < len(pubs); {
+		if s.n == 0 || s.n > len(s.buf)-32 {
+			if err := s.refill(); err != nil {
+				return 0, err
+			}
+		}
+		overflow := s.ks[i].SetByteSlice(s.buf[s.n : s.n+32])
+		s.n++
+		if overflow || s.ks[i].IsZero() {
+			s.invalid++
+			continue // not a valid key; as likely as guessing one
+		}
+		secp256k1.ScalarBaseMultNonConst(&s.ks[i], &s.pts[i])
+		i++
+	}
+	s.toAffine(pubs)
+	return len(pubs), nil
+}
+
+func (s *fastSource) invalidScalars() uint64 { return s.invalid }
+
+// release clears buf, unless it belongs to the pool, which clears it when the search ends.
+func (s *fastSource) release() {
+	if s.pool == nil {
+		clear(s.buf)
+	}
+	s.affineBatch.release()
+}
+
+// refill replaces buf with fresh random data.
+func (s *fastSource) refill() (err error) {
+	s.n = 0
+	if s.pool != nil {
+		s.buf, err = s.pool.swap(s.buf)
+		return err
+	}
+	start := time.Now()
+	if !s.filled.IsZero() && len(s.buf) < fastMaxBuf && s.read > time.Duration(fastRefillShare*float64(start.Sub(s.filled)+s.read)) {
+		clear(s.buf)
+		s.buf = make([]byte, 2*len(s.buf))
+	}
+	_, err = rand.Read(s.buf)
+	s.filled = time.Now()
+	s.read = s.filled.Sub(start)
+	return err
+}
+
+// nextOne returns a batch of one key from s, for callers that take keys one at a time.
+func nextOne(s batchSource) (*ecdsa.PrivateKey, error) {
+	var pub [1][64]byte
+	if _, err := s.nextBatch(pub[:]); err != nil {
+		return nil, err
+	}
+	return s.key(0, &pub[0]), nil
+}
+
+// affineBatch is scratch space for batches of keys: their scalars and points, and the running products
+// of the points' Z coordinates.
+type affineBatch struct {
+	ks  []secp256k1.ModNScalar
+	pts []secp256k1.JacobianPoint
+	acc []secp256k1.FieldVal
+}
+
+// affineBatches keeps the scratch space of finished searches' sources for later ones.
+var affineBatches sync.Pool
+
+// grow makes room for batches of n.
+func (a *affineBatch) grow(n int) {
+	if len(a.ks) >= n {
+		return
+	}
+	if p, _ := affineBatches.Get().(*affineBatch); p != nil && len(p.ks) >= n {
+		*a = *p
+		return
+	}
+	a.ks = make([]secp256k1.ModNScalar, n)
+	a.pts = make([]secp256k1.JacobianPoint, n)
+	a.acc = make([]secp256k1.FieldVal, n)
+}
+
+// release clears the scalars, which are private keys, and returns the scratch space to the pool. a
+// must not be used afterwards.
+func (a *affineBatch) release() {
+	if a.ks == nil {
+		return
+	}
+	clear(a.ks)
+	p := *a
+	affineBatches.Put(&p)
+	*a = affineBatch{}
+}
+
+func (a *affineBatch) key(i int, pub *[64]byte) *ecdsa.PrivateKey {
+	return privateKey(&a.ks[i], pub)
+}
+
+// toAffine writes the public keys of the first len(pubs) points to pubs. the points are converted to
+// affine coordinates together, with Montgomery's trick, at the cost of a single field inversion for
+// the lot.
+func (a *affineBatch) toAffine(pubs [][64]byte) {
+	n := len(pubs)
+	if n == 0 {
+		return
+	}
+	pts, acc := a.pts[:n], a.acc[:n]
+	for i := range pts {
+		acc[i].Set(&pts[i].Z)
+		if i > 0 {
+			acc[i].Mul(&acc[i-1])
+		}
+	}
+	// inv starts as the inverse of the product of every Z, and loses one factor per point from the end
+	var inv, zinv, zinv2 secp256k1.FieldVal
+	inv.Set(&acc[n-1]).Inverse()
+	for i := n - 1; i >= 0; i-- {
+		if i > 0 {
+			zinv.Mul2(&inv, &acc[i-1])
+			inv.Mul(&pts[i].Z)
+		} else {
+			zinv.Set(&inv)
+		}
+		zinv2.SquareVal(&zinv)
+		x, y := &pts[i].X, &pts[i].Y
+		x.Mul(&zinv2).Normalize()
+		y.Mul(zinv2.Mul(&zinv)).Normalize()
+		x.PutBytesUnchecked(pubs[i][:32])
+		y.PutBytesUnchecked(pubs[i][32:])
+	}
+}
+
+// walkSource keeps a scalar k and the point base + k*G, and steps both by one per key. base is the
+// point at infinity except in split-key searches.
+type walkSource struct {
+	k       secp256k1.ModNScalar
+	p       secp256k1.JacobianPoint
+	base    secp256k1.JacobianPoint
+	invalid uint64 // times the walk reached zero and was seeded again
+	affineBatch
+}
+
+// generator is G in Jacobian coordinates.
+var generator = func() (g secp256k1.JacobianPoint) {
+	var one secp256k1.ModNScalar
+	one.SetInt(1)
+	secp256k1.ScalarBaseMultNonConst(&one, &g)
+	return g
+}()
+
+func (w *walkSource) seed() error {
+	k, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+	w.set(&k.Key)
+	k.Zero()
+	return nil
+}
+
+// set moves the walk to k, so that the next key is k+1.
+func (w *walkSource) set(k *secp256k1.ModNScalar) {
+	w.k = *k
+	secp256k1.ScalarBaseMultNonConst(&w.k, &w.p)
+	secp256k1.AddNonConst(&w.p, &w.base, &w.p)
+}
+
+func (w *walkSource) invalidScalars() uint64 { return w.invalid }
+
+func (w *walkSource) basePoint() (x, y *big.Int) {
+	if w.base.Z.IsZero() { // the point at infinity, as for plain walks
+		return nil, nil
+	}
+	p := w.base
+	p.ToAffine()
+	var b [32]byte
+	p.X.PutBytes(&b)
+	x = new(big.Int).SetBytes(b[:])
+	p.Y.PutBytes(&b)
+	return x, new(big.Int).SetBytes(b[:])
+}
+
+// release clears the walk's position as well as its scratch space.
+func (w *walkSource) release() {
+	w.k.Zero()
+	w.affineBatch.release()
+}
+
+func (w *walkSource) Next() (*ecdsa.PrivateKey, error) {
+	if err := w.step(); err != nil {
+		return nil, err
+	}
+	var pub [64]byte
+	p := w.p
+	p.ToAffine()
+	p.X.PutBytesUnchecked(pub[:32])
+	p.Y.PutBytesUnchecked(pub[32:])
+	return privateKey(&w.k, &pub), nil
+}
+
+func (w *walkSource) nextBatch(pubs [][64]byte) (int, error) {
+	w.grow(len(pubs))
+	return len(pubs), w.walk(pubs, w.ks[:len(pubs)])
+}
+
+// walk steps once for each of pubs, recording the scalars in ks and writing the public keys to pubs.
+func (w *walkSource) walk(pubs [][64]byte, ks []secp256k1.ModNScalar) error {
+	for i := range pubs {
+		if err := w.step(); err != nil {
+			return err
+		}
+		ks[i], w.pts[i] = w.k, w.p
+	}
+	w.toAffine(pubs)
+	return nil
+}
+
+func (w *walkSource) step() error {
+	var one secp256k1.ModNScalar
+	one.SetInt(1)
+	w.k.Add(&one)
+	if w.k.IsZero() {
+		// wrapped around the group order, which is as likely as guessing a key
+		w.invalid++
+		return w.seed()
+	}
+	secp256k1.AddNonConst(&w.p, &generator, &w.p)
+	return nil
+}
+
+// privateKey builds the key with scalar k and public key pub.
+func privateKey(k *secp256k1.ModNScalar, pub *[64]byte) *ecdsa.PrivateKey {
+	d := k.Bytes()
+	defer clear(d[:])
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: crypto.S256(),
+			X:     new(big.Int).SetBytes(pub[:32]),
+			Y:     new(big.Int).SetBytes(pub[32:]),
+		},
+		D: new(big.Int).SetBytes(d[:]),
+	}
+}
+
+// ZeroKey overwrites the private scalar of k, which must not be used afterwards. copies of the key made
+// elsewhere, such as its hex encoding, are not affected.
+func ZeroKey(k *ecdsa.PrivateKey) {
+	if k == nil || k.D == nil {
+		return
+	}
+	clear(k.D.Bits())
+	k.D.SetInt64(0)
+}
+
+// Info returns the description of b from Backends, and false if b is not one of them.
+func (b Backend) Info() (BackendInfo, bool) {
+	if b == "" {
+		b = Geth
+	}
+	for _, info := range Backends {
+		if info.Name == b {
+			return info, true
+		}
+	}
+	return BackendInfo{}, false
+}
@@ -0,0 +1,98 @@
+package vanity
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// checkKeys fills a batch of size candidates from src twice and checks that each candidate's hash is
+// the address of its private key, derived afresh by go-ethereum from the scalar alone.
+func checkKeys(t *testing.T, src KeySource, size int) {
+	t.Helper()
+	b := getBatch(src, size)
+	defer putBatch(b)
+	for range 2 {
+		n, _, err := b.fill()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != size {
+			t.Fatalf("got %d candidates, want %d", n, size)
+		}
+		for j := range n {
+			k := b.key(j)
+			if k == nil {
+				t.Fatalf("candidate %d: the source's public key is not that of its scalar", j)
+			}
+			c, err := crypto.ToECDSA(k.D.FillBytes(make([]byte, 32)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := common.BytesToAddress(b.sums[j][12:])
+			if want := crypto.PubkeyToAddress(c.PublicKey); got != want {
+				t.Errorf("candidate %d: hashed to %s, but the key's address is %s", j, got, want)
+			}
+			if want := crypto.PubkeyToAddress(k.PublicKey); got != want {
+				t.Errorf("candidate %d: hashed to %s, but the key returned has the address %s", j, got, want)
+			}
+		}
+	}
+}
+
+func TestKeySources(t *testing.T) {
+	for _, b := range []Backend{Geth, Fast, Dcrd, Walk} {
+		t.Run(string(b), func(t *testing.T) {
+			// a size that is not a multiple of three leaves part of the endomorphism walk's last point
+			for _, size := range []int{1, 16, 50} {
+				src, err := NewKeySource(b, 4)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkKeys(t, src, size)
+			}
+		})
+	}
+	t.Run("plain walk", func(t *testing.T) {
+		w := new(walkSource)
+		if err := w.seed(); err != nil {
+			t.Fatal(err)
+		}
+		checkKeys(t, w, 40)
+	})
+}
+
+// TestWalkSteps checks that the walk tries consecutive keys, one at a time and in batches.
+func TestWalkSteps(t *testing.T) {
+	w := new(walkSource)
+	if err := w.seed(); err != nil {
+		t.Fatal(err)
+	}
+	first, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := getBatch(w, 20)
+	defer putBatch(b)
+	if _, _, err = b.fill(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []*ecdsa.PrivateKey
+	for j := range 20 {
+		keys = append(keys, b.key(j))
+	}
+	next, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = append(keys, next)
+	for i, k := range keys {
+		want := new(big.Int).Add(first.D, big.NewInt(int64(i+1)))
+		if k.D.Cmp(want) != 0 {
+			t.Fatalf("key %d after the first: got %x, want %x", i+1, k.D, want)
+		}
+	}
+}
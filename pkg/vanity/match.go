@@ -0,0 +1,389 @@
+package vanity
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"math/bits"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errors returned by ValidatePattern, CheckDuration and the built-in matchers
+var (
+	ErrNoPattern      = errors.New("no prefix or suffix to search for")
+	ErrTooLongInvalid = errors.New("combined length of prefix and suffix must be 32 characters or less")
+	ErrTooLong        = errors.New("finding a private key for an address with this prefix/suffix is likely to take a long time")
+	ErrInvalid        = errors.New("prefix/suffix must be a valid hex string containing only characters in the ranges [0-9], [a-f] and [A-F]")
+)
+
+// ValidatePattern checks the combined prefix and suffix s. whether a valid pattern takes too long to find
+// depends on the key rate as well as its length; see CheckDuration.
+func ValidatePattern(s string) error {
+	if len(s) > 32 {
+		return ErrTooLongInvalid
+	}
+	for _, r := range s {
+		switch {
+		case r <= '9' && r >= '0':
+		case r <= 'f' && r >= 'a':
+		case r <= 'F' && r >= 'A':
+		default:
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// LongSearch is how long a search may be expected to take before CheckDuration reports it, so that
+// nobody starts one that would outlast their patience by accident.
+const LongSearch = time.Hour
+
+// CheckDuration returns ErrTooLong if a search expected to take d attempts at rate keys per second is
+// expected to outlast LongSearch. callers may choose to accept it.
+func CheckDuration(d, rate float64) error {
+	if d/rate > LongSearch.Seconds() {
+		return ErrTooLong
+	}
+	return nil
+}
+
+// Difficulty returns the expected number of attempts needed to find an address that matches prefix and
+// suffix. each hex character has a 1 in 16 chance of matching; in case-sensitive mode, each letter also
+// has to match the case chosen by the EIP-55 checksum, which halves the odds.
+func Difficulty(prefix, suffix string, caseSensitive bool) float64 {
+	d, _ := new(big.Float).SetInt(difficulty(prefix, suffix, caseSensitive)).Float64()
+	return d
+}
+
+// Matcher decides which addresses a search accepts. its methods are called concurrently by every worker.
+type Matcher interface {
+	// Match reports whether the 20-byte address addr is acceptable.
+	Match(addr []byte) bool
+	// Difficulty returns the expected number of attempts needed to find an acceptable address.
+	Difficulty() *big.Int
+	// Validate returns an error if the matcher cannot be searched for.
+	Validate() error
+}
+
+// Scorer is implemented by Matchers that can say how close an address they do not accept came, for
+// Searcher.TrackBest. higher scores are closer.
+type Scorer interface {
+	Score(addr []byte) int
+}
+
+// SensitiveMatcher accepts addresses whose EIP-55 checksummed hex form (without 0x) begins with Prefix
+// and ends with Suffix.
+type SensitiveMatcher struct {
+	Prefix, Suffix string
+}
+
+// InsensitiveMatcher is like SensitiveMatcher, but ignores case.
+type InsensitiveMatcher struct {
+	Prefix, Suffix string
+}
+
+// NewMatcher returns the matcher for prefix and suffix.
+func NewMatcher(prefix, suffix string, insensitive bool) Matcher {
+	if insensitive {
+		return InsensitiveMatcher{prefix, suffix}
+	}
+	return SensitiveMatcher{prefix, suffix}
+}
+
+func (m SensitiveMatcher) Match(addr []byte) bool {
+	return match(addr, m.Prefix, m.Suffix, true)
+}
+
+func (m SensitiveMatcher) Score(addr []byte) int {
+	return score(addr, m.Prefix, m.Suffix, true)
+}
+
+func (m SensitiveMatcher) Difficulty() *big.Int {
+	return difficulty(m.Prefix, m.Suffix, true)
+}
+
+func (m SensitiveMatcher) Validate() error {
+	return validate(m.Prefix, m.Suffix)
+}
+
+func (m InsensitiveMatcher) Match(addr []byte) bool {
+	return match(addr, m.Prefix, m.Suffix, false)
+}
+
+func (m InsensitiveMatcher) Score(addr []byte) int {
+	return score(addr, m.Prefix, m.Suffix, false)
+}
+
+func (m InsensitiveMatcher) Difficulty() *big.Int {
+	return difficulty(m.Prefix, m.Suffix, false)
+}
+
+func (m InsensitiveMatcher) Validate() error {
+	return validate(m.Prefix, m.Suffix)
+}
+
+// the matchers compare patterns with the address bytes directly rather than with its hex form, which
+// would cost an allocation, and, in case-sensitive mode, a hash, for every key tried. the hash that
+// decides the case of an address's letters is only computed once a letter's digit has matched.
+
+// hexLen is the length of an address in hex digits.
+const hexLen = 2 * common.AddressLength
+
+// match reports whether the 20-byte address addr begins with prefix and ends with suffix, in its EIP-55
+// checksummed form if sensitive, and ignoring case otherwise.
+func match(addr []byte, prefix, suffix string, sensitive bool) bool {
+	if len(prefix)+len(suffix) > hexLen {
+		return false
+	}
+	var c checksum
+	for i := 0; i < len(prefix); i++ {
+		if !c.eq(addr, i, prefix[i], sensitive) {
+			return false
+		}
+	}
+	for i := 1; i <= len(suffix); i++ {
+		if !c.eq(addr, hexLen-i, suffix[len(suffix)-i], sensitive) {
+			return false
+		}
+	}
+	return true
+}
+
+// score returns the number of characters of the pattern that addr matches, counting forward from the
+// start of the prefix and backward from the end of the suffix. sensitive is as for match.
+func score(addr []byte, prefix, suffix string, sensitive bool) int {
+	var (
+		c checksum
+		n int
+	)
+	for n < len(prefix) && n < hexLen && c.eq(addr, n, prefix[n], sensitive) {
+		n++
+	}
+	for i := 1; i <= len(suffix) && i <= hexLen && c.eq(addr, hexLen-i, suffix[len(suffix)-i], sensitive); i++ {
+		n++
+	}
+	return n
+}
+
+// checksum is the keccak hash of an address's lower-case hex form, which EIP-55 takes the case of its
+// letters from, computed when first needed.
+type checksum struct {
+	hash [32]byte
+	done bool
+}
+
+// eq reports whether hex digit i of addr is the pattern character p, in case too if sensitive.
+func (c *checksum) eq(addr []byte, i int, p byte, sensitive bool) bool {
+	d := nibble(addr, i)
+	switch {
+	case p >= '0' && p <= '9':
+		return d == p-'0'
+	case p|0x20 < 'a' || p|0x20 > 'f' || d != p|0x20-'a'+10:
+		return false
+	case !sensitive:
+		return true
+	}
+	if !c.done {
+		k := keccakPool.Get().(*keccak)
+		hex.Encode(k.buf[:], addr)
+		k.h.Reset()
+		k.h.Write(k.buf[:])
+		k.h.Read(k.sum[:])
+		c.hash, c.done = k.sum, true
+		keccakPool.Put(k)
+	}
+	upper := nibble(c.hash[:], i) >= 8
+	return upper == (p < 'a')
+}
+
+// nibble returns the i'th hex digit of b as a number.
+func nibble(b []byte, i int) byte {
+	if i%2 == 0 {
+		return b[i/2] >> 4
+	}
+	return b[i/2] & 0xf
+}
+
+// compiled is a built-in matcher's pattern compiled for the search loop: its digits as masks over the
+// address, checked a word at a time starting with the word that constrains the most digits, and, in
+// case-sensitive mode, the letters whose case the checksum must give them, which are only checked once
+// every digit has matched, so that the checksum is hardly ever computed.
+type compiled struct {
+	Matcher        // the source, for Difficulty and Validate
+	score   Scorer // the source
+	words   []word // the constrained words
+	letters []letter
+}
+
+// word is a masked comparison of 8 bytes of an address, starting at off.
+type word struct {
+	off        int
+	mask, want uint64
+}
+
+// letter is a hex digit of the address, and whether it must be upper case.
+type letter struct {
+	i     int
+	upper bool
+}
+
+// compile returns the compiled form of m if it is one of the built-in matchers, and m otherwise.
+func compile(m Matcher) Matcher {
+	switch m := m.(type) {
+	case SensitiveMatcher:
+		return newCompiled(m, m.Prefix, m.Suffix, true)
+	case InsensitiveMatcher:
+		return newCompiled(m, m.Prefix, m.Suffix, false)
+	}
+	return m
+}
+
+func newCompiled(m interface {
+	Matcher
+	Scorer
+}, prefix, suffix string, sensitive bool) *compiled {
+	c := &compiled{Matcher: m, score: m}
+	var mask, want [24]byte // the address, padded to three words
+	never := len(prefix)+len(suffix) > hexLen
+	set := func(i int, p byte) {
+		var d byte
+		switch {
+		case p >= '0' && p <= '9':
+			d = p - '0'
+		case p|0x20 >= 'a' && p|0x20 <= 'f':
+			d = p | 0x20 - 'a' + 10
+			if sensitive {
+				c.letters = append(c.letters, letter{i, p < 'a'})
+			}
+		default:
+			never = true
+			return
+		}
+		shift := 4 * (1 - i%2)
+		mask[i/2] |= 0xf << shift
+		want[i/2] |= d << shift
+	}
+	for i := 0; i < len(prefix) && i < hexLen; i++ {
+		set(i, prefix[i])
+	}
+	for i := 1; i <= len(suffix) && i <= hexLen; i++ {
+		set(hexLen-i, suffix[len(suffix)-i])
+	}
+	for off := 0; off < len(mask); off += 8 {
+		if w := (word{off, binary.BigEndian.Uint64(mask[off:]), binary.BigEndian.Uint64(want[off:])}); w.mask != 0 {
+			c.words = append(c.words, w)
+		}
+	}
+	// the word with the most digits to match is the least likely to
+	slices.SortStableFunc(c.words, func(a, b word) int { return bits.OnesCount64(b.mask) - bits.OnesCount64(a.mask) })
+	if never {
+		// a comparison that always fails, at the cost of a real one, so that rates measured against
+		// unmatchable patterns hold for real ones
+		c.words = append([]word{{want: 1}}, c.words...)
+	}
+	return c
+}
+
+func (c *compiled) Match(addr []byte) bool {
+	for _, w := range c.words {
+		if load(addr, w.off)&w.mask != w.want {
+			return false
+		}
+	}
+	if len(c.letters) == 0 {
+		return true
+	}
+	k := keccakPool.Get().(*keccak)
+	defer keccakPool.Put(k)
+	hex.Encode(k.buf[:], addr)
+	k.h.Reset()
+	k.h.Write(k.buf[:])
+	k.h.Read(k.sum[:])
+	for _, l := range c.letters {
+		if upper := nibble(k.sum[:], l.i) >= 8; upper != l.upper {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *compiled) Score(addr []byte) int {
+	return c.score.Score(addr)
+}
+
+// load returns the 8 bytes of the 20-byte address addr starting at off, padded with zeros past its end.
+func load(addr []byte, off int) uint64 {
+	if off+8 <= len(addr) {
+		return binary.BigEndian.Uint64(addr[off:])
+	}
+	var b [8]byte
+	copy(b[:], addr[off:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// keccak is a hasher with buffers for its input and output, which would escape to the heap if they
+// were on the stack of its caller.
+type keccak struct {
+	h   crypto.KeccakState
+	buf [hexLen]byte
+	sum [32]byte
+}
+
+var keccakPool = sync.Pool{New: func() any { return &keccak{h: crypto.NewKeccakState()} }}
+
+func difficulty(prefix, suffix string, caseSensitive bool) *big.Int {
+	bits := 4 * (len(prefix) + len(suffix))
+	if caseSensitive {
+		for _, r := range prefix + suffix {
+			if r > '9' {
+				bits++
+			}
+		}
+	}
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits))
+}
+
+func validate(prefix, suffix string) error {
+	if prefix == "" && suffix == "" {
+		return ErrNoPattern
+	}
+	return ValidatePattern(prefix + suffix)
+}
+
+// bestMatch tracks the closest candidate seen so far across all workers.
+type bestMatch struct {
+	score atomic.Int64 // read without the lock so workers can cheaply skip worse candidates
+	mu    sync.Mutex
+	res   Result
+}
+
+// offer records res if its score beats the best so far, and then calls notify, if not nil, with the
+// lock held so that calls are never concurrent or out of order.
+func (b *bestMatch) offer(res Result, score int, notify func(Result, int)) {
+	if int64(score) <= b.score.Load() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if int64(score) > b.score.Load() {
+		b.res = res
+		b.score.Store(int64(score))
+		if notify != nil {
+			notify(res, score)
+		}
+	}
+}
+
+// get returns the best candidate and its score. the score is 0 if no candidate matched any characters.
+func (b *bestMatch) get() (Result, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res, int(b.score.Load())
+}
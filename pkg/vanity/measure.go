@@ -0,0 +1,38 @@
+package vanity
+
+import (
+	"context"
+	"time"
+)
+
+// MeasureRate runs the search loop with the given number of workers and backend against a pattern that
+// can never match for dur and returns the observed number of keys generated per second across all
+// workers. prefixLen is ignored; Fast used to size its buffer by it, but searches now share one buffer
+// among their workers. the measurement ends early if ctx is done.
+func MeasureRate(ctx context.Context, b Backend, prefixLen int, insensitive bool, dur time.Duration, workers int) (float64, error) {
+	return measure(ctx, b, insensitive, dur, WithWorkers(workers))
+}
+
+// measure is MeasureRate with the Searcher's other options given by opts.
+func measure(ctx context.Context, b Backend, insensitive bool, dur time.Duration, opts ...Option) (float64, error) {
+	s, err := New(append([]Option{
+		WithMatcher(unmatchable{compile(NewMatcher("x", "", insensitive))}), // x is not hex, so nothing matches
+		WithBackend(b),
+	}, opts...)...)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dur)
+	defer cancel()
+	start := time.Now()
+	if _, err = s.Run(ctx); ctx.Err() == nil {
+		return 0, err
+	}
+	return float64(s.Attempts()) / time.Since(start).Seconds(), nil
+}
+
+// unmatchable lets a matcher for an invalid pattern be searched for, so that rates are measured with the
+// cost of matching included.
+type unmatchable struct{ Matcher }
+
+func (unmatchable) Validate() error { return nil }
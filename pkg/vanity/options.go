@@ -0,0 +1,189 @@
+package vanity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Option configures a Searcher created by New.
+type Option func(*Searcher) error
+
+// New returns a Searcher configured by opts, or the first error found in its configuration. a Searcher
+// can also be configured by setting its fields directly, in which case errors are reported by the first
+// call to Run or Stream instead.
+func New(opts ...Option) (*Searcher, error) {
+	s := new(Searcher)
+	for _, o := range opts {
+		if err := o(s); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WithPrefix sets the hex characters that addresses must begin with, after 0x.
+func WithPrefix(prefix string) Option {
+	return func(s *Searcher) error {
+		s.Prefix = prefix
+		return nil
+	}
+}
+
+// WithSuffix sets the hex characters that addresses must end with.
+func WithSuffix(suffix string) Option {
+	return func(s *Searcher) error {
+		s.Suffix = suffix
+		return nil
+	}
+}
+
+// WithCaseInsensitive sets whether letters match regardless of their EIP-55 checksum case.
+func WithCaseInsensitive(insensitive bool) Option {
+	return func(s *Searcher) error {
+		s.CaseInsensitive = insensitive
+		return nil
+	}
+}
+
+// WithMatcher replaces the prefix and suffix with m.
+func WithMatcher(m Matcher) Option {
+	return func(s *Searcher) error {
+		if m == nil {
+			return errors.New("nil matcher")
+		}
+		s.Matcher = m
+		return nil
+	}
+}
+
+// WithChain sets the chain whose addresses are searched.
+func WithChain(c Chain) Option {
+	return func(s *Searcher) error {
+		if c != Ethereum {
+			return fmt.Errorf("unsupported chain %q", c)
+		}
+		s.Chain = c
+		return nil
+	}
+}
+
+// WithBackend sets the built-in KeySource used by the workers.
+func WithBackend(b Backend) Option {
+	return func(s *Searcher) error {
+		if _, ok := b.Info(); !ok {
+			return fmt.Errorf("unknown backend %q", b)
+		}
+		s.Backend = b
+		return nil
+	}
+}
+
+// WithKeySource replaces the backend with a KeySource from newSource for each worker.
+func WithKeySource(newSource func() (KeySource, error)) Option {
+	return func(s *Searcher) error {
+		s.NewKeySource = newSource
+		return nil
+	}
+}
+
+// WithWorkers sets the number of workers that run initially.
+func WithWorkers(n int) Option {
+	return func(s *Searcher) error {
+		if n < 1 {
+			return errors.New("the number of workers must be at least 1")
+		}
+		s.Workers = n
+		return nil
+	}
+}
+
+// WithMaxWorkers sets the number of workers started, so that more than were given to WithWorkers can be
+// enabled later with SetActive.
+func WithMaxWorkers(n int) Option {
+	return func(s *Searcher) error {
+		if n < 1 {
+			return errors.New("the maximum number of workers must be at least 1")
+		}
+		s.MaxWorkers = n
+		return nil
+	}
+}
+
+// WithBatchSize sets the number of candidates each worker takes at a time. 0 means DefaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(s *Searcher) error {
+		if n < 0 {
+			return errors.New("the batch size must not be negative")
+		}
+		s.BatchSize = n
+		return nil
+	}
+}
+
+// WithCPUs pins the workers to cpus in turn, on Linux. see CoreCPUs for a set without SMT siblings.
+func WithCPUs(cpus ...int) Option {
+	return func(s *Searcher) error {
+		s.CPUs = cpus
+		return nil
+	}
+}
+
+// WithTrackBest sets whether the closest candidate is kept for Best.
+func WithTrackBest(track bool) Option {
+	return func(s *Searcher) error {
+		s.TrackBest = track
+		return nil
+	}
+}
+
+// WithMaxAttempts makes searches stop with ErrMaxAttempts after n attempts. 0 means no limit.
+func WithMaxAttempts(n uint64) Option {
+	return func(s *Searcher) error {
+		s.MaxAttempts = n
+		return nil
+	}
+}
+
+// WithRanges makes searches try only the keys in ranges, stopping with ErrExhausted once they have.
+func WithRanges(ranges ...KeyRange) Option {
+	return func(s *Searcher) error {
+		for _, r := range ranges {
+			if err := r.validate(); err != nil {
+				return err
+			}
+		}
+		s.Ranges = ranges
+		return nil
+	}
+}
+
+// WithProgress makes fn be called with a Progress sample every interval while a search runs.
+func WithProgress(interval time.Duration, fn func(Progress)) Option {
+	return func(s *Searcher) error {
+		s.ProgressInterval, s.OnProgress = interval, fn
+		return nil
+	}
+}
+
+// WithBestCallback makes fn be called whenever a closer candidate is found, and enables TrackBest.
+func WithBestCallback(fn func(res Result, score int)) Option {
+	return func(s *Searcher) error {
+		s.OnBest, s.TrackBest = fn, true
+		return nil
+	}
+}
+
+// WithMilestones makes fn be called each time the attempt count passes a multiple of step.
+func WithMilestones(step uint64, fn func(attempts uint64)) Option {
+	return func(s *Searcher) error {
+		if step == 0 {
+			return errors.New("the milestone step must be at least 1")
+		}
+		s.MilestoneStep, s.OnMilestone = step, fn
+		return nil
+	}
+}
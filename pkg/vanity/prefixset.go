@@ -0,0 +1,178 @@
+package vanity
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// PrefixSetMatcher accepts addresses whose hex form begins with any of a set of prefixes, in their
+// EIP-55 checksummed case unless it ignores case. it is meant for sets far too large to try one by one,
+// such as every spelling of every word in a list: the first digits of each address, as many as the
+// shortest prefix has, are looked up in a Bloom filter, and only the rare addresses that pass it are
+// compared with the prefixes themselves.
+type PrefixSetMatcher struct {
+	insensitive bool
+	k           int           // the number of digits the filter covers
+	filter      []bloomBlock  // a blocked Bloom filter of the first k digits of every prefix
+	entries     []prefixEntry // sorted by key
+	err         error         // of the first invalid prefix
+	bitsPerKey  float64       // for FalsePositiveRate
+	keys        int           // distinct keys in the filter
+}
+
+// bloomBlock is a cache line of the filter. each key sets bloomHashes bits in a single block, so that
+// looking it up costs one cache miss.
+type bloomBlock [8]uint64
+
+// bloomBitsPerKey and bloomHashes give a false positive rate of about 1%.
+const (
+	bloomBitsPerKey = 10
+	bloomHashes     = 7
+)
+
+// prefixEntry is a prefix and its first k digits.
+type prefixEntry struct {
+	key    uint64
+	prefix string
+}
+
+// NewPrefixSetMatcher returns a PrefixSetMatcher for prefixes. duplicates, including prefixes that only
+// differ in case if insensitive, are searched for once. invalid prefixes are reported by Validate.
+func NewPrefixSetMatcher(prefixes []string, insensitive bool) *PrefixSetMatcher {
+	m := &PrefixSetMatcher{insensitive: insensitive, k: 16}
+	for _, p := range prefixes {
+		if insensitive {
+			p = strings.ToLower(p)
+		}
+		if err := ValidatePattern(p); p == "" || err != nil {
+			if m.err == nil {
+				m.err = validate(p, "")
+			}
+			continue
+		}
+		m.k = min(m.k, len(p))
+		m.entries = append(m.entries, prefixEntry{prefix: p})
+	}
+	for i := range m.entries {
+		m.entries[i].key = m.key(prefixWord(m.entries[i].prefix))
+	}
+	slices.SortFunc(m.entries, func(a, b prefixEntry) int {
+		if c := cmp.Compare(a.key, b.key); c != 0 {
+			return c
+		}
+		return strings.Compare(a.prefix, b.prefix)
+	})
+	m.entries = slices.CompactFunc(m.entries, func(a, b prefixEntry) bool { return a.prefix == b.prefix })
+
+	for i := range m.entries {
+		if i == 0 || m.entries[i].key != m.entries[i-1].key {
+			m.keys++
+		}
+	}
+	m.filter = make([]bloomBlock, max((m.keys*bloomBitsPerKey+511)/512, 1))
+	m.bitsPerKey = float64(512*len(m.filter)) / float64(max(m.keys, 1))
+	for _, e := range m.entries {
+		b, h := m.locate(e.key)
+		for j := 0; j < bloomHashes; j++ {
+			b[h>>6&7] |= 1 << (h & 63)
+			h >>= 9
+		}
+	}
+	return m
+}
+
+// prefixWord returns the first 16 digits of the prefix p as a word, as load would return those of an
+// address, with zeros for digits p does not have.
+func prefixWord(p string) uint64 {
+	var w uint64
+	for i := 0; i < len(p) && i < 16; i++ {
+		w |= uint64(digit(p[i])) << (60 - 4*i)
+	}
+	return w
+}
+
+// key returns the first k digits of the word w.
+func (m *PrefixSetMatcher) key(w uint64) uint64 {
+	return w >> (64 - 4*m.k)
+}
+
+// locate returns the filter block of key and the hash that picks its bits within it.
+func (m *PrefixSetMatcher) locate(key uint64) (*bloomBlock, uint64) {
+	// splitmix64's finalizer
+	h := key + 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	h ^= h >> 31
+	i, _ := bits.Mul64(h, uint64(len(m.filter)))
+	return &m.filter[i], bits.RotateLeft64(h, 32)
+}
+
+func (m *PrefixSetMatcher) Match(addr []byte) bool {
+	if len(m.entries) == 0 {
+		return false
+	}
+	key := m.key(binary.BigEndian.Uint64(addr))
+	b, h := m.locate(key)
+	for j := 0; j < bloomHashes; j++ {
+		if b[h>>6&7]&(1<<(h&63)) == 0 {
+			return false
+		}
+		h >>= 9
+	}
+	i, _ := slices.BinarySearchFunc(m.entries, key, func(e prefixEntry, key uint64) int { return cmp.Compare(e.key, key) })
+	for ; i < len(m.entries) && m.entries[i].key == key; i++ {
+		if match(addr, m.entries[i].prefix, "", !m.insensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// Difficulty returns the expected number of attempts needed to find an address that begins with any of
+// the prefixes, counting prefixes that begin with others as if they were independent of them.
+func (m *PrefixSetMatcher) Difficulty() *big.Int {
+	var p float64
+	for _, e := range m.entries {
+		p += math.Ldexp(1, 1-difficulty(e.prefix, "", !m.insensitive).BitLen())
+	}
+	if p >= 1 || p == 0 {
+		return big.NewInt(1)
+	}
+	d, _ := big.NewFloat(1 / p).Int(nil)
+	return d
+}
+
+func (m *PrefixSetMatcher) Validate() error {
+	if m.err != nil {
+		return m.err
+	}
+	if len(m.entries) == 0 {
+		return ErrNoPattern
+	}
+	return nil
+}
+
+// Patterns returns the prefixes searched for, without duplicates, and in lower case if case is ignored.
+func (m *PrefixSetMatcher) Patterns() []string {
+	p := make([]string, len(m.entries))
+	for i, e := range m.entries {
+		p[i] = e.prefix
+	}
+	return p
+}
+
+// FilterSize returns the size of the Bloom filter in bytes.
+func (m *PrefixSetMatcher) FilterSize() int {
+	return 64 * len(m.filter)
+}
+
+// FalsePositiveRate returns the expected share of addresses that the Bloom filter passes although
+// they begin with none of the prefixes' first digits, each of which costs a search of the prefixes.
+func (m *PrefixSetMatcher) FalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-bloomHashes/m.bitsPerKey), bloomHashes)
+}
@@ -0,0 +1,56 @@
+package vanity
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultProgressInterval is the interval between calls to OnProgress if ProgressInterval is not set.
+const DefaultProgressInterval = time.Second
+
+// Progress is a sample of a running search, passed to Searcher.OnProgress.
+type Progress struct {
+	Attempts    uint64        // in total, including earlier searches by the same Searcher
+	Elapsed     time.Duration // since the search started
+	Rate        float64       // keys per second since the previous sample
+	WorkerRates []float64     // likewise, for each of the MaxWorkers workers
+	Difficulty  float64       // expected attempts per match
+	BestScore   int           // score of the closest candidate so far, if TrackBest is set
+	Invalid     uint64        // candidates skipped as invalid so far; see Searcher.Invalid
+}
+
+// reportProgress calls s.OnProgress every interval until ctx is done.
+func (s *Searcher) reportProgress(ctx context.Context) {
+	interval := s.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	start := time.Now()
+	last, lastN, lastW := start, s.Attempts(), s.WorkerAttempts()
+	d := s.Difficulty()
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+		now, n, w := time.Now(), s.Attempts(), s.WorkerAttempts()
+		secs := now.Sub(last).Seconds()
+		p := Progress{
+			Attempts:    n,
+			Elapsed:     now.Sub(start),
+			Rate:        float64(n-lastN) / secs,
+			WorkerRates: make([]float64, len(w)),
+			Difficulty:  d,
+			Invalid:     s.Invalid(),
+		}
+		for i := range w {
+			p.WorkerRates[i] = float64(w[i]-lastW[i]) / secs
+		}
+		_, p.BestScore = s.Best()
+		s.OnProgress(p)
+		last, lastN, lastW = now, n, w
+	}
+}
@@ -0,0 +1,178 @@
+package vanity
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// ErrExhausted is returned by Run once every key in Searcher.Ranges has been tried, and by KeySources
+// that have no keys left, which stops the worker using them.
+var ErrExhausted = errors.New("searched every key in the assigned ranges")
+
+// KeyRange is the Size consecutive private keys beginning with Start. searches given disjoint ranges
+// never repeat each other's work, however many machines and workers they are spread over.
+type KeyRange struct {
+	Start *big.Int
+	Size  uint64
+}
+
+// ParseKeyRange parses a range written as START:SIZE, where START is the first key in hex and SIZE is a
+// number of keys, in decimal or as a power of two such as 2^40.
+func ParseKeyRange(s string) (KeyRange, error) {
+	start, size, ok := strings.Cut(s, ":")
+	if !ok {
+		return KeyRange{}, fmt.Errorf("invalid key range %q: want START:SIZE", s)
+	}
+	r := KeyRange{Start: new(big.Int)}
+	if _, ok = r.Start.SetString(strings.TrimPrefix(start, "0x"), 16); !ok {
+		return KeyRange{}, fmt.Errorf("invalid key range %q: start is not a hex number", s)
+	}
+	var err error
+	if exp, ok := strings.CutPrefix(size, "2^"); ok {
+		var e uint64
+		if e, err = strconv.ParseUint(exp, 10, 0); err == nil && e > 63 {
+			err = errors.New("too large")
+		}
+		r.Size = 1 << e
+	} else {
+		r.Size, err = strconv.ParseUint(size, 10, 64)
+	}
+	if err != nil {
+		return KeyRange{}, fmt.Errorf("invalid key range %q: size: %w", s, err)
+	}
+	return r, r.validate()
+}
+
+// String returns r in the form ParseKeyRange accepts.
+func (r KeyRange) String() string {
+	return fmt.Sprintf("%064x:%d", r.Start, r.Size)
+}
+
+// Split divides r into n disjoint ranges of nearly equal size, in order. if r has fewer than n keys, the
+// last parts are empty.
+func (r KeyRange) Split(n int) []KeyRange {
+	parts := make([]KeyRange, n)
+	start := new(big.Int).Set(r.Start)
+	for i := range parts {
+		size := r.Size / uint64(n)
+		if uint64(i) < r.Size%uint64(n) {
+			size++
+		}
+		parts[i] = KeyRange{new(big.Int).Set(start), size}
+		start.Add(start, new(big.Int).SetUint64(size))
+	}
+	return parts
+}
+
+func (r KeyRange) validate() error {
+	end := new(big.Int).Add(r.Start, new(big.Int).SetUint64(r.Size))
+	if r.Start.Sign() <= 0 || end.Cmp(secp256k1.S256().N) > 0 {
+		return fmt.Errorf("key range %s is not within [1, N), where N is the group order", r)
+	}
+	return nil
+}
+
+// shareRanges divides ranges among n workers so that each gets the same number of keys, give or take
+// one.
+func shareRanges(ranges []KeyRange, n int) ([][]KeyRange, error) {
+	var total uint64
+	for _, r := range ranges {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+		if total+r.Size < total {
+			return nil, errors.New("key ranges hold more than 2^64 keys")
+		}
+		total += r.Size
+	}
+	shares := make([][]KeyRange, n)
+	rest := ranges
+	for i, part := range (KeyRange{big.NewInt(0), total}).Split(n) {
+		shares[i] = takeKeys(rest, part.Size)
+		rest = skipKeys(rest, part.Size)
+	}
+	return shares, nil
+}
+
+// takeKeys returns the first n keys of ranges.
+func takeKeys(ranges []KeyRange, n uint64) []KeyRange {
+	var taken []KeyRange
+	for _, r := range ranges {
+		if n == 0 {
+			break
+		}
+		r.Size = min(r.Size, n)
+		n -= r.Size
+		taken = append(taken, r)
+	}
+	return taken
+}
+
+// skipKeys returns what is left of ranges after the first n keys.
+func skipKeys(ranges []KeyRange, n uint64) []KeyRange {
+	for len(ranges) > 0 && n >= ranges[0].Size {
+		n -= ranges[0].Size
+		ranges = ranges[1:]
+	}
+	if len(ranges) == 0 || n == 0 {
+		return ranges
+	}
+	first := KeyRange{new(big.Int).Add(ranges[0].Start, new(big.Int).SetUint64(n)), ranges[0].Size - n}
+	return append([]KeyRange{first}, ranges[1:]...)
+}
+
+// rangeSource walks through ranges in order, like walkSource.
+type rangeSource struct {
+	walkSource
+	ranges []KeyRange
+	left   uint64 // keys left in the range being walked
+}
+
+func (r *rangeSource) Next() (*ecdsa.PrivateKey, error) {
+	if err := r.advance(); err != nil {
+		return nil, err
+	}
+	r.left--
+	return r.walkSource.Next()
+}
+
+func (r *rangeSource) nextBatch(pubs [][64]byte) (int, error) {
+	r.grow(len(pubs))
+	var n int
+	for n < len(pubs) {
+		if err := r.advance(); err == ErrExhausted && n > 0 {
+			break
+		} else if err != nil {
+			return n, err
+		}
+		m := int(min(r.left, uint64(len(pubs)-n)))
+		if err := r.walk(pubs[n:n+m], r.ks[n:n+m]); err != nil {
+			return n, err
+		}
+		r.left -= uint64(m)
+		n += m
+	}
+	return n, nil
+}
+
+// advance moves on to the next range if the current one is done.
+func (r *rangeSource) advance() error {
+	for r.left == 0 {
+		if len(r.ranges) == 0 {
+			return ErrExhausted
+		}
+		// start the walk one before the range, so that its first key is Start
+		var k, minusOne secp256k1.ModNScalar
+		k.SetByteSlice(r.ranges[0].Start.Bytes())
+		minusOne.SetInt(1).Negate()
+		r.set(k.Add(&minusOne))
+		r.left, r.ranges = r.ranges[0].Size, r.ranges[1:]
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package vanity
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func mustParseRanges(t *testing.T, ss ...string) []KeyRange {
+	t.Helper()
+	var ranges []KeyRange
+	for _, s := range ss {
+		r, err := ParseKeyRange(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// keysOf lists the keys in ranges, in order.
+func keysOf(ranges []KeyRange) []*big.Int {
+	var keys []*big.Int
+	for _, r := range ranges {
+		for i := range r.Size {
+			keys = append(keys, new(big.Int).Add(r.Start, new(big.Int).SetUint64(i)))
+		}
+	}
+	return keys
+}
+
+func TestShareRanges(t *testing.T) {
+	tests := []struct {
+		ranges  []string
+		workers int
+	}{
+		{[]string{"1:100"}, 1},
+		{[]string{"1:100"}, 3},
+		{[]string{"1:2"}, 5}, // more workers than keys
+		{[]string{"ff:10", "1000:1", "20000:33"}, 4},
+		{[]string{"ff:10", "1000:1", "20000:33"}, 44},
+		{[]string{"abc:7", "def:7"}, 2}, // a share ending exactly where a range does
+	}
+	for _, tt := range tests {
+		ranges := mustParseRanges(t, tt.ranges...)
+		shares, err := shareRanges(ranges, tt.workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shares) != tt.workers {
+			t.Fatalf("%v among %d: got %d shares", tt.ranges, tt.workers, len(shares))
+		}
+		// every key exactly once, in order, and the shares within one key of each other in size
+		var got []KeyRange
+		lo, hi := ^uint64(0), uint64(0)
+		for _, share := range shares {
+			got = append(got, share...)
+			var n uint64
+			for _, r := range share {
+				n += r.Size
+			}
+			lo, hi = min(lo, n), max(hi, n)
+		}
+		if hi-lo > 1 {
+			t.Errorf("%v among %d: shares of %d to %d keys", tt.ranges, tt.workers, lo, hi)
+		}
+		want, have := keysOf(ranges), keysOf(got)
+		if len(have) != len(want) {
+			t.Fatalf("%v among %d: shares hold %d keys, want %d", tt.ranges, tt.workers, len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Cmp(want[i]) != 0 {
+				t.Fatalf("%v among %d: key %d is %x, want %x", tt.ranges, tt.workers, i, have[i], want[i])
+			}
+		}
+	}
+}
+
+// recorder accepts no address, and records every one it is asked about.
+type recorder struct {
+	mu   sync.Mutex
+	seen map[common.Address]int
+}
+
+func (r *recorder) Match(addr []byte) bool {
+	r.mu.Lock()
+	r.seen[common.BytesToAddress(addr)]++
+	r.mu.Unlock()
+	return false
+}
+
+func (r *recorder) Difficulty() *big.Int { return big.NewInt(1) }
+func (r *recorder) Validate() error      { return nil }
+
+// TestRemaining checks that a search stopped part of the way through its ranges and resumed from
+// Remaining tries every key exactly once.
+func TestRemaining(t *testing.T) {
+	ranges := mustParseRanges(t, "1:300", "5000:1", "123456:200")
+	want := make(map[common.Address]bool)
+	for _, k := range keysOf(ranges) {
+		key, err := crypto.ToECDSA(k.FillBytes(make([]byte, 32)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[crypto.PubkeyToAddress(key.PublicKey)] = true
+	}
+	rec := &recorder{seen: make(map[common.Address]int)}
+
+	first := &Searcher{Matcher: rec, Ranges: ranges, Workers: 3, BatchSize: 16, MaxAttempts: 200}
+	if _, err := first.Run(context.Background()); !errors.Is(err, ErrMaxAttempts) {
+		t.Fatalf("first search: got %v, want %v", err, ErrMaxAttempts)
+	}
+	rest := first.Remaining()
+	tried, total := first.RangeProgress()
+	if total != uint64(len(want)) || tried != first.Attempts() {
+		t.Fatalf("first search: progress %d of %d after %d attempts, want %d in total", tried, total, first.Attempts(), len(want))
+	}
+	var left uint64
+	for _, r := range rest {
+		left += r.Size
+	}
+	if left+tried != total {
+		t.Fatalf("first search: %d keys tried and %d left, want %d in all", tried, left, total)
+	}
+
+	second := &Searcher{Matcher: rec, Ranges: rest, Workers: 2, BatchSize: 16}
+	if _, err := second.Run(context.Background()); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("second search: got %v, want %v", err, ErrExhausted)
+	}
+	if second.Remaining() != nil {
+		t.Errorf("second search: %v left", second.Remaining())
+	}
+	for addr, n := range rec.seen {
+		if !want[addr] {
+			t.Errorf("tried %s, which is not in the ranges", addr)
+		} else if n > 1 {
+			t.Errorf("tried %s %d times", addr, n)
+		}
+	}
+	if len(rec.seen) != len(want) {
+		t.Errorf("tried %d keys, want %d", len(rec.seen), len(want))
+	}
+}
@@ -0,0 +1,499 @@
+// Package vanity searches for Ethereum private keys whose addresses match a pattern.
+//
+// a search is configured by setting the fields of a Searcher and run by calling its Run method for a
+// single match, or its Stream method for as many as the caller wants:
+//
+//	s, err := vanity.New(vanity.WithPrefix("dead"), vanity.WithCaseInsensitive(true))
+//	...
+//	res, err := s.Run(ctx)
+//
+// the other methods report on a search in progress and control it, and are safe to call from any
+// goroutine.
+package vanity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Chain names the chain whose address format is searched.
+type Chain string
+
+// Ethereum is the only chain supported so far, and the default.
+const Ethereum Chain = "ethereum"
+
+// ErrMaxAttempts is returned by Run once MaxAttempts is reached.
+var ErrMaxAttempts = errors.New("reached the attempt limit")
+
+// Result is a key found by a search.
+type Result struct {
+	Key     *ecdsa.PrivateKey
+	Address common.Address
+}
+
+// Searcher searches for a key whose address begins with Prefix and ends with Suffix, or is accepted by
+// Matcher. the fields must not be changed once any method has been called.
+type Searcher struct {
+	Prefix, Suffix  string
+	CaseInsensitive bool                      // match letters regardless of their EIP-55 checksum case
+	Matcher         Matcher                   // replaces Prefix, Suffix and CaseInsensitive if set
+	Chain           Chain                     // defaults to Ethereum
+	Backend         Backend                   // defaults to Geth
+	NewKeySource    func() (KeySource, error) // replaces Backend if set; called for each worker at the start of every Run
+	Workers         int                       // number of workers that run initially; defaults to GOMAXPROCS
+	MaxWorkers      int                       // number of workers started, so that more can be enabled later with SetActive; defaults to Workers
+	TrackBest       bool                      // keep track of the closest candidate for Best, at a small cost per attempt
+	MaxAttempts     uint64                    // Run returns ErrMaxAttempts once Attempts reaches this; 0 means no limit
+	BatchSize       int                       // candidates each worker takes at a time; defaults to DefaultBatchSize
+	CPUs            []int                     // if set, worker i runs only on CPU CPUs[i%len(CPUs)]; Linux only
+
+	// Ranges, if set, are the only keys tried. they are walked as by the Walk backend, which replaces
+	// Backend, and shared equally among the MaxWorkers workers, so inactive workers hold their shares
+	// back. Run returns ErrExhausted once every key has been tried.
+	Ranges []KeyRange
+
+	// callbacks for following a search without polling. they are called synchronously and should return
+	// quickly.
+	OnProgress       func(Progress)              // called every ProgressInterval from a single goroutine while a search runs
+	ProgressInterval time.Duration               // defaults to DefaultProgressInterval
+	OnBest           func(res Result, score int) // called from the workers, one at a time, whenever a closer candidate is found; requires TrackBest
+	OnMilestone      func(attempts uint64)       // called from the workers, possibly concurrently, each time Attempts passes a multiple of MilestoneStep; multiples passed together are reported once
+	MilestoneStep    uint64
+
+	once           sync.Once
+	err            error
+	gate           *gate
+	attempts       atomic.Uint64 // added with AddAttempts; the workers' own are in workerAttempts
+	workerAttempts []counter     // updated after every batch, so they may lag slightly behind
+	milestone      atomic.Uint64 // the last one reported
+	invalid        atomic.Uint64 // candidates skipped as invalid; see Invalid
+	watch          bool          // whether the workers must add up the counts, for MaxAttempts or milestones
+	best           bestMatch
+	matcher        Matcher
+	shares         [][]KeyRange // of Ranges, by worker
+	batchSize      int
+	limit          chan struct{} // closed once MaxAttempts is reached
+	limitOnce      sync.Once
+}
+
+// init validates the configuration and sets up the search state the first time it is called.
+func (s *Searcher) init() error {
+	s.once.Do(func() {
+		s.matcher = s.Matcher
+		if s.matcher == nil {
+			s.matcher = NewMatcher(s.Prefix, s.Suffix, s.CaseInsensitive)
+		}
+		if s.err = s.matcher.Validate(); s.err != nil {
+			return
+		}
+		s.matcher = compile(s.matcher)
+		if s.Chain != "" && s.Chain != Ethereum {
+			s.err = fmt.Errorf("unsupported chain %q", s.Chain)
+			return
+		}
+		if _, ok := s.Backend.Info(); !ok && s.NewKeySource == nil {
+			s.err = fmt.Errorf("unknown backend %q", s.Backend)
+			return
+		}
+		workers := s.Workers
+		if workers < 1 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+		s.gate = newGate(workers)
+		if s.batchSize = s.BatchSize; s.batchSize < 1 {
+			s.batchSize = DefaultBatchSize()
+		}
+		if len(s.CPUs) > 0 {
+			if s.err = checkCPUs(s.CPUs); s.err != nil {
+				return
+			}
+		}
+		s.workerAttempts = make([]counter, max(workers, s.MaxWorkers))
+		s.watch = s.MaxAttempts > 0 || s.OnMilestone != nil && s.MilestoneStep > 0
+		if len(s.Ranges) > 0 {
+			if s.NewKeySource != nil {
+				s.err = errors.New("Ranges and NewKeySource cannot be used together")
+				return
+			}
+			if s.shares, s.err = shareRanges(s.Ranges, len(s.workerAttempts)); s.err != nil {
+				return
+			}
+		}
+		s.limit = make(chan struct{})
+	})
+	return s.err
+}
+
+// Stream is a search started by Searcher.Stream.
+type Stream struct {
+	// C receives every match as it is found, and is closed once the search has stopped and all of its
+	// workers have exited. matches are not deduplicated.
+	C   <-chan Result
+	err error
+}
+
+// Err returns why the search stopped: the context's error, ErrMaxAttempts or ErrExhausted. it is only
+// meaningful once C has been closed.
+func (st *Stream) Err() error {
+	return st.err
+}
+
+// Stream starts a search that runs until ctx is done, MaxAttempts is reached or Ranges are exhausted,
+// delivering matches on
+// the returned Stream. the caller decides when it has enough and cancels ctx; it must keep receiving
+// from C until C is closed, or the workers will never exit. it returns an error without searching if
+// the configuration is invalid. attempt counts carry over from earlier searches.
+func (s *Searcher) Stream(ctx context.Context) (*Stream, error) {
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	sources := make([]KeySource, len(s.workerAttempts))
+	var pool *entropyPool // shared by Fast workers
+	if s.shares == nil && s.NewKeySource == nil && s.Backend == Fast {
+		var err error
+		if pool, err = newEntropyPool(len(sources)); err != nil {
+			return nil, err
+		}
+	}
+	for i := range sources {
+		var err error
+		switch {
+		case s.shares != nil:
+			// what the worker hasn't searched in earlier runs
+			sources[i] = &rangeSource{ranges: skipKeys(s.shares[i], s.workerAttempts[i].Load())}
+		case s.NewKeySource != nil:
+			sources[i], err = s.NewKeySource()
+		case pool != nil:
+			sources[i] = &fastSource{pool: pool}
+		default:
+			sources[i], err = NewKeySource(s.Backend, len(s.Prefix))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	// a slot per worker, so that none waits on the caller to hand over a match
+	c := make(chan Result, len(sources))
+	st := &Stream{C: c}
+	var stop atomic.Bool // checked by the workers after every batch, which is cheaper than ctx.Err
+	var wg sync.WaitGroup
+	exhausted := make(chan struct{})
+	var left atomic.Int64 // workers with keys left
+	left.Store(int64(len(sources)))
+	for i := range s.workerAttempts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if len(s.CPUs) > 0 {
+				// the thread is never unlocked, so that it exits with the worker rather than going back
+				// to the scheduler still pinned. init has checked the CPUs, so pinning only fails if the
+				// process's own affinity has changed since, and the worker then runs unpinned.
+				runtime.LockOSThread()
+				pin(s.CPUs[i%len(s.CPUs)])
+			}
+			if s.work(ctx, i, sources[i], c, &stop) && left.Add(-1) == 0 {
+				close(exhausted)
+			}
+		}(i)
+	}
+	if s.OnProgress != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.reportProgress(ctx)
+		}()
+	}
+	go func() {
+		select {
+		case <-parent.Done():
+			st.err = parent.Err()
+		case <-s.limit:
+			st.err = ErrMaxAttempts
+		case <-exhausted:
+			st.err = ErrExhausted
+		}
+		stop.Store(true)
+		cancel()
+		s.gate.wake()
+		wg.Wait()
+		if pool != nil {
+			pool.close()
+		}
+		close(c)
+	}()
+	return st, nil
+}
+
+// Run searches for a single match and returns it, or the reason the search stopped as for Stream.Err.
+// it may be called again to find further matches. every worker has exited by the time it returns.
+func (s *Searcher) Run(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	st, err := s.Stream(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	res, ok := <-st.C
+	cancel()
+	for range st.C {
+		// later matches found while stopping are dropped
+	}
+	if !ok {
+		return Result{}, st.Err()
+	}
+	return res, nil
+}
+
+// work runs worker i with keys from src, sending every match on found, until stop is set, ctx is done or
+// src returns ErrExhausted, in which case it returns true.
+func (s *Searcher) work(ctx context.Context, i int, src KeySource, found chan<- Result, stop *atomic.Bool) bool {
+	if !s.gate.wait(ctx, i) {
+		return false
+	}
+	var (
+		res Result
+		j   int    // of the candidate in the batch
+		n   uint64 // attempts not yet added to the shared count
+		b   = getBatch(src, s.batchSize)
+	)
+	defer putBatch(b)
+	defer func() { s.addAttempts(i, n) }()
+	match := func() bool { return s.matcher.Match(res.Address[:]) }
+	if sc, ok := s.matcher.(Scorer); ok && s.TrackBest {
+		match = func() bool {
+			if s.matcher.Match(res.Address[:]) {
+				return true
+			}
+			if score := sc.Score(res.Address[:]); int64(score) > s.best.score.Load() {
+				if res.Key = b.key(j); res.Key != nil {
+					s.best.offer(res, score, s.OnBest)
+				}
+			}
+			return false
+		}
+	}
+	var unchecked int // attempts since the gate was last checked
+	for {
+		if stop.Load() {
+			return false
+		}
+		if n > 0 {
+			s.addAttempts(i, n)
+			n = 0
+		}
+		if unchecked >= 1<<10 {
+			unchecked = 0
+			if !s.gate.wait(ctx, i) {
+				return false
+			}
+		}
+		// counted only once keys have been taken, so that a worker's count is exactly the number of
+		// keys it has taken, which is what resuming Ranges relies on
+		size, invalid, err := b.fill()
+		n += uint64(b.taken)
+		if invalid > 0 {
+			s.invalid.Add(invalid)
+		}
+		unchecked += b.taken
+		if err == ErrExhausted && size == 0 {
+			return true
+		}
+		for j = 0; j < size; j++ {
+			copy(res.Address[:], b.sums[j][12:])
+			if !match() {
+				continue
+			}
+			if res.Key = b.key(j); res.Key == nil {
+				continue
+			}
+			// so the count is current when the match is received; the rest of the batch is counted once
+			// it has been checked, and not at all if the search stops first
+			rest := uint64(size - j - 1)
+			s.addAttempts(i, n-rest)
+			n = rest
+			select {
+			case found <- res:
+			case <-ctx.Done():
+				n = 0
+				return false
+			}
+		}
+	}
+}
+
+// counter is a worker's attempt count, padded to a cache line of its own so that counting never
+// contends with the other workers.
+type counter struct {
+	atomic.Uint64
+	_ [56]byte
+}
+
+func (s *Searcher) addAttempts(i int, n uint64) {
+	s.workerAttempts[i].Add(n)
+	if s.watch {
+		s.checkAttempts()
+	}
+}
+
+// checkAttempts acts on any limit or milestone the total has passed.
+func (s *Searcher) checkAttempts() {
+	total := s.Attempts()
+	if s.MaxAttempts > 0 && total >= s.MaxAttempts {
+		s.limitOnce.Do(func() { close(s.limit) })
+	}
+	if step := s.MilestoneStep; s.OnMilestone != nil && step > 0 {
+		// so that each multiple is reported by only the first worker to see it passed
+		if m, last := total/step*step, s.milestone.Load(); m > last && s.milestone.CompareAndSwap(last, m) {
+			s.OnMilestone(m)
+		}
+	}
+}
+
+// AddAttempts adds n to the attempt count, e.g. the attempts made by an earlier run of a resumed search.
+func (s *Searcher) AddAttempts(n uint64) {
+	if s.init() != nil {
+		return
+	}
+	s.attempts.Add(n)
+	s.checkAttempts()
+}
+
+// Attempts returns the number of keys tried so far. the workers' counts are added up on each call, so
+// that the workers never share one.
+func (s *Searcher) Attempts() uint64 {
+	total := s.attempts.Load()
+	for i := range s.workerAttempts {
+		total += s.workerAttempts[i].Load()
+	}
+	return total
+}
+
+// Invalid returns the number of candidates skipped so far because they were not valid keys: scalars
+// that were zero or not below the group order, and keys a KeySource failed to produce. they are skipped
+// rather than searched, and counted as attempts if their source counts them as taken. for the built-in
+// backends any at all point to a broken source of randomness, as a random scalar is invalid with a
+// probability of about 2^-128.
+func (s *Searcher) Invalid() uint64 {
+	return s.invalid.Load()
+}
+
+// WorkerAttempts returns the number of keys tried so far by each of the MaxWorkers workers.
+func (s *Searcher) WorkerAttempts() []uint64 {
+	if s.init() != nil {
+		return nil
+	}
+	n := make([]uint64, len(s.workerAttempts))
+	for i := range s.workerAttempts {
+		n[i] = s.workerAttempts[i].Load()
+	}
+	return n
+}
+
+// RangeProgress returns the number of keys in Ranges tried so far and the number there are in all.
+func (s *Searcher) RangeProgress() (tried, total uint64) {
+	if s.init() != nil {
+		return 0, 0
+	}
+	for i, share := range s.shares {
+		for _, r := range share {
+			total += r.Size
+		}
+		tried += s.workerAttempts[i].Load()
+	}
+	return tried, total
+}
+
+// Remaining returns the keys in Ranges not yet tried, so that a search can be resumed exactly. keys
+// tried since a worker last updated its count, which it does after every batch of BatchSize
+// candidates, are included.
+func (s *Searcher) Remaining() []KeyRange {
+	if s.init() != nil {
+		return nil
+	}
+	var rest []KeyRange
+	for i, share := range s.shares {
+		rest = append(rest, skipKeys(share, s.workerAttempts[i].Load())...)
+	}
+	return rest
+}
+
+// Best returns the closest candidate seen so far and its score, which for the built-in matchers is the
+// number of characters of the pattern it matches. the score is 0 if no candidate scored, TrackBest is
+// false or the Matcher is not a Scorer.
+func (s *Searcher) Best() (Result, int) {
+	return s.best.get()
+}
+
+// State returns the number of active workers and whether the search is paused.
+func (s *Searcher) State() (active int, paused bool) {
+	if s.init() != nil {
+		return 0, false
+	}
+	return s.gate.state()
+}
+
+// SetActive sets the number of workers that run, up to MaxWorkers.
+func (s *Searcher) SetActive(n int) {
+	if s.init() != nil {
+		return
+	}
+	s.gate.setActive(min(n, len(s.workerAttempts)))
+}
+
+// SetPaused pauses or resumes the workers.
+func (s *Searcher) SetPaused(paused bool) {
+	if s.init() != nil {
+		return
+	}
+	s.gate.setPaused(paused)
+}
+
+// Hold holds the workers while any of the bits in the caller-chosen mask h are set, so that several
+// throttles can hold and release them independently of each other and of SetPaused.
+func (s *Searcher) Hold(h uint, on bool) {
+	if s.init() != nil {
+		return
+	}
+	s.gate.setHold(h, on)
+}
+
+// Matches reports whether a matches.
+func (s *Searcher) Matches(a common.Address) bool {
+	if s.init() != nil {
+		return false
+	}
+	return s.matcher.Match(a[:])
+}
+
+// Score returns the score of a, or 0 if the Matcher is not a Scorer.
+func (s *Searcher) Score(a common.Address) int {
+	if s.init() != nil {
+		return 0
+	}
+	if sc, ok := s.matcher.(Scorer); ok {
+		return sc.Score(a[:])
+	}
+	return 0
+}
+
+// Difficulty returns the expected number of attempts needed to find a match, or +Inf if the
+// configuration is invalid.
+func (s *Searcher) Difficulty() float64 {
+	if s.init() != nil {
+		return math.Inf(1)
+	}
+	d, _ := new(big.Float).SetInt(s.matcher.Difficulty()).Float64()
+	return d
+}
@@ -0,0 +1,89 @@
+package vanity
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// split-key searches let someone else grind a vanity address without learning its private key. the
+// owner generates a base key and hands over only its public key P. the searcher looks for an offset k
+// such that P + k*G has a matching address and hands back k, and the owner combines the two into the
+// private key d + k, where d is the base private key. k alone reveals nothing about d + k.
+
+// SplitKeySource returns a NewKeySource func for a split-key search against the base public key pub.
+// the keys it produces are not ordinary key pairs: their private part is the offset k and their public
+// part is pub + k*G, so a Result's Key.D is the offset to hand back and its Address is that of the
+// combined key.
+func SplitKeySource(pub *ecdsa.PublicKey) (func() (KeySource, error), error) {
+	base, err := jacobian(pub)
+	if err != nil {
+		return nil, err
+	}
+	return func() (KeySource, error) {
+		w := &walkSource{base: base}
+		return w, w.seed()
+	}, nil
+}
+
+// SplitKeyAddress returns the address of the key combining the base public key pub with offset, so
+// that an offset can be checked without the base private key.
+func SplitKeyAddress(pub *ecdsa.PublicKey, offset []byte) (common.Address, error) {
+	base, err := jacobian(pub)
+	if err != nil {
+		return common.Address{}, err
+	}
+	k, err := scalar(offset)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var p secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&k, &p)
+	secp256k1.AddNonConst(&p, &base, &p)
+	p.ToAffine()
+	return crypto.PubkeyToAddress(*secp256k1.NewPublicKey(&p.X, &p.Y).ToECDSA()), nil
+}
+
+// CombineSplitKey returns the private key that combines the base key with the offset found by a
+// split-key search.
+func CombineSplitKey(base *ecdsa.PrivateKey, offset []byte) (*ecdsa.PrivateKey, error) {
+	k, err := scalar(offset)
+	if err != nil {
+		return nil, err
+	}
+	var d secp256k1.ModNScalar
+	if d.SetByteSlice(crypto.FromECDSA(base)) {
+		return nil, errors.New("invalid base key")
+	}
+	d.Add(&k)
+	b := d.Bytes()
+	return crypto.ToECDSA(b[:])
+}
+
+func jacobian(pub *ecdsa.PublicKey) (secp256k1.JacobianPoint, error) {
+	var p secp256k1.JacobianPoint
+	if pub == nil || pub.X == nil || pub.Y == nil {
+		return p, errors.New("missing public key")
+	}
+	k, err := secp256k1.ParsePubKey(crypto.FromECDSAPub(pub))
+	if err != nil {
+		return p, err
+	}
+	k.AsJacobian(&p)
+	return p, nil
+}
+
+func scalar(b []byte) (secp256k1.ModNScalar, error) {
+	var k secp256k1.ModNScalar
+	if len(b) != 32 {
+		return k, fmt.Errorf("offset must be 32 bytes, not %d", len(b))
+	}
+	if k.SetByteSlice(b) {
+		return k, errors.New("offset is not below the group order")
+	}
+	return k, nil
+}
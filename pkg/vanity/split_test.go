@@ -0,0 +1,65 @@
+package vanity
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestSplitKey checks that the candidates of a split-key search, P + k*G, have the addresses of the
+// private keys that CombineSplitKey makes of the base key and each offset k.
+func TestSplitKey(t *testing.T) {
+	base, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSource, err := SplitKeySource(&base.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := newSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := getBatch(src, 25)
+	defer putBatch(b)
+	n, _, err := b.fill()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := range n {
+		k := b.key(j)
+		if k == nil {
+			t.Fatalf("candidate %d: the source's public key is not P + k*G", j)
+		}
+		offset := k.D.FillBytes(make([]byte, 32))
+		combined, err := CombineSplitKey(base, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := common.BytesToAddress(b.sums[j][12:])
+		if want := crypto.PubkeyToAddress(combined.PublicKey); got != want {
+			t.Errorf("candidate %d: hashed to %s, but the combined key's address is %s", j, got, want)
+		}
+		if addr, err := SplitKeyAddress(&base.PublicKey, offset); err != nil || addr != got {
+			t.Errorf("candidate %d: SplitKeyAddress gave %s, %v, want %s", j, addr, err, got)
+		}
+	}
+}
+
+func TestSplitKeyBadOffset(t *testing.T) {
+	base, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := crypto.S256().Params().N.Bytes()
+	for _, offset := range [][]byte{nil, make([]byte, 31), n} {
+		if _, err := CombineSplitKey(base, offset); err == nil {
+			t.Errorf("CombineSplitKey accepted the offset %x", offset)
+		}
+		if _, err := SplitKeyAddress(&base.PublicKey, offset); err == nil {
+			t.Errorf("SplitKeyAddress accepted the offset %x", offset)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package vanity
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// SubstringMatcher accepts addresses whose hex form (without 0x) contains any of a set of patterns
+// anywhere, in their EIP-55 checksummed case unless it ignores case. it runs the address's hex digits
+// through an Aho–Corasick automaton, so the cost of a match does not grow with the number of patterns.
+type SubstringMatcher struct {
+	patterns    []string
+	insensitive bool
+	next        [][16]int32 // the transitions of each state on each hex digit
+	out         [][]int32   // the patterns that end at each state, nil for states where none do
+	depth       []int       // the number of digits each state has matched
+	err         error       // of the first invalid pattern
+}
+
+// NewSubstringMatcher returns a SubstringMatcher for patterns. duplicates, including patterns that only
+// differ in case if insensitive, are searched for once. invalid patterns are reported by Validate.
+func NewSubstringMatcher(patterns []string, insensitive bool) *SubstringMatcher {
+	m := &SubstringMatcher{insensitive: insensitive, next: make([][16]int32, 1), out: make([][]int32, 1), depth: make([]int, 1)}
+	seen := make(map[string]bool, len(patterns))
+	for _, p := range patterns {
+		if insensitive {
+			p = strings.ToLower(p)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if err := ValidatePattern(p); p == "" || err != nil {
+			if m.err == nil {
+				m.err = validate(p, "")
+			}
+			continue
+		}
+		m.add(p)
+	}
+	m.link()
+	return m
+}
+
+// add adds p to the trie of patterns, whose states are numbered from 1, as 0 is the root.
+func (m *SubstringMatcher) add(p string) {
+	var s int32
+	for i := 0; i < len(p); i++ {
+		d := digit(p[i])
+		if m.next[s][d] == 0 {
+			m.next[s][d] = int32(len(m.next))
+			m.next = append(m.next, [16]int32{})
+			m.out = append(m.out, nil)
+			m.depth = append(m.depth, i+1)
+		}
+		s = m.next[s][d]
+	}
+	m.out[s] = append(m.out[s], int32(len(m.patterns)))
+	m.patterns = append(m.patterns, p)
+}
+
+// link turns the trie into an automaton: every missing transition goes where the longest suffix of the
+// digits matched so far that is in the trie would go, and every state also ends the patterns its
+// longest such suffix ends.
+func (m *SubstringMatcher) link() {
+	fail := make([]int32, len(m.next))
+	queue := make([]int32, 0, len(m.next))
+	for d := range m.next[0] {
+		if s := m.next[0][d]; s != 0 {
+			queue = append(queue, s)
+		}
+	}
+	// states are visited in order of depth, so each one's failure state is done before it is
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		m.out[s] = append(m.out[s], m.out[fail[s]]...)
+		for d := range m.next[s] {
+			if t := m.next[s][d]; t != 0 {
+				fail[t] = m.next[fail[s]][d]
+				queue = append(queue, t)
+			} else {
+				m.next[s][d] = m.next[fail[s]][d]
+			}
+		}
+	}
+}
+
+// digit returns the value of the hex digit c, which must be valid.
+func digit(c byte) int {
+	if c <= '9' {
+		return int(c - '0')
+	}
+	return int(c|0x20-'a') + 10
+}
+
+func (m *SubstringMatcher) Match(addr []byte) bool {
+	var (
+		c checksum
+		s int32
+	)
+	for i := 0; i < hexLen; i++ {
+		s = m.next[s][nibble(addr, i)]
+		if m.out[s] != nil && m.accept(addr, i, s, &c) {
+			return true
+		}
+	}
+	return false
+}
+
+// accept reports whether any of the patterns that state s ends, whose digits match those of addr up to
+// digit i, also matches their case.
+func (m *SubstringMatcher) accept(addr []byte, i int, s int32, c *checksum) bool {
+	if m.insensitive {
+		return true
+	}
+	for _, p := range m.out[s] {
+		p := m.patterns[p]
+		ok := true
+		for j := 0; j < len(p) && ok; j++ {
+			ok = c.eq(addr, i-len(p)+1+j, p[j], true)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Score returns the length of the longest start of a pattern that addr contains, ignoring case.
+func (m *SubstringMatcher) Score(addr []byte) int {
+	var (
+		s int32
+		n int
+	)
+	for i := 0; i < hexLen; i++ {
+		s = m.next[s][nibble(addr, i)]
+		n = max(n, m.depth[s])
+	}
+	return n
+}
+
+// Difficulty returns the expected number of attempts needed to find an address that contains any of
+// the patterns, counting each pattern at each position as if they matched independently, which slightly
+// underestimates it when short patterns overlap.
+func (m *SubstringMatcher) Difficulty() *big.Int {
+	var p float64
+	for _, s := range m.patterns {
+		bits := difficulty(s, "", !m.insensitive).BitLen() - 1
+		p += float64(hexLen-len(s)+1) * math.Ldexp(1, -bits)
+	}
+	if p >= 1 || p == 0 {
+		return big.NewInt(1)
+	}
+	d, _ := big.NewFloat(1 / p).Int(nil)
+	return d
+}
+
+func (m *SubstringMatcher) Validate() error {
+	if m.err != nil {
+		return m.err
+	}
+	if len(m.patterns) == 0 {
+		return ErrNoPattern
+	}
+	return nil
+}
+
+// Patterns returns the patterns searched for, without duplicates, and in lower case if case is ignored.
+func (m *SubstringMatcher) Patterns() []string {
+	return m.patterns
+}
@@ -0,0 +1,55 @@
+package vanity
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"time"
+)
+
+// Tuning is a configuration chosen by Tune.
+type Tuning struct {
+	Workers   int
+	BatchSize int
+	Rate      float64 // keys/s, as measured
+}
+
+// Tune measures the key rate of backend b for dur with each of several worker counts, up to twice
+// GOMAXPROCS, and then with several batch sizes around DefaultBatchSize, and returns the fastest
+// configuration. trial, if not nil, is called with the result of each measurement. the other
+// arguments are as for MeasureRate.
+func Tune(ctx context.Context, b Backend, prefixLen int, insensitive bool, dur time.Duration, trial func(Tuning)) (Tuning, error) {
+	procs := runtime.GOMAXPROCS(0)
+	best := Tuning{BatchSize: DefaultBatchSize()}
+	try := func(t Tuning) error {
+		rate, err := measure(ctx, b, insensitive, dur, WithWorkers(t.Workers), WithBatchSize(t.BatchSize))
+		if err != nil {
+			return err
+		}
+		t.Rate = rate
+		if trial != nil {
+			trial(t)
+		}
+		if t.Rate > best.Rate {
+			best = t
+		}
+		return nil
+	}
+	for _, w := range candidates(max(procs/2, 1), procs, procs+procs/2, 2*procs) {
+		if err := try(Tuning{Workers: w, BatchSize: best.BatchSize}); err != nil {
+			return best, err
+		}
+	}
+	for _, n := range candidates(best.BatchSize/4, best.BatchSize/2, best.BatchSize*2, best.BatchSize*4) {
+		if err := try(Tuning{Workers: best.Workers, BatchSize: n}); err != nil {
+			return best, err
+		}
+	}
+	return best, nil
+}
+
+// candidates returns ns sorted without duplicates.
+func candidates(ns ...int) []int {
+	slices.Sort(ns)
+	return slices.Compact(ns)
+}
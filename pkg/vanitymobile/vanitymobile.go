@@ -0,0 +1,172 @@
+// Package vanitymobile wraps the vanity package for gomobile, whose bindings only support simple types:
+// no channels, maps, unsigned integers or func values. build it with
+//
+//	gomobile bind -target android ./pkg/vanitymobile
+//	gomobile bind -target ios ./pkg/vanitymobile
+package vanitymobile
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Options configures a search. create one with NewOptions and set its fields.
+type Options struct {
+	Prefix, Suffix  string
+	CaseInsensitive bool
+	Backend         string // one of BackendNames; empty means the default
+	Workers         int    // 0 means one per CPU
+	MaxAttempts     int64  // 0 means no limit
+	ProgressSeconds int    // interval between progress reports; 0 means 1
+}
+
+// NewOptions returns the default options.
+func NewOptions() *Options {
+	return &Options{Backend: string(vanity.Geth)}
+}
+
+// BackendNames returns the names of the available backends, separated by newlines.
+func BackendNames() string {
+	var names string
+	for i, b := range vanity.Backends {
+		if i > 0 {
+			names += "\n"
+		}
+		names += string(b.Name)
+	}
+	return names
+}
+
+// BackendSecurity returns the security note for the backend with the given name, or an empty string if
+// there is no such backend.
+func BackendSecurity(name string) string {
+	info, _ := vanity.Backend(name).Info()
+	return info.Security
+}
+
+// ProgressHandler receives reports on a running search. it is implemented by the app.
+type ProgressHandler interface {
+	OnProgress(attempts int64, rate float64, bestScore int)
+}
+
+// Result is a key found by a search.
+type Result struct {
+	Address    string // checksummed, with 0x
+	PrivateKey string // hex, without 0x
+	Attempts   int64
+}
+
+// Search is a search started by Start.
+type Search struct {
+	s      *vanity.Searcher
+	cancel context.CancelFunc
+	done   chan struct{}
+	res    *Result
+	err    error
+}
+
+// ErrCanceled is returned by Wait after Cancel.
+var ErrCanceled = errors.New("search canceled")
+
+// Start starts a search in the background. h may be nil.
+func Start(o *Options, h ProgressHandler) (*Search, error) {
+	if o == nil {
+		o = NewOptions()
+	}
+	if o.MaxAttempts < 0 {
+		return nil, errors.New("MaxAttempts must not be negative")
+	}
+	opts := []vanity.Option{
+		vanity.WithPrefix(o.Prefix),
+		vanity.WithSuffix(o.Suffix),
+		vanity.WithCaseInsensitive(o.CaseInsensitive),
+		vanity.WithMaxAttempts(uint64(o.MaxAttempts)),
+	}
+	if o.Backend != "" {
+		opts = append(opts, vanity.WithBackend(vanity.Backend(o.Backend)))
+	}
+	if o.Workers > 0 {
+		opts = append(opts, vanity.WithWorkers(o.Workers))
+	}
+	if h != nil {
+		interval := time.Duration(max(o.ProgressSeconds, 1)) * time.Second
+		opts = append(opts, vanity.WithTrackBest(true), vanity.WithProgress(interval, func(p vanity.Progress) {
+			h.OnProgress(int64(p.Attempts), p.Rate, p.BestScore)
+		}))
+	}
+	s, err := vanity.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	search := &Search{s: s, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(search.done)
+		defer cancel()
+		res, err := s.Run(ctx)
+		switch {
+		case errors.Is(err, context.Canceled):
+			search.err = ErrCanceled
+		case err != nil:
+			search.err = err
+		default:
+			search.res = &Result{
+				Address:    res.Address.Hex(),
+				PrivateKey: hex.EncodeToString(crypto.FromECDSA(res.Key)),
+				Attempts:   int64(s.Attempts()),
+			}
+		}
+	}()
+	return search, nil
+}
+
+// Generate runs a search and waits for its result.
+func Generate(o *Options) (*Result, error) {
+	s, err := Start(o, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.Wait()
+}
+
+// Wait blocks until the search has finished and returns its result.
+func (s *Search) Wait() (*Result, error) {
+	<-s.done
+	return s.res, s.err
+}
+
+// Done reports whether the search has finished, so that apps can poll instead of blocking in Wait.
+func (s *Search) Done() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Cancel stops the search. Wait then returns ErrCanceled unless a key had already been found.
+func (s *Search) Cancel() {
+	s.cancel()
+}
+
+// Attempts returns the number of keys tried so far.
+func (s *Search) Attempts() int64 {
+	return int64(s.s.Attempts())
+}
+
+// SetPaused pauses or resumes the search, e.g. while the app is in the background.
+func (s *Search) SetPaused(paused bool) {
+	s.s.SetPaused(paused)
+}
+
+// Difficulty returns the expected number of attempts needed to find a match.
+func (s *Search) Difficulty() float64 {
+	return s.s.Difficulty()
+}
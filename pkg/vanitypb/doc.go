@@ -0,0 +1,5 @@
+// Package vanitypb contains the gRPC service that 'vanity serve -grpc-addr' serves, generated from
+// vanity.proto, including its Go client; see NewVanityClient.
+package vanitypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative vanity.proto
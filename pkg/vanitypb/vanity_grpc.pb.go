@@ -0,0 +1,304 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: vanity.proto
+
+// the gRPC counterpart of the REST API served by 'vanity serve'. the two share one job queue.
+
+package vanitypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Vanity_SubmitJob_FullMethodName = "/vanity.v1.Vanity/SubmitJob"
+	Vanity_GetJob_FullMethodName    = "/vanity.v1.Vanity/GetJob"
+	Vanity_ListJobs_FullMethodName  = "/vanity.v1.Vanity/ListJobs"
+	Vanity_CancelJob_FullMethodName = "/vanity.v1.Vanity/CancelJob"
+	Vanity_WatchJob_FullMethodName  = "/vanity.v1.Vanity/WatchJob"
+)
+
+// VanityClient is the client API for Vanity service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VanityClient interface {
+	// SubmitJob queues a search and returns it.
+	SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// GetJob returns a job's progress and, once it is done, its result. the result can only be fetched
+	// with the token that submitted the job.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// ListJobs returns every job of the caller's tenant that the server remembers, without their results.
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// CancelJob cancels a queued or running job.
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// WatchJob sends the job every interval until it finishes, and then once more with its final state.
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (Vanity_WatchJobClient, error)
+}
+
+type vanityClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVanityClient(cc grpc.ClientConnInterface) VanityClient {
+	return &vanityClient{cc}
+}
+
+func (c *vanityClient) SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Vanity_SubmitJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vanityClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Vanity_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vanityClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, Vanity_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vanityClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Vanity_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vanityClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (Vanity_WatchJobClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Vanity_ServiceDesc.Streams[0], Vanity_WatchJob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vanityWatchJobClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Vanity_WatchJobClient interface {
+	Recv() (*Job, error)
+	grpc.ClientStream
+}
+
+type vanityWatchJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *vanityWatchJobClient) Recv() (*Job, error) {
+	m := new(Job)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VanityServer is the server API for Vanity service.
+// All implementations must embed UnimplementedVanityServer
+// for forward compatibility
+type VanityServer interface {
+	// SubmitJob queues a search and returns it.
+	SubmitJob(context.Context, *SubmitJobRequest) (*Job, error)
+	// GetJob returns a job's progress and, once it is done, its result. the result can only be fetched
+	// with the token that submitted the job.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// ListJobs returns every job of the caller's tenant that the server remembers, without their results.
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// CancelJob cancels a queued or running job.
+	CancelJob(context.Context, *CancelJobRequest) (*Job, error)
+	// WatchJob sends the job every interval until it finishes, and then once more with its final state.
+	WatchJob(*WatchJobRequest, Vanity_WatchJobServer) error
+	mustEmbedUnimplementedVanityServer()
+}
+
+// UnimplementedVanityServer must be embedded to have forward compatible implementations.
+type UnimplementedVanityServer struct {
+}
+
+func (UnimplementedVanityServer) SubmitJob(context.Context, *SubmitJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedVanityServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedVanityServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedVanityServer) CancelJob(context.Context, *CancelJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedVanityServer) WatchJob(*WatchJobRequest, Vanity_WatchJobServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedVanityServer) mustEmbedUnimplementedVanityServer() {}
+
+// UnsafeVanityServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VanityServer will
+// result in compilation errors.
+type UnsafeVanityServer interface {
+	mustEmbedUnimplementedVanityServer()
+}
+
+func RegisterVanityServer(s grpc.ServiceRegistrar, srv VanityServer) {
+	s.RegisterService(&Vanity_ServiceDesc, srv)
+}
+
+func _Vanity_SubmitJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VanityServer).SubmitJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vanity_SubmitJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VanityServer).SubmitJob(ctx, req.(*SubmitJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vanity_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VanityServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vanity_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VanityServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vanity_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VanityServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vanity_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VanityServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vanity_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VanityServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vanity_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VanityServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vanity_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VanityServer).WatchJob(m, &vanityWatchJobServer{ServerStream: stream})
+}
+
+type Vanity_WatchJobServer interface {
+	Send(*Job) error
+	grpc.ServerStream
+}
+
+type vanityWatchJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *vanityWatchJobServer) Send(m *Job) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Vanity_ServiceDesc is the grpc.ServiceDesc for Vanity service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Vanity_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vanity.v1.Vanity",
+	HandlerType: (*VanityServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitJob",
+			Handler:    _Vanity_SubmitJob_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _Vanity_GetJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _Vanity_ListJobs_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _Vanity_CancelJob_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchJob",
+			Handler:       _Vanity_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "vanity.proto",
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// plan describes a search as generate would run it, for -dry-run.
+type plan struct {
+	prefix, suffix string   // normalized
+	listed         []string // for -patterns or -prefixes, which replace prefix and suffix
+	anchored       bool     // whether listed are prefixes
+	caseSensitive  bool
+	backend        vanity.Backend
+	workers        int
+	count          int
+	difficulty     float64
+	deadline       time.Time
+	maxAttempts    uint64
+	keyPath        string // empty if no loose key file is written
+	format         string
+	bundlePath     string
+	checkpoint     string
+	resumed        *checkpoint
+	ranges         []vanity.KeyRange
+}
+
+func (p plan) print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	row := func(k, v string, a ...any) { fmt.Fprintf(tw, k+":\t"+v+"\n", a...) }
+
+	switch list := strings.Join(p.listed[:min(len(p.listed), 3)], ","); {
+	case p.listed != nil && p.anchored:
+		row("pattern", "0x{%s}* (any of %d)", list, len(p.listed))
+	case p.listed != nil:
+		row("pattern", "0x*{%s}* (any of %d)", list, len(p.listed))
+	default:
+		row("pattern", "0x%s%s%s", p.prefix, strings.Repeat("*", 40-len(p.prefix)-len(p.suffix)), p.suffix)
+	}
+	row("case-sensitive", "%t", p.caseSensitive)
+	row("chain", "ethereum")
+	if info, ok := p.backend.Info(); ok {
+		row("backend", "%s (security: %s)", info.Name, info.Security)
+	}
+	row("workers", "%d", p.workers)
+	for _, r := range p.ranges {
+		row("key range", "%s", r)
+	}
+	row("keys wanted", "%d", p.count)
+	row("expected attempts", "%.0f per key", p.difficulty)
+	if p.deadline.IsZero() {
+		row("deadline", "none")
+	} else {
+		row("deadline", "%s (in %s)", p.deadline.Format(time.RFC3339), time.Until(p.deadline).Round(time.Second))
+	}
+	if p.maxAttempts > 0 {
+		row("max attempts", "%d", p.maxAttempts)
+	}
+	if p.keyPath != "" {
+		row("key file", "%s (%s)", p.keyPath, p.format)
+	}
+	if p.bundlePath != "" {
+		row("bundle", p.bundlePath)
+	}
+	if p.checkpoint != "" {
+		row("checkpoint", p.checkpoint)
+	}
+	if p.resumed != nil {
+		row("resuming", "after %d attempts with %d keys found", p.resumed.Attempts, len(p.resumed.Found))
+	}
+	tw.Flush()
+}
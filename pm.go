@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// pmTimeout bounds each run of a password manager's CLI, which may wait for the user to unlock it.
+const pmTimeout = 2 * time.Minute
+
+// passwordManager hands keys to the 1Password or Bitwarden CLI as new items. the item is piped to the
+// CLI's stdin, never written to a file or passed as an argument, where other users could read it from
+// the process list.
+type passwordManager struct {
+	cli        string // op or bw
+	vault      string // 1Password vault, or Bitwarden organization ID
+	collection string // Bitwarden collection ID
+}
+
+var errUnknownPasswordManager = errors.New("unknown password manager; use op (1Password) or bw (Bitwarden)")
+
+func newPasswordManager(cli, vault, collection string) (*passwordManager, error) {
+	switch cli {
+	case "op":
+		if collection != "" {
+			return nil, errors.New("-pm-collection is only for Bitwarden")
+		}
+	case "bw":
+		if collection != "" && vault == "" {
+			return nil, errors.New("-pm-collection needs the collection's organization ID as -pm-vault")
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownPasswordManager, cli)
+	}
+	if _, err := exec.LookPath(cli); err != nil {
+		return nil, err
+	}
+	return &passwordManager{cli: cli, vault: vault, collection: collection}, nil
+}
+
+// store creates an item holding key and returns a reference to it, such as op:VAULT/ID, once it has
+// read the item back and checked it. if the item was created but could not be checked, the reference is
+// returned along with the error, so that the caller does not create it again.
+func (pm *passwordManager) store(key *ecdsa.PrivateKey) (string, error) {
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	d := crypto.FromECDSA(key)
+	defer clear(d)
+	keyHex := fmt.Sprintf("%x", d)
+	title := "vanity address " + addr.Hex()
+
+	var item any
+	var args []string
+	switch pm.cli {
+	case "op":
+		type field struct {
+			ID    string `json:"id"`
+			Type  string `json:"type"`
+			Label string `json:"label"`
+			Value string `json:"value"`
+		}
+		item = map[string]any{
+			"title":    title,
+			"category": "SECURE_NOTE",
+			"fields": []field{
+				{"address", "STRING", "address", addr.Hex()},
+				{"private_key", "CONCEALED", "private key", keyHex},
+			},
+		}
+		args = []string{"item", "create", "--format", "json"}
+		if pm.vault != "" {
+			args = append(args, "--vault", pm.vault)
+		}
+	case "bw":
+		type field struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  int    `json:"type"` // 0 text, 1 hidden
+		}
+		it := map[string]any{
+			"type":       2, // secure note
+			"secureNote": map[string]int{"type": 0},
+			"name":       title,
+			"notes":      "generated by vanity " + version,
+			"fields":     []field{{"address", addr.Hex(), 0}, {"private key", keyHex, 1}},
+		}
+		if pm.vault != "" {
+			it["organizationId"] = pm.vault
+		}
+		if pm.collection != "" {
+			it["collectionIds"] = []string{pm.collection}
+		}
+		item = it
+		args = []string{"create", "item"}
+	}
+	in, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	defer clear(in)
+	if pm.cli == "bw" {
+		// bw takes items base64-encoded
+		enc := make([]byte, base64.StdEncoding.EncodedLen(len(in)))
+		base64.StdEncoding.Encode(enc, in)
+		defer clear(enc)
+		in = enc
+	}
+	out, err := pm.run(in, args...)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Vault struct {
+			Name string `json:"name"`
+		} `json:"vault"`
+	}
+	if err = json.Unmarshal(out, &created); err != nil || created.ID == "" {
+		return "", fmt.Errorf("%s did not return the new item's ID: %v", pm.cli, err)
+	}
+	ref := pm.cli + ":" + created.ID
+	if created.Vault.Name != "" {
+		ref = pm.cli + ":" + created.Vault.Name + "/" + created.ID
+	}
+	if err = pm.check(created.ID, addr); err != nil {
+		return ref, fmt.Errorf("%s item %s: %w", pm.cli, created.ID, err)
+	}
+	return ref, nil
+}
+
+// check reads back the item with the given ID and checks that it holds the key of addr.
+func (pm *passwordManager) check(id string, addr common.Address) error {
+	var out []byte
+	var err error
+	if pm.cli == "op" {
+		out, err = pm.run(nil, "item", "get", id, "--fields", "label=private key", "--reveal")
+	} else {
+		out, err = pm.run(nil, "get", "item", id)
+	}
+	if err != nil {
+		return err
+	}
+	defer clear(out)
+	keyHex := string(bytes.TrimSpace(out))
+	if pm.cli == "bw" {
+		var item struct {
+			Fields []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		}
+		if err = json.Unmarshal(out, &item); err != nil {
+			return err
+		}
+		keyHex = ""
+		for _, f := range item.Fields {
+			if f.Name == "private key" {
+				keyHex = f.Value
+			}
+		}
+	}
+	d := common.FromHex(keyHex)
+	defer clear(d)
+	return checkScalar(d, addr)
+}
+
+// run runs the CLI with args and in as its stdin, and returns its stdout.
+func (pm *passwordManager) run(in []byte, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pmTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, pm.cli, args...)
+	if in != nil {
+		cmd.Stdin = bytes.NewReader(in)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %w: %s", pm.cli, strings.Join(args[:2], " "), err, msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", pm.cli, strings.Join(args[:2], " "), err)
+	}
+	return out, nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// a pool shares a split-key search among workers that need not be trusted. the owner of a base key
+// publishes its public key and the pattern through a coordinator, any number of workers search for an
+// offset and submit it, and the coordinator checks each submission against the base public key and the
+// pattern, so that a bogus one is rejected rather than handed to the owner. the offset reveals nothing
+// about the key, which only the owner can make with 'splitkey -offset'.
+
+const (
+	poolPollInterval = 30 * time.Second // how often workers check whether the search is over
+	poolLinger       = time.Minute      // how long the coordinator keeps serving once it is, to tell them
+)
+
+// poolJob is what the coordinator publishes.
+type poolJob struct {
+	PublicKey       string `json:"public_key"` // base public key, in hex
+	Prefix          string `json:"prefix,omitempty"`
+	Suffix          string `json:"suffix,omitempty"`
+	CaseInsensitive bool   `json:"case_insensitive,omitempty"`
+	Done            bool   `json:"done"`
+	Address         string `json:"address,omitempty"` // once done
+}
+
+// poolSubmission is what a worker submits.
+type poolSubmission struct {
+	Offset string `json:"offset"` // in hex
+	Worker string `json:"worker,omitempty"`
+}
+
+// checkSplitOffset checks that the hex offset, combined with the base public key pub, gives an address
+// that m accepts, and returns the offset and the address.
+func checkSplitOffset(pub *ecdsa.PublicKey, m vanity.Matcher, offsetHex string) ([]byte, common.Address, error) {
+	offset, err := hex.DecodeString(strings.TrimPrefix(offsetHex, "0x"))
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("invalid offset: %w", err)
+	}
+	addr, err := vanity.SplitKeyAddress(pub, offset)
+	if err != nil {
+		return nil, addr, err
+	}
+	if !m.Match(addr[:]) {
+		return nil, addr, fmt.Errorf("the offset gives %s, which does not match", addr.Hex())
+	}
+	return offset, addr, nil
+}
+
+// poolCoordinator serves a pool's job and checks its submissions.
+type poolCoordinator struct {
+	pub     *ecdsa.PublicKey
+	matcher vanity.Matcher
+	done    chan struct{} // closed once an offset is accepted
+
+	mu       sync.Mutex
+	job      poolJob
+	offset   []byte
+	rejected int
+}
+
+func (c *poolCoordinator) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /job", func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		job := c.job
+		c.mu.Unlock()
+		writeJSON(w, http.StatusOK, job, nil)
+	})
+	mux.HandleFunc("POST /submissions", func(w http.ResponseWriter, r *http.Request) {
+		var sub poolSubmission
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<12)).Decode(&sub); err != nil {
+			writeJSON(w, 0, nil, httpError{http.StatusBadRequest, err})
+			return
+		}
+		offset, addr, err := checkSplitOffset(c.pub, c.matcher, sub.Offset)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		switch {
+		case c.job.Done:
+			writeJSON(w, 0, nil, httpError{http.StatusGone, errors.New("the search is over")})
+		case err != nil:
+			c.rejected++
+			slog.Warn("rejected a submission", "worker", sub.Worker, "remote", r.RemoteAddr, "err", err, "rejected", c.rejected)
+			writeJSON(w, 0, nil, httpError{http.StatusUnprocessableEntity, err})
+		default:
+			c.job.Done, c.job.Address, c.offset = true, addr.Hex(), offset
+			slog.Info("accepted a submission", "worker", sub.Worker, "remote", r.RemoteAddr, "address", addr.Hex())
+			close(c.done)
+			writeJSON(w, http.StatusOK, c.job, nil)
+		}
+	})
+	return mux
+}
+
+// pool implements the pool subcommand, whose own subcommands are the coordinator and worker of a pool.
+func pool(args []string) int {
+	roles := []struct {
+		name, desc string
+		run        func(args []string) int
+	}{
+		{"serve", "publish a split-key search and check the offsets workers submit", poolServe},
+		{"work", "search for a pool's offset and submit it", poolWork},
+	}
+	if len(args) > 0 {
+		for _, r := range roles {
+			if r.name == args[0] {
+				return r.run(args[1:])
+			}
+		}
+	}
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "usage: %s pool <role> [flags]\n\nshares a split-key search for the base public key of '%[1]s splitkey -new' among untrusted\nworkers. the coordinator rejects offsets that do not give a matching address.\n\nroles:\n", os.Args[0])
+	for _, r := range roles {
+		fmt.Fprintf(w, "  %-6s %s\n", r.name, r.desc)
+	}
+	return exitUsage
+}
+
+func poolServe(args []string) int {
+	fs := flag.NewFlagSet("pool serve", flag.ExitOnError)
+	var (
+		addr        *string = fs.String("addr", ":8547", "address to listen on")
+		pubHex      *string = fs.String("pubkey", "", "base public key to search against, in hex, as printed by 'splitkey -new'")
+		prefix      *string = fs.String("p", "", "output address prefix (excluding 0x)")
+		suffix      *string = fs.String("s", "", "output address suffix")
+		insensitive *bool   = fs.Bool("i", false, "accept case-insensitive solutions")
+		out         *string = fs.String("o", "", "also write the accepted offset to this file")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s pool serve -pubkey KEY [flags]\n\npublishes a split-key search at GET /job and accepts the first offset POSTed to /submissions\nthat gives a matching address, then prints the address.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *pubHex == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	pub, err := parsePublicKey(*pubHex)
+	if err != nil {
+		fatal(usageError{fmt.Errorf("invalid -pubkey: %w", err)})
+	}
+	if err = vanity.ValidatePattern(*prefix + *suffix); err != nil {
+		fatal(err) // long patterns are what pools are for
+	}
+	c := &poolCoordinator{
+		pub:     pub,
+		matcher: vanity.NewMatcher(*prefix, *suffix, *insensitive),
+		done:    make(chan struct{}),
+		job:     poolJob{PublicKey: hex.EncodeToString(crypto.CompressPubkey(pub)), Prefix: *prefix, Suffix: *suffix, CaseInsensitive: *insensitive},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: *addr, Handler: c.handler(), ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	slog.Info("serving the pool", "addr", *addr, "prefix", *prefix, "suffix", *suffix, "expected_attempts", vanity.Difficulty(*prefix, *suffix, !*insensitive))
+	select {
+	case err = <-serveErr:
+		fatal(err)
+	case <-ctx.Done():
+		slog.Info("interrupted")
+		return exitInterrupted
+	case <-c.done:
+	}
+
+	c.mu.Lock()
+	offset, found := c.offset, c.job.Address
+	c.mu.Unlock()
+	fmt.Println(found)
+	if *out != "" {
+		if err = os.WriteFile(*out, []byte(hex.EncodeToString(offset)+"\n"), 0644); err != nil {
+			slog.Error("could not write the offset", "err", err)
+		}
+	}
+	slog.Info("combine the offset with the base key", "offset", hex.EncodeToString(offset), "command", fmt.Sprintf("%s splitkey -k BASE_KEY -offset %x -expect %s", os.Args[0], offset, found))
+
+	// keep serving a while, so that the workers learn the search is over
+	select {
+	case <-time.After(poolLinger):
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdown)
+	return exitOK
+}
+
+// fetchPoolJob fetches the job from the coordinator at base.
+func fetchPoolJob(ctx context.Context, base *url.URL) (*poolJob, error) {
+	var job poolJob
+	return &job, poolRequest(ctx, http.MethodGet, base.JoinPath("job"), nil, &job)
+}
+
+func poolRequest(ctx context.Context, method string, u *url.URL, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(b, &e)
+		return httpError{resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, u.Path, resp.Status, e.Error)}
+	}
+	return json.Unmarshal(b, out)
+}
+
+func poolWork(args []string) int {
+	fs := flag.NewFlagSet("pool work", flag.ExitOnError)
+	var (
+		rawURL     *string = fs.String("url", "", "URL of the coordinator, such as http://192.0.2.1:8547")
+		name       *string = fs.String("name", "", "name to submit offsets under, for the coordinator's log")
+		numWorkers *int    = fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s pool work -url URL [flags]\n\nsearches for the offset of the pool at URL, until it or another worker finds one.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *rawURL == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	base, err := url.Parse(*rawURL)
+	if err != nil {
+		fatal(usageError{fmt.Errorf("invalid -url: %w", err)})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	job, err := fetchPoolJob(ctx, base)
+	if err != nil {
+		fatal(err)
+	}
+	if job.Done {
+		slog.Info("the search is already over", "address", job.Address)
+		return exitOK
+	}
+	pub, err := parsePublicKey(job.PublicKey)
+	if err != nil {
+		fatal(fmt.Errorf("the coordinator's public key: %w", err))
+	}
+	search, err := splitSearch(pub, job.Prefix, job.Suffix, job.CaseInsensitive, *numWorkers)
+	if err != nil {
+		fatal(err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var overtaken atomic.Bool
+	// stop once another worker has found it
+	go func() {
+		t := time.NewTicker(poolPollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if j, err := fetchPoolJob(ctx, base); err != nil {
+					slog.Warn("could not reach the coordinator", "err", err)
+				} else if j.Done {
+					slog.Info("another worker found it", "address", j.Address)
+					overtaken.Store(true)
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	slog.Info("searching", "prefix", job.Prefix, "suffix", job.Suffix, "case_sensitive", !job.CaseInsensitive)
+	res, err := search.Run(ctx)
+	switch {
+	case err != nil && overtaken.Load():
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		slog.Info("interrupted", "attempts", search.Attempts())
+		return exitInterrupted
+	case err != nil:
+		fatal(err)
+	}
+	offset := crypto.FromECDSA(res.Key)
+	var accepted poolJob
+	err = poolRequest(context.Background(), http.MethodPost, base.JoinPath("submissions"), poolSubmission{hex.EncodeToString(offset), *name}, &accepted)
+	var he httpError
+	if errors.As(err, &he) && he.code == http.StatusGone {
+		slog.Info("another worker found it first", "attempts", search.Attempts())
+		return exitOK
+	} else if err != nil {
+		fatal(err)
+	}
+	fmt.Println(accepted.Address)
+	slog.Info("the coordinator accepted the offset", "attempts", search.Attempts())
+	return exitOK
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	_ "net/http/pprof" // registers its handlers on http.DefaultServeMux
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// profileOptions holds the flags for profiling a command.
+type profileOptions struct {
+	cpu  *string
+	mem  *string
+	addr *string
+	f    *os.File // the CPU profile being written
+}
+
+func addProfileFlags(fs *flag.FlagSet) *profileOptions {
+	return &profileOptions{
+		cpu:  fs.String("cpuprofile", "", "write a CPU profile to this file"),
+		mem:  fs.String("memprofile", "", "write a heap profile to this file on exit"),
+		addr: fs.String("pprof", "", "serve net/http/pprof on this address, e.g. localhost:6060"),
+	}
+}
+
+// start begins CPU profiling and starts the pprof listener, as requested.
+func (o *profileOptions) start() error {
+	if *o.addr != "" {
+		go func() {
+			// not fatal; the search is worth more than the profile
+			if err := http.ListenAndServe(*o.addr, nil); err != nil {
+				slog.Warn("pprof listener failed", "err", err)
+			}
+		}()
+	}
+	if *o.cpu == "" {
+		return nil
+	}
+	f, err := os.Create(*o.cpu)
+	if err != nil {
+		return err
+	}
+	if err = pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	o.f = f
+	return nil
+}
+
+// stop finishes the CPU profile and writes the heap profile.
+func (o *profileOptions) stop() {
+	if o.f != nil {
+		pprof.StopCPUProfile()
+		o.f.Close()
+	}
+	if *o.mem == "" {
+		return
+	}
+	f, err := os.Create(*o.mem)
+	if err != nil {
+		slog.Warn("could not write heap profile", "err", err)
+		return
+	}
+	defer f.Close()
+	runtime.GC() // for up to date statistics
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		slog.Warn("could not write heap profile", "err", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// reportProgress logs the number of attempts made so far, the key rate and the expected time to a match
+// of search every interval, and whenever the process receives SIGUSR1 on systems that have it. an
+// interval <= 0 disables the periodic reports. if deadline is not zero,
+// the chance of finding a match before it is reported as well. start is when the search began, which is
+// earlier than now for resumed searches. if there is more than one worker, the rate of each is reported
+// too, at debug level. for searches of a -range, rangeSize is its size and the share of it covered is
+// reported. it returns once ctx is done.
+func reportProgress(ctx context.Context, search *vanity.Searcher, interval time.Duration, deadline, start time.Time, rangeSize uint64) {
+	sig := make(chan os.Signal, 1)
+	notifyProgress(sig)
+	defer signal.Stop(sig)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	d := search.Difficulty()
+	last, lastN := time.Now(), search.Attempts()
+	lastW := search.WorkerAttempts()
+	for {
+		select {
+		case <-tick:
+		case <-sig:
+		case <-ctx.Done():
+			return
+		}
+		now, n := time.Now(), search.Attempts()
+		rate := float64(n) / now.Sub(start).Seconds()
+		// attempts are independent, so the expected time remaining never shrinks.
+		attrs := []any{
+			"attempts", n,
+			"elapsed", now.Sub(start).Round(time.Second),
+			"keys_per_sec", math.Round(float64(n-lastN) / now.Sub(last).Seconds()),
+			"overall_keys_per_sec", math.Round(rate),
+			"expected_time", fmtSeconds(d / rate),
+		}
+		if !deadline.IsZero() {
+			left := max(deadline.Sub(now), 0)
+			attrs = append(attrs,
+				"chance", fmt.Sprintf("%.1f%%", 100*successProbability(rate*left.Seconds(), d)),
+				"remaining", left.Round(time.Second))
+		}
+		if rangeSize > 0 {
+			attrs = append(attrs, "covered", fmt.Sprintf("%.2f%%", 100*covered(search, rangeSize)))
+		}
+		if invalid := search.Invalid(); invalid > 0 {
+			attrs = append(attrs, "invalid", invalid)
+		}
+		slog.Info("progress", attrs...)
+		if len(lastW) > 1 {
+			rates := make([]float64, len(lastW))
+			for i, w := range search.WorkerAttempts() {
+				rates[i] = math.Round(float64(w-lastW[i]) / now.Sub(last).Seconds())
+				lastW[i] = w
+			}
+			slog.Debug("keys/s by worker", "rates", rates)
+		}
+		last, lastN = now, n
+	}
+}
+
+// covered returns the share of a -range of rangeSize keys that has been searched, including by the
+// earlier sessions of a resumed search.
+func covered(search *vanity.Searcher, rangeSize uint64) float64 {
+	tried, total := search.RangeProgress()
+	return 1 - float64(total-tried)/float64(rangeSize)
+}
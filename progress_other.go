@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// there is no SIGUSR1 equivalent; progress is only reported periodically.
+func notifyProgress(c chan<- os.Signal) {}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// limits are the quotas each tenant of a server is held to. zero means no limit.
+type limits struct {
+	jobs          int           // queued or running at once
+	cpu           time.Duration // worker time over the server's lifetime
+	maxDifficulty float64       // expected attempts per job
+}
+
+// quotaError rejects a job that would take its tenant over one of its limits. it carries the job's
+// expected cost, so that the client can judge how much easier a pattern it needs.
+type quotaError struct {
+	code       int
+	msg        string
+	expected   float64 // attempts
+	cpuSeconds float64 // estimated; 0 if the server has no key rate to go by
+}
+
+func (e quotaError) Error() string {
+	s := fmt.Sprintf("%s; the pattern needs about %.3g attempts", e.msg, e.expected)
+	if e.cpuSeconds > 0 {
+		s += fmt.Sprintf(", or %s of CPU time", fmtSeconds(e.cpuSeconds))
+	}
+	return s
+}
+
+// checkQuota returns a quotaError if j would take its tenant over a limit. s.mu must be held.
+func (s *server) checkQuota(j *job) error {
+	expected := j.search.Difficulty()
+	reject := func(code int, format string, args ...any) error {
+		e := quotaError{code: code, msg: fmt.Sprintf(format, args...), expected: expected}
+		if rate := s.keyRate(string(j.search.Backend)); rate > 0 {
+			e.cpuSeconds = expected / rate
+		}
+		return e
+	}
+	if l := s.limits.maxDifficulty; l > 0 && expected > l {
+		return reject(http.StatusForbidden, "this server accepts jobs of at most %.3g expected attempts", l)
+	}
+	if l := s.limits.jobs; l > 0 {
+		var active int
+		for _, id := range s.order {
+			if other := s.jobs[id]; other.tenant == j.tenant {
+				other.mu.Lock()
+				if other.status == jobQueued || other.status == jobRunning {
+					active++
+				}
+				other.mu.Unlock()
+			}
+		}
+		if active >= l {
+			return reject(http.StatusTooManyRequests, "%d of your jobs are already queued or running, the most this server allows", active)
+		}
+	}
+	if l := s.limits.cpu; l > 0 && s.cpuUsed(j.tenant) >= l {
+		return reject(http.StatusForbidden, "you have used all %s of CPU time this server allows", l)
+	}
+	return nil
+}
+
+// cpuLeft returns how much of its CPU time tenant has left, and false if it has no limit.
+func (s *server) cpuLeft(tenant string) (time.Duration, bool) {
+	if s.limits.cpu <= 0 {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limits.cpu - s.cpuUsed(tenant), true
+}
+
+// cpuUsed returns the worker time spent on tenant's jobs, including those still running. s.mu must be
+// held.
+func (s *server) cpuUsed(tenant string) time.Duration {
+	used := s.usage[tenant]
+	for _, id := range s.order {
+		if j := s.jobs[id]; j.tenant == tenant {
+			j.mu.Lock()
+			if j.status == jobRunning && !j.started.IsZero() {
+				used += time.Since(j.started) * time.Duration(s.workers)
+			}
+			j.mu.Unlock()
+		}
+	}
+	return used
+}
+
+// record charges j's worker time to its tenant once it has finished, and adds it to the server's
+// observed key rate.
+func (s *server) record(j *job) {
+	j.mu.Lock()
+	spent := j.finished.Sub(j.started) * time.Duration(s.workers)
+	j.mu.Unlock()
+	if spent <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage[j.tenant] += spent
+	s.attempts += j.search.Attempts()
+	s.worked += spent
+}
+
+// keyRate returns the keys per second a single worker finds, as observed over the jobs run so far, or
+// else from the calibration profile, or 0 if there is neither. s.mu must be held.
+func (s *server) keyRate(backend string) float64 {
+	switch {
+	case s.worked > 0:
+		return float64(s.attempts) / s.worked.Seconds()
+	case s.cal != nil && s.cal.Workers > 0:
+		return s.cal.Rates[backend] / float64(s.cal.Workers)
+	}
+	return 0
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/openpgp"
+)
+
+// the key files and bundles generate writes are read back and checked before it reports success, so
+// that a full disk or an encoding bug never leaves a matched address with no usable key. the address
+// is derived with decred's secp256k1, independently of the go-ethereum code the key was written with.
+
+var errReadBack = errors.New("the saved key does not match the address found")
+
+// checkKeyFile reads back the key file at path, in format, and checks that it holds the key of addr.
+// paths that are not regular files, such as /dev/stdout, cannot be read back and are not checked.
+func checkKeyFile(path, format string, pass []byte, addr common.Address) error {
+	data, err := readBack(path)
+	if data == nil || err != nil {
+		return err
+	}
+	defer clear(data)
+	var d []byte
+	switch format {
+	case "eip2335":
+		k, err := decryptV4(data, pass)
+		if err != nil {
+			return err
+		}
+		d = crypto.FromECDSA(k)
+		vanity.ZeroKey(k)
+	case "foundry":
+		k, err := keystore.DecryptKey(data, string(pass))
+		if err != nil {
+			return err
+		}
+		d = crypto.FromECDSA(k.PrivateKey)
+		vanity.ZeroKey(k.PrivateKey)
+	default:
+		if d, err = hex.DecodeString(string(bytes.TrimSpace(data))); err != nil {
+			return err
+		}
+	}
+	defer clear(d)
+	return checkScalar(d, addr)
+}
+
+// checkBundle reads back the bundle at path and checks that the key in it is the key of addr.
+func checkBundle(bundlePath string, pass []byte, addr common.Address) error {
+	data, err := readBack(bundlePath)
+	if data == nil || err != nil {
+		return err
+	}
+	tried := false
+	md, err := openpgp.ReadMessage(bytes.NewReader(data), nil, func([]openpgp.Key, bool) ([]byte, error) {
+		if tried {
+			return nil, errors.New("wrong passphrase")
+		}
+		tried = true
+		return pass, nil
+	}, nil)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(md.UnverifiedBody)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%w: the bundle has no priv.key", errReadBack)
+		} else if err != nil {
+			return err
+		}
+		if path.Base(hdr.Name) != "priv.key" {
+			continue
+		}
+		keyHex, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		defer clear(keyHex)
+		d, err := hex.DecodeString(string(keyHex))
+		if err != nil {
+			return err
+		}
+		defer clear(d)
+		return checkScalar(d, addr)
+	}
+}
+
+// readBack returns the contents of the file at path, or nil if it is not a regular file.
+func readBack(path string) ([]byte, error) {
+	if fi, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if !fi.Mode().IsRegular() {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
+
+// checkScalar checks that d is the private key of addr.
+func checkScalar(d []byte, addr common.Address) error {
+	if len(d) != 32 {
+		return fmt.Errorf("%w: the key is %d bytes long", errReadBack, len(d))
+	}
+	pub := secp256k1.PrivKeyFromBytes(d).PubKey().SerializeUncompressed()
+	if common.BytesToAddress(crypto.Keccak256(pub[1:])[12:]) != addr {
+		return errReadBack
+	}
+	return nil
+}
+
+// rescue saves the key that write could not save to path, which must not be lost while it is still
+// in memory. on a terminal, it asks for other paths until one works or the user gives up; otherwise
+// it tries path with .retry appended, once. it returns the path the key was saved to.
+func rescue(path string, write func(string) error) (string, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		alt := path + ".retry"
+		return alt, write(alt)
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "path to save the key to instead (empty to give up): ")
+		line, err := in.ReadString('\n')
+		alt := strings.TrimSpace(line)
+		if alt == "" {
+			if err == nil {
+				err = errors.New("gave up saving the key")
+			}
+			return "", err
+		}
+		if err = write(alt); err == nil {
+			return alt, nil
+		}
+		slog.Error("could not save the key there either", "path", alt, "err", err)
+	}
+}
+
+// saveChecked saves a key with write, which writes it to a path and reads it back, and returns the path
+// it was saved to: path, or another one if that failed and rescue found one. it exits if none worked.
+func saveChecked(path string, write func(string) error) string {
+	err := write(path)
+	if err == nil {
+		return path
+	}
+	slog.Error("could not save the key", "path", path, "err", err)
+	alt, err := rescue(path, write)
+	if err != nil {
+		fatal(err)
+	}
+	slog.Warn("saved the key to another path", "path", alt)
+	return alt
+}
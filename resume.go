@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resume implements the resume subcommand, a shorthand for generate -resume.
+func resume(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[len(args)-1], "-") {
+		fmt.Fprintf(os.Stderr, "usage: %s resume [generate flags] checkpoint\n\ncontinues the search saved in a checkpoint file; flags override the saved ones.\n", os.Args[0])
+		return exitUsage
+	}
+	return generate(append([]string{"-resume", args[len(args)-1]}, args[:len(args)-1]...))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// rpcTimeout bounds each request to a node or Etherscan.
+const rpcTimeout = 30 * time.Second
+
+// rpcClient reads the state of addresses from an Ethereum JSON-RPC endpoint, or from Etherscan's API,
+// given as its URL with an apikey parameter, such as https://api.etherscan.io/v2/api?chainid=1&apikey=KEY.
+type rpcClient struct {
+	endpoint  *url.URL
+	etherscan bool
+}
+
+func newRPCClient(rawURL string) (*rpcClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%s is not an http or https URL", u.Redacted())
+	}
+	return &rpcClient{endpoint: u, etherscan: strings.HasSuffix(u.Hostname(), "etherscan.io")}, nil
+}
+
+// String returns the endpoint's URL without any credentials, which API keys in the path or query are.
+func (c *rpcClient) String() string {
+	return c.endpoint.Scheme + "://" + c.endpoint.Host
+}
+
+// addressUse is what the chain shows of an address's use.
+type addressUse struct {
+	Balance *big.Int // in wei
+	Nonce   uint64   // transactions sent
+	Code    int      // bytes of contract code
+}
+
+// used reports whether the address has any history: a freshly generated one has none.
+func (u addressUse) used() bool {
+	return u.Balance.Sign() != 0 || u.Nonce != 0 || u.Code != 0
+}
+
+// addressUse returns the state of addr at the latest block.
+func (c *rpcClient) addressUse(ctx context.Context, addr common.Address) (addressUse, error) {
+	var u addressUse
+	var err error
+	if u.Balance, err = c.balance(ctx, addr); err != nil {
+		return u, err
+	}
+	nonce, err := c.call(ctx, "eth_getTransactionCount", addr)
+	if err != nil {
+		return u, err
+	}
+	if u.Nonce, err = hexutil.DecodeUint64(nonce); err != nil {
+		return u, fmt.Errorf("eth_getTransactionCount: %w", err)
+	}
+	code, err := c.call(ctx, "eth_getCode", addr)
+	if err != nil {
+		return u, err
+	}
+	b, err := hexutil.Decode(code)
+	if err != nil {
+		return u, fmt.Errorf("eth_getCode: %w", err)
+	}
+	u.Code = len(b)
+	return u, nil
+}
+
+// balance returns the balance of addr at the latest block, in wei.
+func (c *rpcClient) balance(ctx context.Context, addr common.Address) (*big.Int, error) {
+	bal, err := c.call(ctx, "eth_getBalance", addr)
+	if err != nil {
+		return nil, err
+	}
+	return parseQuantity(bal)
+}
+
+// parseQuantity parses a JSON-RPC quantity, or the decimal balances of Etherscan's account module.
+func parseQuantity(s string) (*big.Int, error) {
+	if strings.HasPrefix(s, "0x") {
+		return hexutil.DecodeBig(s)
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
+
+// call calls method, which takes an address and a block, for addr at the latest block, and returns
+// its result, which is a hex string for every method used here.
+func (c *rpcClient) call(ctx context.Context, method string, addr common.Address) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+	var req *http.Request
+	var err error
+	if c.etherscan {
+		// the proxy module mirrors JSON-RPC, except for balances, which the account module has
+		q := c.endpoint.Query()
+		q.Set("module", "proxy")
+		q.Set("action", method)
+		if method == "eth_getBalance" {
+			q.Set("module", "account")
+			q.Set("action", "balance")
+		}
+		q.Set("address", addr.Hex())
+		q.Set("tag", "latest")
+		u := *c.endpoint
+		u.RawQuery = q.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	} else {
+		body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": []any{addr, "latest"}})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint.String(), bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "vanity/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// the error includes the URL, which may hold an API key
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return "", fmt.Errorf("%s %s: %w", method, c, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("%s %s: %s", method, c, resp.Status)
+	}
+	var r struct {
+		Result  json.RawMessage `json:"result"`
+		Status  string          `json:"status"`  // Etherscan's, "0" on failure
+		Message string          `json:"message"` // Etherscan's
+		Error   *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.Unmarshal(data, &r); err != nil {
+		return "", fmt.Errorf("%s %s: %w", method, c, err)
+	}
+	var result string
+	json.Unmarshal(r.Result, &result)
+	switch {
+	case r.Error != nil:
+		return "", fmt.Errorf("%s %s: %s", method, c, r.Error.Message)
+	case r.Status == "0":
+		return "", fmt.Errorf("%s %s: %s: %s", method, c, r.Message, result)
+	case result == "":
+		return "", fmt.Errorf("%s %s: no result", method, c)
+	}
+	return result, nil
+}
+
+// checkUnused checks with c that addr, which was just found, has never been used, and warns if it has:
+// then someone else holds its key, which for a freshly drawn key points to a broken source of
+// randomness. it only warns, as the key has been saved by then.
+func checkUnused(c *rpcClient, addr common.Address) {
+	u, err := c.addressUse(context.Background(), addr)
+	if err != nil {
+		slog.Warn("could not check that the address is unused", "address", addr.Hex(), "err", err)
+		return
+	}
+	if u.used() {
+		slog.Error("THE ADDRESS HAS ALREADY BEEN USED: someone else may hold its key; do not use it", "address", addr.Hex(), "balance_wei", u.Balance, "nonce", u.Nonce, "code_bytes", u.Code, "rpc", c)
+		return
+	}
+	slog.Info("checked that the address is unused", "address", addr.Hex(), "rpc", c)
+}
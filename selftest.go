@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// known-answer vectors, all from published sources so that they check this build against the outside
+// world rather than against itself.
+var (
+	// private keys and their addresses. 1, 2 and 3 are the smallest valid keys; the last is the example
+	// account from the web3.js documentation.
+	keyVectors = []struct{ key, addr string }{
+		{"0000000000000000000000000000000000000000000000000000000000000001", "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"},
+		{"0000000000000000000000000000000000000000000000000000000000000002", "0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF"},
+		{"0000000000000000000000000000000000000000000000000000000000000003", "0x6813Eb9362372EEF6200f3b1dbC3f819671cBA69"},
+		{"4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},
+	}
+
+	// the EIP-55 checksum examples
+	checksumVectors = []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+
+	// the first account of the all-"abandon" BIP-39 test mnemonic, as derived by every major wallet
+	mnemonicVector = struct{ mnemonic, path, addr string }{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"m/44'/60'/0'/0/0",
+		"0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+	}
+
+	// the scrypt test vector from EIP-2335
+	eip2335Vector = struct{ password, secret, keystore string }{
+		"\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511",
+		"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+		`{"crypto": {
+			"kdf": {"function": "scrypt", "params": {"dklen": 32, "n": 262144, "p": 1, "r": 8, "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"}, "message": ""},
+			"checksum": {"function": "sha256", "params": {}, "message": "d2217fe5f3e9a1e34581ef8a78f7c9928e436d36dacc5e846690a5581e8ea484"},
+			"cipher": {"function": "aes-128-ctr", "params": {"iv": "264daa3f303d7259501c93d997d84fe6"}, "message": "06ae90d55fe0a6e9c5c3bc5b170827b2e5cce3929ed3f116c2811e6366dfe20f"}},
+		"pubkey": "", "path": "m/12381/60/3141592653/589793238", "uuid": "1d85ae20-35c5-4611-98e8-aa14a633906f", "version": 4}`,
+	}
+
+	// the "HELLO WORLD" version 1-M example from the QR code specification: its data codewords and the
+	// error correction codewords that follow them
+	qrVector = struct{ data, ecc []byte }{
+		[]byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+		[]byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23},
+	}
+)
+
+// selftests are the checks run by the selftest subcommand.
+var selftests = []struct {
+	name string
+	run  func() error
+}{
+	{"key to address (geth)", testKeys(func(b []byte) (common.Address, error) {
+		k, err := crypto.ToECDSA(b)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(k.PublicKey), nil
+	})},
+	{"key to address (dcrd)", testKeys(func(b []byte) (common.Address, error) {
+		pub := secp256k1.PrivKeyFromBytes(b).PubKey().SerializeUncompressed()
+		return common.BytesToAddress(crypto.Keccak256(pub[1:])[12:]), nil
+	})},
+	{"crypto/rand health", vanity.CheckEntropy},
+	{"key sources", testKeySources},
+	{"key ranges", testKeyRanges},
+	{"batch hashing", testBatchHasher},
+	{"EIP-55 checksums", testChecksums},
+	{"case-sensitive matching", testMatchers(false)},
+	{"case-insensitive matching", testMatchers(true)},
+	{"substring matching", testSubstrings},
+	{"prefix set matching", testPrefixSets},
+	{"BIP-39/BIP-32 derivation", testMnemonic},
+	{"EIP-2335 keystore decryption", testKeystore},
+	{"EIP-2335 keystore round trip", testKeystoreRoundTrip},
+	{"QR error correction", testQR},
+}
+
+func testKeys(toAddr func([]byte) (common.Address, error)) func() error {
+	return func() error {
+		for _, v := range keyVectors {
+			b, _ := hex.DecodeString(v.key)
+			a, err := toAddr(b)
+			if err != nil {
+				return err
+			}
+			if a.Hex() != v.addr {
+				return fmt.Errorf("key %s: got %s, want %s", v.key, a.Hex(), v.addr)
+			}
+		}
+		return nil
+	}
+}
+
+// testKeySources checks that every backend's keys carry the public keys of their private keys, which
+// matters for the ones that don't derive the former from the latter directly.
+func testKeySources() error {
+	for _, b := range vanity.Backends {
+		src, err := vanity.NewKeySource(b.Name, 0)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 100; i++ {
+			k, err := src.Next()
+			if err != nil {
+				return fmt.Errorf("%s: %w", b.Name, err)
+			}
+			want, err := crypto.ToECDSA(crypto.FromECDSA(k))
+			if err != nil {
+				return fmt.Errorf("%s: %w", b.Name, err)
+			}
+			if !k.PublicKey.Equal(&want.PublicKey) {
+				return fmt.Errorf("%s: key %d has the wrong public key", b.Name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// testKeyRanges checks that searching a range tries exactly the keys in it, using the smallest keys,
+// whose addresses are known.
+func testKeyRanges() error {
+	r, err := vanity.ParseKeyRange("1:3")
+	if err != nil {
+		return err
+	}
+	rec := new(recorder)
+	s, err := vanity.New(
+		vanity.WithMatcher(rec),
+		vanity.WithWorkers(2),
+		vanity.WithRanges(r),
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = s.Run(context.Background()); err != vanity.ErrExhausted {
+		return fmt.Errorf("got error %v, want %v", err, vanity.ErrExhausted)
+	}
+	if len(rec.addrs) != 3 {
+		return fmt.Errorf("tried %d keys, want 3", len(rec.addrs))
+	}
+	for i, v := range keyVectors[:3] {
+		if !slices.Contains(rec.addrs, common.HexToAddress(v.addr)) {
+			return fmt.Errorf("key %d was not tried", i+1)
+		}
+	}
+	return nil
+}
+
+// recorder is a Matcher that accepts nothing and records every address it is shown.
+type recorder struct {
+	mu    sync.Mutex
+	addrs []common.Address
+}
+
+func (r *recorder) Match(addr []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addrs = append(r.addrs, common.BytesToAddress(addr))
+	return false
+}
+
+func (r *recorder) Difficulty() *big.Int { return big.NewInt(1) }
+
+func (r *recorder) Validate() error { return nil }
+
+func testChecksums() error {
+
+	for _, v := range checksumVectors {
+		if got := common.HexToAddress(v).Hex(); got != v {
+			return fmt.Errorf("got %s, want %s", got, v)
+		}
+	}
+	return nil
+}
+
+// testBatchHasher checks the hasher workers use, which may be one written for this CPU, against
+// go-ethereum's, over batches of every size up to a few dozen keys.
+func testBatchHasher() error {
+	h := vanity.NewBatchHasher()
+	for n := 0; n < 40; n++ {
+		pubs, sums := make([][64]byte, n), make([][32]byte, n)
+		for i := range pubs {
+			copy(pubs[i][:], crypto.Keccak256([]byte{byte(n), byte(i)}, []byte{byte(i)}))
+			copy(pubs[i][32:], crypto.Keccak256(pubs[i][:32]))
+		}
+		h.Hash(pubs, sums)
+		for i := range pubs {
+			if want := crypto.Keccak256(pubs[i][:]); !bytes.Equal(sums[i][:], want) {
+				return fmt.Errorf("key %d of %d: got %x, want %x", i, n, sums[i], want)
+			}
+		}
+	}
+	return nil
+}
+
+func testMatchers(insensitive bool) func() error {
+	return func() error {
+		a := common.HexToAddress(checksumVectors[0]) // 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed
+		cases := []struct {
+			prefix, suffix string
+			sensitive      bool // the expected result in case-sensitive mode
+			insensitive    bool
+		}{
+			{"5aAe", "", true, true},
+			{"5aae", "", false, true},
+			{"", "BeAed", true, true},
+			{"", "beaed", false, true},
+			{"5a", "ed", true, true},
+			{"5b", "", false, false},
+			{"", "BeAee", false, false},
+		}
+		for _, c := range cases {
+			s := &vanity.Searcher{Prefix: c.prefix, Suffix: c.suffix, CaseInsensitive: insensitive}
+			want := c.sensitive
+			if insensitive {
+				want = c.insensitive
+			}
+			if got := s.Matches(a); got != want {
+				return fmt.Errorf("prefix %q, suffix %q: got %t, want %t", c.prefix, c.suffix, got, want)
+			}
+			full := len(c.prefix) + len(c.suffix)
+			if score := s.Score(a); (score == full) != want {
+				return fmt.Errorf("prefix %q, suffix %q: partial score %d disagrees with the match", c.prefix, c.suffix, score)
+			}
+		}
+		return nil
+	}
+}
+
+func testSubstrings() error {
+	a := common.HexToAddress(checksumVectors[0]) // 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed
+	cases := []struct {
+		patterns    []string
+		sensitive   bool // the expected result in case-sensitive mode
+		insensitive bool
+	}{
+		{[]string{"6053F3"}, true, true},
+		{[]string{"6053f3"}, false, true},
+		{[]string{"5aAe"}, true, true},
+		{[]string{"BeAed"}, true, true},
+		{[]string{"beef", "dead", "b9A09f"}, true, true},
+		{[]string{"beef", "b9a09f"}, false, true},
+		{[]string{"beef", "dead", "5aAf"}, false, false},
+	}
+	for _, c := range cases {
+		for _, insensitive := range []bool{false, true} {
+			m := vanity.NewSubstringMatcher(c.patterns, insensitive)
+			want := c.sensitive
+			if insensitive {
+				want = c.insensitive
+			}
+			if got := m.Match(a[:]); got != want {
+				return fmt.Errorf("patterns %q, case-insensitive %t: got %t, want %t", c.patterns, insensitive, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+func testPrefixSets() error {
+	a := common.HexToAddress(checksumVectors[0]) // 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed
+	cases := []struct {
+		prefixes    []string
+		sensitive   bool // the expected result in case-sensitive mode
+		insensitive bool
+	}{
+		{[]string{"5aAeb6"}, true, true},
+		{[]string{"5aaeb6"}, false, true},
+		{[]string{"beef", "dead", "5aAe"}, true, true},
+		{[]string{"beef", "5aAeb60", "5aae"}, true, true},
+		{[]string{"beef", "5AaEb6"}, false, true},
+		{[]string{"beef", "dead", "aAeb"}, false, false},
+	}
+	for _, c := range cases {
+		for _, insensitive := range []bool{false, true} {
+			m := vanity.NewPrefixSetMatcher(c.prefixes, insensitive)
+			want := c.sensitive
+			if insensitive {
+				want = c.insensitive
+			}
+			if got := m.Match(a[:]); got != want {
+				return fmt.Errorf("prefixes %q, case-insensitive %t: got %t, want %t", c.prefixes, insensitive, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+func testMnemonic() error {
+	v := mnemonicVector
+	seed := bip39.NewSeed(v.mnemonic, "")
+	path, err := accounts.ParseDerivationPath(v.path)
+	if err != nil {
+		return err
+	}
+	k, err := deriveKey(seed, path)
+	if err != nil {
+		return err
+	}
+	if got := crypto.PubkeyToAddress(k.PublicKey).Hex(); got != v.addr {
+		return fmt.Errorf("got %s, want %s", got, v.addr)
+	}
+	return nil
+}
+
+func testKeystore() error {
+	v := eip2335Vector
+	k, err := decryptV4([]byte(v.keystore), []byte(v.password))
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(crypto.FromECDSA(k)); got != v.secret {
+		return fmt.Errorf("got secret %s, want %s", got, v.secret)
+	}
+	return nil
+}
+
+func testKeystoreRoundTrip() error {
+	b, _ := hex.DecodeString(keyVectors[3].key)
+	k, err := crypto.ToECDSA(b)
+	if err != nil {
+		return err
+	}
+	pass := []byte("selftest")
+	ks, err := encryptV4(k, pass, "pbkdf2", "")
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(ks)
+	if err != nil {
+		return err
+	}
+	got, err := decryptV4(data, pass)
+	if err != nil {
+		return err
+	}
+	if !got.Equal(k) {
+		return fmt.Errorf("decrypted a different key")
+	}
+	if _, err = decryptV4(data, []byte("wrong")); err != errWrongPassphrase {
+		return fmt.Errorf("wrong passphrase: got error %v, want %v", err, errWrongPassphrase)
+	}
+	return nil
+}
+
+func testQR() error {
+	v := qrVector
+	if got := rsRemainder(v.data, rsDivisor(len(v.ecc))); !bytes.Equal(got, v.ecc) {
+		return fmt.Errorf("got %v, want %v", got, v.ecc)
+	}
+	// an address fills a version 3 symbol exactly
+	q, err := qrEncode([]byte(keyVectors[0].addr))
+	if err != nil {
+		return err
+	}
+	if q.size != 29 {
+		return fmt.Errorf("an address encoded to a %dx%[1]d symbol, want 29x29", q.size)
+	}
+	return nil
+}
+
+// selftest implements the selftest subcommand, which checks the key derivation, matching and encoding
+// code of this build against known answers.
+func selftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s selftest\n\nchecks this build's key derivation, matching and encoding against known-answer vectors.\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	status := exitOK
+	for _, t := range selftests {
+		start := time.Now()
+		if err := t.run(); err != nil {
+			fmt.Printf("FAIL  %s: %v\n", t.name, err)
+			status = exitFailure
+			continue
+		}
+		fmt.Printf("ok    %s (%s)\n", t.name, time.Since(start).Round(time.Millisecond))
+	}
+	if status != exitOK {
+		fmt.Println("\nsome checks failed; do not trust keys generated by this build")
+	}
+	return status
+}
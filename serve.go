@@ -0,0 +1,809 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// job states
+const (
+	jobQueued   = "queued"
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobFailed   = "failed"
+	jobCanceled = "canceled"
+)
+
+// jobRequest is the body of a POST /jobs request.
+type jobRequest struct {
+	Prefix          string  `json:"prefix"`
+	Suffix          string  `json:"suffix"`
+	CaseInsensitive bool    `json:"case_insensitive"`
+	Backend         string  `json:"backend"`
+	MaxAttempts     uint64  `json:"max_attempts"`
+	TimeoutSeconds  float64 `json:"timeout_seconds"`
+	PublicKey       string  `json:"public_key"` // base public key of a split-key search, in hex
+	OneTime         bool    `json:"one_time"`   // forget the result once it has been fetched
+}
+
+type jobResult struct {
+	Address    string `json:"address"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Offset     string `json:"offset,omitempty"`     // for split-key jobs, to be combined with 'vanity splitkey'
+	Downloaded bool   `json:"downloaded,omitempty"` // the one-time result has been fetched and forgotten
+
+	// signed by the key, so the submitter can show others that they hold it; split-key results have none,
+	// as the server never has their key
+	Attestation *attestation `json:"attestation,omitempty"`
+}
+
+// job is a search submitted to the server. its fields after mu are guarded by it.
+type job struct {
+	id      string
+	tenant  string
+	req     jobRequest
+	pub     *ecdsa.PublicKey // nil unless the job is split-key
+	search  *vanity.Searcher
+	created time.Time
+	done    chan struct{} // closed when the job finishes
+
+	mu       sync.Mutex
+	status   string
+	started  time.Time
+	finished time.Time
+	cancel   context.CancelFunc // set while running
+	key      []byte             // derived from the submitter's token; held only until the result is sealed
+	result   *jobResult         // the address only
+	sealed   []byte             // the whole result, sealed with key
+	err      string
+}
+
+// jobStatus is the body of a GET /jobs/{id} response. rates are in keys per second and times in seconds.
+type jobStatus struct {
+	ID               string     `json:"id"`
+	Status           string     `json:"status"`
+	Prefix           string     `json:"prefix,omitempty"`
+	Suffix           string     `json:"suffix,omitempty"`
+	CaseInsensitive  bool       `json:"case_insensitive"`
+	Backend          string     `json:"backend"`
+	SplitKey         bool       `json:"split_key"`
+	Created          time.Time  `json:"created"`
+	Started          *time.Time `json:"started,omitempty"`
+	Finished         *time.Time `json:"finished,omitempty"`
+	Attempts         uint64     `json:"attempts"`
+	Invalid          uint64     `json:"invalid,omitempty"` // candidates skipped as invalid
+	Rate             float64    `json:"rate"`
+	ExpectedAttempts float64    `json:"expected_attempts"`
+	ETASeconds       *float64   `json:"eta_seconds,omitempty"`
+	Best             *bestEvent `json:"best,omitempty"`
+	Result           *jobResult `json:"result,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// snapshot returns the job's current status, with no more of its result than the address.
+func (j *job) snapshot() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := jobStatus{
+		ID:               j.id,
+		Status:           j.status,
+		Prefix:           j.req.Prefix,
+		Suffix:           j.req.Suffix,
+		CaseInsensitive:  j.req.CaseInsensitive,
+		Backend:          string(j.search.Backend),
+		SplitKey:         j.pub != nil,
+		Created:          j.created,
+		Attempts:         j.search.Attempts(),
+		Invalid:          j.search.Invalid(),
+		ExpectedAttempts: math.Round(j.search.Difficulty()),
+		Error:            j.err,
+	}
+	if j.result != nil {
+		s.Result = &jobResult{Address: j.result.Address, Downloaded: j.sealed == nil}
+	}
+	if j.pub != nil {
+		s.Backend = "split"
+	}
+	if !j.started.IsZero() {
+		started := j.started
+		s.Started = &started
+		end := time.Now()
+		if !j.finished.IsZero() {
+			finished := j.finished
+			s.Finished, end = &finished, finished
+		}
+		if elapsed := end.Sub(started).Seconds(); elapsed > 0 {
+			s.Rate = float64(s.Attempts) / elapsed
+		}
+		if j.status == jobRunning && s.Rate > 0 {
+			eta := s.ExpectedAttempts / s.Rate
+			s.ETASeconds = &eta
+		}
+	}
+	if j.status == jobRunning {
+		if res, score := j.search.Best(); score > 0 {
+			s.Best = &bestEvent{res.Address.Hex(), score, len(j.req.Prefix) + len(j.req.Suffix)}
+		}
+	}
+	return s
+}
+
+// reveal fills in the secret part of st's result, which it must have been given by snapshot, for c. it
+// fails if c did not submit the job, and forgets the result if it was to be fetched only once.
+func (j *job) reveal(st *jobStatus, c caller) error {
+	if st.Result == nil || st.Result.Downloaded {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.sealed == nil { // fetched since the snapshot
+		st.Result.Downloaded = true
+		return nil
+	}
+	key, err := resultKey(c.token, j.id)
+	if err != nil {
+		return err
+	}
+	plain, err := unseal(key, j.sealed)
+	if err != nil {
+		return httpError{http.StatusForbidden, err}
+	}
+	if err = json.Unmarshal(plain, st.Result); err != nil {
+		return err
+	}
+	if j.req.OneTime {
+		j.sealed = nil
+		slog.Info("one-time result fetched", "id", j.id, "tenant", j.tenant)
+	}
+	return nil
+}
+
+// view returns the job's status for c, including the result if c may see it.
+func (j *job) view(c caller) (jobStatus, error) {
+	st := j.snapshot()
+	return st, j.reveal(&st, c)
+}
+
+// server runs the jobs submitted to it, at most concurrent at a time, splitting its workers among them.
+type server struct {
+	workers    int // per running job
+	maxLength  int
+	tokens     map[[32]byte]string // tenants by the SHA-256 of their tokens; empty if anyone may submit
+	wsOrigin   string              // allowed to open WebSockets, besides the server's own origin; * allows any
+	queue      chan *job
+	mu         sync.Mutex
+	jobs       map[string]*job
+	order      []string // job ids in submission order
+	maxHistory int      // finished jobs kept for GET
+	limits     limits
+	usage      map[string]time.Duration // worker time of each tenant's finished jobs
+	attempts   uint64                   // over all finished jobs, for the key rate
+	worked     time.Duration
+	cal        *calibration // for the key rate until a job has finished
+	audit      *auditLog    // nil without -audit-log
+	draining   atomic.Bool  // no longer accepting or starting jobs
+	stopping   atomic.Bool  // running jobs are being stopped, to be saved and run again after a restart
+}
+
+// httpError is an error with the HTTP status to report it with.
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e httpError) Error() string { return e.err.Error() }
+
+// newJob validates req and builds the job for it, owned by c.
+func (s *server) newJob(req jobRequest, c caller) (*job, error) {
+	bad := func(err error) error { return httpError{http.StatusBadRequest, err} }
+	err := vanity.ValidatePattern(req.Prefix + req.Suffix)
+	if err != nil {
+		return nil, bad(err)
+	}
+	if len(req.Prefix)+len(req.Suffix) > s.maxLength {
+		return nil, bad(fmt.Errorf("this server accepts patterns of at most %d characters", s.maxLength))
+	}
+	if req.TimeoutSeconds < 0 {
+		return nil, bad(errors.New("timeout_seconds must not be negative"))
+	}
+	if req.Backend == "" {
+		req.Backend = string(vanity.Geth)
+	}
+	opts := []vanity.Option{
+		vanity.WithPrefix(req.Prefix),
+		vanity.WithSuffix(req.Suffix),
+		vanity.WithCaseInsensitive(req.CaseInsensitive),
+		vanity.WithWorkers(s.workers),
+		vanity.WithTrackBest(true),
+		vanity.WithMaxAttempts(req.MaxAttempts),
+	}
+	j := &job{tenant: c.tenant, req: req, created: time.Now(), done: make(chan struct{}), status: jobQueued}
+	if req.PublicKey != "" {
+		if j.pub, err = parsePublicKey(req.PublicKey); err != nil {
+			return nil, bad(fmt.Errorf("invalid public_key: %w", err))
+		}
+		src, err := vanity.SplitKeySource(j.pub)
+		if err != nil {
+			return nil, bad(err)
+		}
+		opts = append(opts, vanity.WithKeySource(src))
+	} else {
+		opts = append(opts, vanity.WithBackend(vanity.Backend(req.Backend)))
+	}
+	if j.search, err = vanity.New(opts...); err != nil {
+		return nil, bad(err)
+	}
+	if req.MaxAttempts == 0 && req.TimeoutSeconds <= 0 {
+		// as generate asks for -l or -t
+		backend := vanity.Backend(req.Backend)
+		if j.pub != nil {
+			backend = vanity.Walk // split-key searches walk from the offset
+		}
+		err = expectLong(j.search.Difficulty(), backend, s.workers, req.CaseInsensitive)
+		if errors.Is(err, vanity.ErrTooLong) {
+			return nil, bad(fmt.Errorf("%w; set max_attempts or timeout_seconds if you wish to continue", err))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	var id [8]byte
+	if _, err = rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	j.id = hex.EncodeToString(id[:])
+	if j.key, err = resultKey(c.token, j.id); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// run runs the jobs in the queue one at a time, in the context jobs, until ctx is done. a job taken
+// from the queue as ctx is done is left queued.
+func (s *server) run(ctx, jobs context.Context) {
+	for {
+		select {
+		case j := <-s.queue:
+			if ctx.Err() != nil {
+				return
+			}
+			s.runJob(jobs, j)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *server) runJob(parent context.Context, j *job) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	timeout := time.Duration(j.req.TimeoutSeconds * float64(time.Second))
+	left, quota := s.cpuLeft(j.tenant)
+	if quota && timeout > 0 && timeout < left/time.Duration(s.workers) {
+		quota = false // the job's own timeout comes first
+	}
+	if quota {
+		timeout = max(left/time.Duration(s.workers), 0)
+	}
+	if timeout > 0 || quota {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	defer s.record(j) // after the unlock below
+	j.mu.Lock()
+	if j.status != jobQueued { // canceled while it waited
+		j.mu.Unlock()
+		return
+	}
+	j.status, j.started, j.cancel = jobRunning, time.Now(), cancel
+	j.mu.Unlock()
+	slog.Info("job started", "id", j.id, "prefix", j.req.Prefix, "suffix", j.req.Suffix)
+	s.audit.start(j.id, j.auditStart())
+
+	res, err := j.search.Run(ctx)
+	var (
+		result *jobResult
+		sealed []byte
+	)
+	if err == nil {
+		result, err = j.resultFor(res)
+	}
+	if err == nil {
+		sealed, err = j.seal(result)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished, j.cancel = time.Now(), nil
+	if err != nil && s.stopping.Load() && j.status == jobRunning && errors.Is(err, context.Canceled) {
+		j.status = jobQueued // keeping its key, to seal the result with when it runs again
+		slog.Info("job stopped for the restart", "id", j.id, "attempts", j.search.Attempts())
+		return
+	}
+	j.key = nil
+	close(j.done)
+	switch {
+	case err == nil:
+		j.status, j.result, j.sealed = jobDone, &jobResult{Address: result.Address}, sealed
+	case j.status == jobCanceled:
+	case errors.Is(err, context.DeadlineExceeded) && quota:
+		j.status, j.err = jobFailed, "CPU time quota used up"
+	case errors.Is(err, context.DeadlineExceeded):
+		j.status, j.err = jobFailed, "timed out"
+	default:
+		j.status, j.err = jobFailed, err.Error()
+	}
+	slog.Info("job finished", "id", j.id, "status", j.status, "attempts", j.search.Attempts(), "elapsed", j.finished.Sub(j.started).Round(time.Millisecond))
+	var found int
+	if j.result != nil {
+		found = 1
+		s.audit.found(j.id, j.result.Address, "", "", j.search.Attempts())
+	}
+	s.audit.end(j.id, j.status, j.err, found, j.search.Attempts(), j.search.Invalid(), j.finished.Sub(j.started))
+}
+
+// auditStart describes the job for the audit log, with its options in place of flags.
+func (j *job) auditStart() auditStart {
+	a := auditStart{
+		Command:       "serve",
+		Tenant:        j.tenant,
+		Prefix:        j.req.Prefix,
+		Suffix:        j.req.Suffix,
+		CaseSensitive: !j.req.CaseInsensitive,
+		Chain:         string(vanity.Ethereum),
+		Backend:       j.req.Backend,
+		Flags:         make(map[string]string),
+	}
+	if j.pub != nil {
+		a.Backend = "split"
+		a.Flags["public_key"] = j.req.PublicKey
+	}
+	if j.req.MaxAttempts > 0 {
+		a.Flags["max_attempts"] = strconv.FormatUint(j.req.MaxAttempts, 10)
+	}
+	if j.req.TimeoutSeconds > 0 {
+		a.Flags["timeout_seconds"] = strconv.FormatFloat(j.req.TimeoutSeconds, 'g', -1, 64)
+	}
+	if j.req.OneTime {
+		a.Flags["one_time"] = "true"
+	}
+	return a
+}
+
+// resultFor converts res to the form the job returns it in, checking split-key results against the base
+// public key so that a bad offset is never handed out.
+func (j *job) resultFor(res vanity.Result) (*jobResult, error) {
+	key := hex.EncodeToString(crypto.FromECDSA(res.Key))
+	if j.pub == nil {
+		att, err := attest(res.Key, claim{
+			Address:       res.Address.Hex(),
+			Prefix:        j.req.Prefix,
+			Suffix:        j.req.Suffix,
+			CaseSensitive: !j.req.CaseInsensitive,
+			Chain:         string(vanity.Ethereum),
+			Timestamp:     time.Now().UTC(),
+			Version:       version,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &jobResult{Address: res.Address.Hex(), PrivateKey: key, Attestation: att}, nil
+	}
+	addr, err := vanity.SplitKeyAddress(j.pub, crypto.FromECDSA(res.Key))
+	if err != nil {
+		return nil, err
+	}
+	if addr != res.Address {
+		return nil, fmt.Errorf("internal error: offset gives %s, not %s", addr, res.Address)
+	}
+	return &jobResult{Address: res.Address.Hex(), Offset: key}, nil
+}
+
+// seal encrypts result with the job's key, so that the server keeps no key it could read without the
+// submitter's token.
+func (j *job) seal(result *jobResult) ([]byte, error) {
+	plain, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plain)
+	return seal(j.key, plain)
+}
+
+// add registers j and queues it, forgetting the oldest finished jobs beyond maxHistory.
+func (s *server) add(j *job) error {
+	if s.draining.Load() {
+		return httpError{http.StatusServiceUnavailable, errors.New("the server is shutting down")}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkQuota(j); err != nil {
+		return err
+	}
+	select {
+	case s.queue <- j:
+	default:
+		return httpError{http.StatusServiceUnavailable, errors.New("the job queue is full")}
+	}
+	s.jobs[j.id] = j
+	s.order = append(s.order, j.id)
+
+	var finished int
+	for i := len(s.order) - 1; i >= 0; i-- {
+		old := s.jobs[s.order[i]]
+		old.mu.Lock()
+		done := old.status != jobQueued && old.status != jobRunning
+		old.mu.Unlock()
+		if done {
+			if finished++; finished > s.maxHistory {
+				delete(s.jobs, s.order[i])
+				s.order = append(s.order[:i], s.order[i+1:]...)
+			}
+		}
+	}
+	return nil
+}
+
+// get returns the job id if it belongs to tenant. other tenants' jobs are reported not to exist.
+func (s *server) get(id, tenant string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok || j.tenant != tenant {
+		return nil, httpError{http.StatusNotFound, fmt.Errorf("no job %q", id)}
+	}
+	return j, nil
+}
+
+// list returns the status of every job belonging to tenant, without their results.
+func (s *server) list(tenant string) []jobStatus {
+	s.mu.Lock()
+	var jobs []*job
+	for _, id := range s.order {
+		if j := s.jobs[id]; j.tenant == tenant {
+			jobs = append(jobs, j)
+		}
+	}
+	s.mu.Unlock()
+	list := make([]jobStatus, len(jobs))
+	for i, j := range jobs {
+		list[i] = j.snapshot()
+		list[i].Result = nil // fetched one job at a time, so that keys aren't sprayed around
+	}
+	return list
+}
+
+// cancel cancels tenant's job id if it has not finished.
+func (s *server) cancel(id, tenant string) (*job, error) {
+	j, err := s.get(id, tenant)
+	if err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.status {
+	case jobQueued:
+		j.status, j.finished, j.key = jobCanceled, time.Now(), nil
+		close(j.done)
+	case jobRunning:
+		j.status = jobCanceled
+		j.cancel()
+	default:
+		return nil, httpError{http.StatusConflict, fmt.Errorf("job %s has already finished", j.id)}
+	}
+	slog.Info("job canceled", "id", j.id)
+	return j, nil
+}
+
+// handler returns the REST API, with the web interface at / if ui is set.
+func (s *server) handler(ui bool) http.Handler {
+	mux := http.NewServeMux()
+	if ui {
+		mux.Handle("GET /", uiHandler())
+	}
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	mux.HandleFunc("POST /jobs", s.handle(func(r *http.Request, c caller) (int, any, error) {
+		var req jobRequest
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<16))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			return 0, nil, httpError{http.StatusBadRequest, err}
+		}
+		j, err := s.newJob(req, c)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err = s.add(j); err != nil {
+			return 0, nil, err
+		}
+		slog.Info("job queued", "id", j.id, "tenant", j.tenant, "remote", r.RemoteAddr)
+		return http.StatusCreated, j.snapshot(), nil
+	}))
+	mux.HandleFunc("GET /jobs", s.handle(func(r *http.Request, c caller) (int, any, error) {
+		return http.StatusOK, s.list(c.tenant), nil
+	}))
+	mux.HandleFunc("GET /jobs/{id}", s.handle(func(r *http.Request, c caller) (int, any, error) {
+		j, err := s.get(r.PathValue("id"), c.tenant)
+		if err != nil {
+			return 0, nil, err
+		}
+		st, err := j.view(c)
+		return http.StatusOK, st, err
+	}))
+	mux.HandleFunc("GET /jobs/{id}/events", s.watch)
+	mux.HandleFunc("POST /jobs/{id}/keystore", s.handle(s.keystore))
+	mux.HandleFunc("DELETE /jobs/{id}", s.handle(func(r *http.Request, c caller) (int, any, error) {
+		j, err := s.cancel(r.PathValue("id"), c.tenant)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, j.snapshot(), nil
+	}))
+	return mux
+}
+
+// handle adapts f to an http.HandlerFunc that authenticates the caller and writes f's result or error as
+// JSON.
+func (s *server) handle(f func(*http.Request, caller) (int, any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			code int
+			v    any
+			err  error = httpError{http.StatusUnauthorized, errors.New("missing or invalid bearer token")}
+		)
+		if c, ok := s.authenticate(r.Header.Get("Authorization")); ok {
+			code, v, err = f(r, c)
+		}
+		if st, ok := v.(jobStatus); ok && code == http.StatusCreated {
+			w.Header().Set("Location", "/jobs/"+st.ID)
+		}
+		writeJSON(w, code, v, err)
+	}
+}
+
+// writeJSON writes v as the body of a response with status code, or, if err is not nil, err's message
+// with the status it calls for.
+func writeJSON(w http.ResponseWriter, code int, v any, err error) {
+	if err != nil {
+		code = http.StatusInternalServerError
+		var (
+			he httpError
+			qe quotaError
+		)
+		switch {
+		case errors.As(err, &he):
+			code = he.code
+		case errors.As(err, &qe):
+			code = qe.code
+		}
+		v = struct {
+			Error               string  `json:"error"`
+			ExpectedAttempts    float64 `json:"expected_attempts,omitempty"`
+			EstimatedCPUSeconds float64 `json:"estimated_cpu_seconds,omitempty"`
+		}{err.Error(), math.Round(qe.expected), qe.cpuSeconds}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// serve implements the serve subcommand, which runs searches submitted over HTTP.
+func serve(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		addr       *string        = fs.String("addr", "127.0.0.1:8080", "address to serve the REST API on")
+		grpcAddr   *string        = fs.String("grpc-addr", "", "also serve the gRPC API in pkg/vanitypb on this address")
+		workers    *int           = fs.Int("workers", runtime.GOMAXPROCS(0), "total number of worker goroutines, shared among the running jobs")
+		concurrent *int           = fs.Int("concurrent", 1, "number of jobs to run at once; the rest wait in the queue")
+		queueLen   *int           = fs.Int("queue", 100, "maximum number of jobs waiting to run")
+		history    *int           = fs.Int("history", 1000, "number of finished jobs to remember")
+		maxLength  *int           = fs.Int("max-length", 10, "longest pattern the server accepts, in characters")
+		drain      *time.Duration = fs.Duration("drain-timeout", 30*time.Second, "on SIGTERM, how long to let running jobs finish before stopping them")
+		statePath  *string        = fs.String("state", "", "file to save the jobs in when the server stops, and to restore them from when it starts; it holds the keys that unfinished jobs' results will be encrypted with")
+		ui         *bool          = fs.Bool("ui", true, "serve a web interface for submitting and watching jobs at /")
+		wsOrigin   *string        = fs.String("ws-origin", "", "origin, such as https://example.com, of a web frontend allowed to open /jobs/{id}/events; * allows any")
+		tokenFile  *string        = fs.String("token-file", "", "file containing a token that requests must present as 'Authorization: Bearer <token>' (defaults to $VANITY_TOKEN)")
+		tokensFile *string        = fs.String("tokens", "", "file of 'tenant token' lines, for a server shared by several tenants who may not see each other's jobs")
+		tenantJobs *int           = fs.Int("tenant-jobs", 0, "most jobs each tenant may have queued or running at once; 0 means no limit")
+		tenantCPU  *time.Duration = fs.Duration("tenant-cpu", 0, "total CPU time, counted as worker time, each tenant's jobs may use; 0 means no limit")
+		maxDiff    *float64       = fs.Float64("max-difficulty", 0, "most expected attempts a job may need; 0 means no limit beyond -max-length")
+		profile    *string        = fs.String("profile", defaultCalibrationPath(), "calibration profile saved by 'bench -save', for estimating the CPU time of rejected jobs before any have run")
+		auditPath  *string        = fs.String("audit-log", "", "append a JSON line to this file when each job starts, finds its key and ends, as an audit trail of what was generated; no key material is written")
+		mlock      *bool          = fs.Bool("mlock", false, "lock the process's memory so that keys are never written to swap (Linux only); the server runs with a warning if it cannot")
+		logOpts                   = addLogFlags(fs)
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s serve [flags]\n\nruns searches submitted over HTTP:\n\n"+
+			"  GET /              the web interface, unless -ui=false\n"+
+			"  POST /jobs         submit a job: {\"prefix\", \"suffix\", \"case_insensitive\", \"backend\",\n"+
+			"                     \"max_attempts\", \"timeout_seconds\", \"public_key\", \"one_time\"}\n"+
+			"  GET /jobs          list the jobs\n"+
+			"  GET /jobs/{id}     get a job's progress and, once it is done, its result\n"+
+			"  GET /jobs/{id}/events\n"+
+			"                     a WebSocket that receives the job's status every ?interval (default 1s)\n"+
+			"                     until it finishes, and then its final status with the result\n"+
+			"  POST /jobs/{id}/keystore\n"+
+			"                     get a job's key as an EIP-2335 keystore: {\"passphrase\", \"kdf\"}\n"+
+			"  DELETE /jobs/{id}  cancel a job\n"+
+			"  GET /healthz       whether the server is up\n"+
+			"  GET /readyz        whether it is accepting jobs\n\n"+
+			"jobs with a public_key are split-key searches: their result is an offset that only the holder of\n"+
+			"the base key can turn into a private key, with 'vanity splitkey'. with -grpc-addr, the same jobs\n"+
+			"are also served over gRPC; see pkg/vanitypb/vanity.proto.\n\n"+
+			"with -tokens, each tenant sees only its own jobs. results are kept encrypted with a key derived from\n"+
+			"the token that submitted the job, which the server does not store, so only that token can fetch\n"+
+			"them; a one_time result is forgotten once it has been fetched, by GET or at the end of a watch.\n"+
+			"-tenant-jobs, -tenant-cpu and -max-difficulty hold each tenant to quotas; jobs over them are\n"+
+			"rejected with their expected_attempts and, if the server knows its key rate, estimated_cpu_seconds.\n\n"+
+			"on SIGINT or SIGTERM, the server stops accepting jobs and fails /readyz, lets running jobs finish for\n"+
+			"up to -drain-timeout, and then stops them. with -state, stopped and queued jobs are saved, along\n"+
+			"with finished ones, and resumed when the server next starts.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if err := logOpts.setup(os.Stderr, false); err != nil {
+		fatal(usageError{err})
+	}
+	defer logOpts.close()
+	if *concurrent < 1 || *workers < *concurrent {
+		fatal(usageError{errors.New("-concurrent must be at least 1 and at most -workers")})
+	}
+	if *queueLen < 1 || *history < 0 {
+		fatal(usageError{errors.New("-queue must be at least 1 and -history at least 0")})
+	}
+	if *tenantJobs < 0 || *tenantCPU < 0 || *maxDiff < 0 {
+		fatal(usageError{errors.New("-tenant-jobs, -tenant-cpu and -max-difficulty must not be negative")})
+	}
+	if err := checkEntropy(); err != nil {
+		fatal(err)
+	}
+	if *mlock {
+		if err := lockMemory(); err != nil {
+			slog.Warn("could not lock memory; keys may be written to swap", "err", err)
+		}
+	}
+	var cal *calibration
+	if *profile != "" {
+		var err error
+		if cal, err = loadCalibration(*profile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fatal(err)
+		}
+	}
+	tokens := make(map[[32]byte]string)
+	switch token := os.Getenv("VANITY_TOKEN"); {
+	case *tokensFile != "" && *tokenFile != "":
+		fatal(usageError{errors.New("-token-file and -tokens cannot be used together")})
+	case *tokensFile != "":
+		var err error
+		if tokens, err = loadTokens(*tokensFile); err != nil {
+			fatal(err)
+		}
+	case *tokenFile != "":
+		pass, err := readPassphrase(*tokenFile)
+		if err != nil {
+			fatal(err)
+		}
+		token = string(pass)
+		fallthrough
+	case token != "":
+		tokens[sha256.Sum256([]byte(token))] = "default"
+	default:
+		slog.Warn("no -token-file or -tokens given; anyone who can reach the server can submit jobs and fetch their keys", "addr", *addr)
+	}
+
+	s := &server{
+		workers:    *workers / *concurrent,
+		maxLength:  *maxLength,
+		tokens:     tokens,
+		wsOrigin:   *wsOrigin,
+		queue:      make(chan *job, *queueLen),
+		jobs:       make(map[string]*job),
+		maxHistory: *history,
+		limits:     limits{jobs: *tenantJobs, cpu: *tenantCPU, maxDifficulty: *maxDiff},
+		usage:      make(map[string]time.Duration),
+		cal:        cal,
+	}
+	if *auditPath != "" {
+		var err error
+		if s.audit, err = openAuditLog(*auditPath); err != nil {
+			fatal(err)
+		}
+		defer s.audit.close()
+	}
+	if *statePath != "" {
+		if err := s.restore(*statePath); err != nil {
+			fatal(err)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var running sync.WaitGroup
+	for i := 0; i < *concurrent; i++ {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			s.run(ctx, jobs)
+		}()
+	}
+	if *grpcAddr != "" {
+		l, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fatal(err)
+		}
+		g := newGRPCServer(s)
+		go g.Serve(l)
+		defer g.Stop()
+		slog.Info("serving gRPC", "addr", *grpcAddr)
+	}
+	srv := &http.Server{Addr: *addr, Handler: s.handler(*ui), ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	slog.Info("serving", "addr", *addr, "workers", *workers, "concurrent", *concurrent)
+	select {
+	case err := <-serveErr:
+		fatal(err)
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the server at once
+
+	// keep serving, so that clients can still follow and fetch their jobs, until they are done
+	s.draining.Store(true)
+	slog.Info("draining", "timeout", *drain)
+	idle := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(idle)
+	}()
+	select {
+	case <-idle:
+	case <-time.After(*drain):
+		s.stopping.Store(true)
+		stopJobs()
+		<-idle
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdown)
+
+	if *statePath == "" {
+		if n := s.unfinished(); n > 0 {
+			slog.Warn("jobs lost; use -state to keep them across restarts", "jobs", n)
+		}
+		return exitOK
+	}
+	if err := s.save(*statePath); err != nil {
+		fatal(err)
+	}
+	slog.Info("saved jobs", "path", *statePath, "unfinished", s.unfinished())
+	return exitOK
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// splitkey implements the splitkey subcommand, which handles the owner's side of a split-key search:
+// making the base key whose public key is handed to the searcher, and combining it with the offset the
+// searcher hands back.
+func splitkey(args []string) int {
+	fs := flag.NewFlagSet("splitkey", flag.ExitOnError)
+	var (
+		keyPath *string = fs.String("k", "base.key", "path of the base key file")
+		newKey  *bool   = fs.Bool("new", false, "generate a new base key and write it to -k")
+		offset  *string = fs.String("offset", "", "offset found by a split-key search, in hex; combined with the base key and written to -o")
+		out     *string = fs.String("o", "priv.key", "output path of the combined key")
+		expect  *string = fs.String("expect", "", "address the combined key must have")
+		inPass  *string = fs.String("in-pass", "", "file containing the passphrase of an encrypted base key (defaults to $VANITY_PASSPHRASE)")
+		attPath *string = fs.String("attest", "", "also write an attestation, signed by the combined key, that its holder has it to this path; see 'verify -attestation'")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s splitkey [flags]\n\nprints the public key of a base key for a split-key search, or combines the base key with\nthe offset the search found. the searcher never learns the combined key.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *newKey && *offset != "" {
+		fatal(usageError{errors.New("-new and -offset cannot be used together")})
+	}
+
+	if *newKey {
+		if _, err := os.Stat(*keyPath); err == nil {
+			fatal(fmt.Errorf("%s already exists", *keyPath)) // it may be the only copy of someone's base key
+		}
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			fatal(err)
+		}
+		if err = saveHex(*keyPath, key); err != nil {
+			fatal(err)
+		}
+		fmt.Println(hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey)))
+		return exitOK
+	}
+
+	data, err := os.ReadFile(*keyPath)
+	if err != nil {
+		fatal(err)
+	}
+	base, err := loadKey(data, *inPass, "")
+	if err != nil {
+		fatal(err)
+	}
+	if *offset == "" {
+		fmt.Println(hex.EncodeToString(crypto.FromECDSAPub(&base.PublicKey)))
+		return exitOK
+	}
+	off, err := hex.DecodeString(strings.TrimPrefix(*offset, "0x"))
+	if err != nil {
+		fatal(usageError{fmt.Errorf("invalid -offset: %w", err)})
+	}
+	key, err := vanity.CombineSplitKey(base, off)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	if *expect != "" && common.HexToAddress(*expect) != addr {
+		fatal(fmt.Errorf("the combined key's address is %s, not %s", addr, *expect))
+	}
+	if err = saveHex(*out, key); err != nil {
+		fatal(err)
+	}
+	if *attPath != "" {
+		// the search's own pattern is not known here, so only the address is attested
+		att, err := attest(key, claim{Address: addr.Hex(), Chain: string(vanity.Ethereum), Timestamp: time.Now().UTC(), Version: version})
+		if err != nil {
+			fatal(err)
+		}
+		b, err := json.MarshalIndent(att, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err = os.WriteFile(*attPath, append(b, '\n'), 0644); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Println(addr)
+	return exitOK
+}
+
+// parsePublicKey decodes a secp256k1 public key given in hex, compressed or not.
+func parsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 33 {
+		return crypto.DecompressPubkey(b)
+	}
+	return crypto.UnmarshalPubkey(b)
+}
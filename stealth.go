@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// an ERC-5564 stealth meta-address publishes two public keys: a spending key, which controls the funds
+// sent to the stealth addresses derived from it, and a viewing key, which lets its holder find them. for
+// secp256k1 it is st:eth:0x followed by both keys, compressed, so the start of its hex is the spending
+// key and the end is the viewing key, and a prefix and a suffix are searched for separately.
+// see https://eips.ethereum.org/EIPS/eip-5564
+const stealthMetaPrefix = "st:eth:0x"
+
+// stealthKeys is what the stealth subcommand writes.
+type stealthKeys struct {
+	MetaAddress string `json:"meta_address"`
+	SpendingKey string `json:"spending_key"`
+	ViewingKey  string `json:"viewing_key"`
+}
+
+// stealthMetaAddress returns the stealth meta-address of the spending and viewing public keys.
+func stealthMetaAddress(spend, view *ecdsa.PublicKey) string {
+	return stealthMetaPrefix + hex.EncodeToString(crypto.CompressPubkey(spend)) + hex.EncodeToString(crypto.CompressPubkey(view))
+}
+
+// parseStealthMetaAddress returns the spending and viewing public keys of the stealth meta-address s.
+func parseStealthMetaAddress(s string) (spend, view *ecdsa.PublicKey, err error) {
+	if !strings.HasPrefix(s, stealthMetaPrefix) {
+		return nil, nil, fmt.Errorf("%q does not begin with %s", s, stealthMetaPrefix)
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(s, stealthMetaPrefix))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) != 66 {
+		return nil, nil, fmt.Errorf("a stealth meta-address holds 66 bytes, not %d", len(b))
+	}
+	if spend, err = crypto.DecompressPubkey(b[:33]); err != nil {
+		return nil, nil, fmt.Errorf("spending key: %w", err)
+	}
+	if view, err = crypto.DecompressPubkey(b[33:]); err != nil {
+		return nil, nil, fmt.Errorf("viewing key: %w", err)
+	}
+	return spend, view, nil
+}
+
+// checkStealthKeys checks that the meta-address in k encodes the public keys of its private keys.
+func checkStealthKeys(k *stealthKeys) error {
+	spend, view, err := parseStealthMetaAddress(k.MetaAddress)
+	if err != nil {
+		return err
+	}
+	for _, c := range []struct {
+		name string
+		hex  string
+		pub  *ecdsa.PublicKey
+	}{{"spending", k.SpendingKey, spend}, {"viewing", k.ViewingKey, view}} {
+		key, err := crypto.HexToECDSA(c.hex)
+		if err != nil {
+			return fmt.Errorf("%s key: %w", c.name, err)
+		}
+		ok := key.PublicKey.Equal(c.pub)
+		vanity.ZeroKey(key)
+		if !ok {
+			return fmt.Errorf("the meta-address does not hold the public key of the %s key", c.name)
+		}
+	}
+	return nil
+}
+
+// checkStealthPattern checks that prefix and suffix are lower-case hex that a meta-address can begin and
+// end with.
+func checkStealthPattern(prefix, suffix string) error {
+	for _, p := range []string{prefix, suffix} {
+		if len(p) > 66 {
+			return fmt.Errorf("%w: %q is longer than a compressed public key", vanity.ErrInvalid, p)
+		}
+		if strings.Trim(p, "0123456789abcdef") != "" {
+			return fmt.Errorf("%w: %q is not lower-case hex", vanity.ErrInvalid, p)
+		}
+	}
+	// the spending key begins with 02 or 03, for the parity of its y coordinate
+	if !strings.HasPrefix("02", prefix[:min(len(prefix), 2)]) && !strings.HasPrefix("03", prefix[:min(len(prefix), 2)]) {
+		return usageError{errors.New("meta-addresses begin with st:eth:0x02 or st:eth:0x03, so -p must begin with 02 or 03")}
+	}
+	return nil
+}
+
+// grindCompressed generates keys with workers goroutines until one's compressed public key, in hex,
+// satisfies match, and returns it. it adds the keys it tries to attempts. if a worker cannot generate
+// a key, the others are stopped and the error is returned.
+func grindCompressed(ctx context.Context, workers int, attempts *atomic.Uint64, match func(pubHex []byte) bool) (*ecdsa.PrivateKey, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		once   sync.Once
+		found  *ecdsa.PrivateKey
+		failed error // the first worker's that could not generate a key
+		wg     sync.WaitGroup
+	)
+	fail := func(err error) {
+		once.Do(func() { failed = err })
+		cancel()
+	}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			src, err := vanity.NewKeySource(vanity.Geth, 0) // every key independent, as both are kept
+			if err != nil {
+				fail(err)
+				return
+			}
+			pubHex := make([]byte, 66)
+			for ctx.Err() == nil {
+				key, err := src.Next()
+				attempts.Add(1)
+				if err != nil {
+					fail(err)
+					return
+				}
+				hex.Encode(pubHex, crypto.CompressPubkey(&key.PublicKey))
+				if !match(pubHex) {
+					vanity.ZeroKey(key)
+					continue
+				}
+				once.Do(func() { found = key })
+				if found != key {
+					vanity.ZeroKey(key) // another worker's was first
+				}
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	if failed != nil {
+		return nil, failed
+	}
+	if found == nil {
+		return nil, ctx.Err()
+	}
+	return found, nil
+}
+
+// stealth implements the stealth subcommand, which searches for an ERC-5564 stealth meta-address that
+// begins and ends with a pattern.
+func stealth(args []string) int {
+	fs := flag.NewFlagSet("stealth", flag.ExitOnError)
+	var (
+		prefix     *string = fs.String("p", "", "hex the meta-address begins with after st:eth:0x, which is always 02 or 03 and then the spending key")
+		suffix     *string = fs.String("s", "", "hex the meta-address ends with, the end of the viewing key")
+		out        *string = fs.String("o", "stealth.json", "output path of the spending and viewing keys, as JSON")
+		numWorkers *int    = fs.Int("workers", runtime.GOMAXPROCS(0), "number of worker goroutines")
+		riskyOut   *bool   = fs.Bool("risky-output", false, "write the keys to a terminal, a directory every user can write to, or a network or cloud-synced folder without asking first")
+	)
+	var timeOut timeoutFlag
+	fs.Var(&timeOut, "t", "maximum acceptable search time, as a duration (90m, 2h30m) or a number of seconds")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s stealth [flags]\n\nsearches for an ERC-5564 stealth meta-address, st:eth:0x followed by a spending and a viewing\npublic key, that begins with -p and ends with -s. the prefix is searched for in the spending key and\nthe suffix in the viewing key, so the two cost no more than either alone.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	*prefix, *suffix = strings.ToLower(*prefix), strings.ToLower(*suffix)
+	if err := checkStealthPattern(*prefix, *suffix); err != nil {
+		fatal(err)
+	}
+	if *numWorkers < 1 {
+		fatal(usageError{errors.New("-workers must be at least 1")})
+	}
+	if err := confirmDestination(*out, *riskyOut, true); err != nil {
+		fatal(usageError{err})
+	}
+	if _, err := os.Stat(*out); err == nil {
+		fatal(fmt.Errorf("%s already exists", *out)) // it may hold someone's only copy of their keys
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeOut > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeOut))
+		defer cancel()
+	}
+	// the 02 or 03 is right half the time, and every other character a sixteenth of it
+	expected := math.Pow(16, float64(max(len(*prefix)-2, 0)))
+	if len(*prefix) >= 2 {
+		expected *= 2
+	}
+	expected += math.Pow(16, float64(len(*suffix)))
+	slog.Info("generating keys. this may take awhile...", "expected_attempts", uint64(expected))
+	start := time.Now()
+	var attempts atomic.Uint64
+	p, sfx := []byte(*prefix), []byte(*suffix)
+	spend, err := grindCompressed(ctx, *numWorkers, &attempts, func(pub []byte) bool { return bytes.HasPrefix(pub, p) })
+	if err == nil {
+		defer vanity.ZeroKey(spend)
+		var view *ecdsa.PrivateKey
+		if view, err = grindCompressed(ctx, *numWorkers, &attempts, func(pub []byte) bool { return bytes.HasSuffix(pub, sfx) }); err == nil {
+			defer vanity.ZeroKey(view)
+			err = saveStealthKeys(*out, spend, view)
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.Error("timed out", "attempts", attempts.Load())
+		return exitGaveUp
+	case errors.Is(err, context.Canceled):
+		slog.Info("interrupted", "attempts", attempts.Load())
+		return exitInterrupted
+	case err != nil:
+		fatal(err)
+	}
+	slog.Info("search finished", "attempts", attempts.Load(), "elapsed", time.Since(start).Round(10*time.Millisecond))
+	return exitOK
+}
+
+// saveStealthKeys writes the keys to path, reads them back and checks them, and prints the
+// meta-address.
+func saveStealthKeys(path string, spend, view *ecdsa.PrivateKey) error {
+	d1, d2 := crypto.FromECDSA(spend), crypto.FromECDSA(view)
+	defer clear(d1)
+	defer clear(d2)
+	k := stealthKeys{stealthMetaAddress(&spend.PublicKey, &view.PublicKey), hex.EncodeToString(d1), hex.EncodeToString(d2)}
+	if err := checkStealthKeys(&k); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	defer clear(b)
+	fmt.Println(k.MetaAddress) // first, in case the path is /dev/stdout
+	path = saveChecked(path, func(path string) error {
+		if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+			return err
+		}
+		data, err := readBack(path)
+		if data == nil || err != nil {
+			return err
+		}
+		defer clear(data)
+		var got stealthKeys
+		if err = json.Unmarshal(data, &got); err != nil {
+			return fmt.Errorf("%w: %v", errReadBack, err)
+		}
+		if got != k {
+			return fmt.Errorf("%w: the keys read back differ", errReadBack)
+		}
+		return checkStealthKeys(&got)
+	})
+	slog.Info("saved the spending and viewing keys", "path", path)
+	return nil
+}
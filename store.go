@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// storeTimeout bounds each upload or download of a remote checkpoint.
+const storeTimeout = time.Minute
+
+// storeObject is the name of the checkpoint object under a -checkpoint-store prefix.
+const storeObject = "checkpoint.enc"
+
+var errNoRemoteCheckpoint = errors.New("no checkpoint in the store")
+
+// checkpointStore keeps an encrypted copy of the checkpoint in object storage, so that a search on a
+// machine that may be taken away at any moment, such as a spot instance, can be picked up by the next.
+type checkpointStore struct {
+	object *url.URL // of the checkpoint object
+	sign   func(*http.Request, []byte)
+	pass   []byte
+}
+
+// openCheckpointStore returns the store at rawURL, which is one of
+//
+//	s3://bucket/prefix    AWS S3, or an S3-compatible service at $AWS_ENDPOINT_URL, with credentials from
+//	                      $AWS_ACCESS_KEY_ID, $AWS_SECRET_ACCESS_KEY and $AWS_SESSION_TOKEN, in $AWS_REGION
+//	gs://bucket/prefix    Google Cloud Storage, through its S3-compatible API, with an HMAC key given as for S3
+//	https://account.blob.core.windows.net/container/prefix?<SAS token>
+//	                      Azure Blob Storage, with a shared access signature allowing reads, writes and deletes
+//
+// checkpoints are encrypted with pass before they are uploaded.
+func openCheckpointStore(rawURL string, pass []byte) (*checkpointStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if key != "" && !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	key += storeObject
+	s := &checkpointStore{pass: pass}
+	switch u.Scheme {
+	case "s3", "gs":
+		region, endpoint := os.Getenv("AWS_REGION"), os.Getenv("AWS_ENDPOINT_URL")
+		if region == "" {
+			region = "us-east-1"
+		}
+		var obj string
+		switch {
+		case u.Scheme == "gs":
+			region, obj = "auto", "https://storage.googleapis.com/"+u.Host+"/"+key
+		case endpoint != "":
+			obj = strings.TrimSuffix(endpoint, "/") + "/" + u.Host + "/" + key // path-style, which every S3 clone supports
+		default:
+			obj = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", u.Host, region, key)
+		}
+		if s.object, err = url.Parse(obj); err != nil {
+			return nil, err
+		}
+		id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if id == "" || secret == "" {
+			return nil, errors.New("-checkpoint-store: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		token := os.Getenv("AWS_SESSION_TOKEN")
+		s.sign = func(req *http.Request, body []byte) {
+			if token != "" {
+				req.Header.Set("X-Amz-Security-Token", token)
+			}
+			signV4(req, body, id, secret, region, "s3", time.Now())
+		}
+	case "https":
+		if !strings.HasSuffix(u.Host, ".blob.core.windows.net") || u.RawQuery == "" {
+			return nil, fmt.Errorf("-checkpoint-store: %s is not an Azure Blob Storage URL with a SAS token", u.Redacted())
+		}
+		u.Path = "/" + key
+		s.object = u
+		s.sign = func(req *http.Request, _ []byte) {
+			req.Header.Set("X-Ms-Version", "2021-08-06")
+			if req.Method == http.MethodPut {
+				req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("-checkpoint-store: unsupported URL scheme %q; use s3://, gs:// or an Azure https:// URL", u.Scheme)
+	}
+	return s, nil
+}
+
+// String returns the object's URL without any credentials.
+func (s *checkpointStore) String() string {
+	u := *s.object
+	u.RawQuery = ""
+	return u.String()
+}
+
+// put encrypts c and uploads it, replacing the previous checkpoint.
+func (s *checkpointStore) put(c *checkpoint) error {
+	data, err := c.seal(s.pass)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodPut, data)
+	return err
+}
+
+// get downloads and decrypts the checkpoint, or returns errNoRemoteCheckpoint if there is none.
+func (s *checkpointStore) get() (*checkpoint, error) {
+	data, err := s.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	c, err := openCheckpoint(data, s.pass)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s, err)
+	}
+	return c, nil
+}
+
+// remove deletes the checkpoint, if there is one.
+func (s *checkpointStore) remove() error {
+	_, err := s.do(http.MethodDelete, nil)
+	return err
+}
+
+func (s *checkpointStore) do(method string, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, s.object.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case method == http.MethodGet && resp.StatusCode == http.StatusNotFound:
+		return nil, errNoRemoteCheckpoint
+	case method == http.MethodDelete && resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("%s %s: %s: %s", method, s, resp.Status, bytes.TrimSpace(data[:min(len(data), 512)]))
+	}
+	return data, nil
+}
+
+// signV4 signs req, whose body is body, with AWS Signature Version 4, covering every header set on it.
+// see https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func signV4(req *http.Request, body []byte, id, secret, region, service string, now time.Time) {
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+	stamp := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", stamp)
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, k := range names {
+		canonical.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signed := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	creq := strings.Join([]string{req.Method, path, req.URL.Query().Encode(), canonical.String(), signed, payload}, "\n")
+
+	scope := stamp[:8] + "/" + region + "/" + service + "/aws4_request"
+	csum := sha256.Sum256([]byte(creq))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(csum[:])
+	mac := func(key []byte, s string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(s))
+		return h.Sum(nil)
+	}
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{stamp[:8], region, service, "aws4_request"} {
+		key = mac(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%x", id, scope, signed, mac(key, toSign)))
+}
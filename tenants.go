@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+var errWrongToken = errors.New("the result can only be fetched with the token that submitted the job")
+
+// caller is whoever presented a valid token. tenant is empty on a server without tokens.
+type caller struct {
+	tenant string
+	token  string
+}
+
+// callerKey is the context key of the caller of a gRPC method.
+type callerKey struct{}
+
+func withCaller(ctx context.Context, c caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// loadTokens reads a -tokens file, in which each line names a tenant and one of its tokens, separated
+// by white space. a tenant may have several tokens; blank lines and lines beginning with # are ignored.
+// only the tokens' hashes are kept.
+func loadTokens(path string) (map[[32]byte]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tokens := make(map[[32]byte]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want a tenant name and a token", path, n)
+		}
+		h := sha256.Sum256([]byte(fields[1]))
+		if t, ok := tokens[h]; ok {
+			return nil, fmt.Errorf("%s:%d: token already belongs to %s", path, n, t)
+		}
+		tokens[h] = fields[0]
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s: no tokens", path)
+	}
+	return tokens, nil
+}
+
+// authenticate returns the caller presenting the Authorization header auth. any caller is accepted if
+// the server has no tokens.
+func (s *server) authenticate(auth string) (caller, bool) {
+	if len(s.tokens) == 0 {
+		return caller{}, true
+	}
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return caller{}, false
+	}
+	// the lookup is by hash, so its timing says nothing about the tokens
+	tenant, ok := s.tokens[sha256.Sum256([]byte(token))]
+	return caller{tenant, token}, ok
+}
+
+// resultKey derives the key that seals job id's result from the token that submitted it. the server
+// never stores tokens, so once a job is done its key can only be recovered by presenting the token again.
+func resultKey(token, id string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(token), []byte(id), []byte("vanity job result")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// seal encrypts plain with AES-256-GCM under key, prefixing the nonce.
+func seal(key, plain []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+// unseal reverses seal, returning errWrongToken if key is not the one data was sealed with.
+func unseal(key, data []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errWrongToken
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errWrongToken
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// thermalPoll is how often the CPU temperature is checked.
+const thermalPoll = 5 * time.Second
+
+// thermalThrottle holds the workers of search while the CPU is hotter than maxTemp and releases them once it has
+// cooled to coolTemp (both in degrees Celsius), until ctx is done. read errors are logged and
+// otherwise ignored, since a sensor that goes away is no reason to stop the search.
+func thermalThrottle(ctx context.Context, search *vanity.Searcher, maxTemp, coolTemp float64) {
+	defer search.Hold(holdThermal, false)
+	tick := time.NewTicker(thermalPoll)
+	defer tick.Stop()
+	hot := false
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+		t, err := cpuTemp()
+		switch {
+		case err != nil:
+			slog.Warn("could not read the CPU temperature", "err", err)
+		case !hot && t > maxTemp:
+			hot = true
+			search.Hold(holdThermal, true)
+			slog.Warn("CPU too hot; pausing the search", "celsius", t, "resume_at", coolTemp)
+		case hot && t <= coolTemp:
+			hot = false
+			search.Hold(holdThermal, false)
+			slog.Info("CPU cooled down; resuming the search", "celsius", t)
+		}
+	}
+}
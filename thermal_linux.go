@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cpuTemp returns the hottest reading, in degrees Celsius, of the sensors that belong to the CPU. it
+// looks at the hwmon drivers for Intel and AMD CPUs first and falls back to the thermal zones, which
+// on ARM boards and many laptops are the only sensors there are.
+func cpuTemp() (float64, error) {
+	var temps []float64
+	hwmons, _ := filepath.Glob("/sys/class/hwmon/hwmon*")
+	for _, dir := range hwmons {
+		switch readSysfs(filepath.Join(dir, "name")) {
+		case "coretemp", "k10temp", "zenpower", "cpu_thermal":
+			inputs, _ := filepath.Glob(filepath.Join(dir, "temp*_input"))
+			for _, p := range inputs {
+				if t, err := strconv.ParseFloat(readSysfs(p), 64); err == nil {
+					temps = append(temps, t/1000)
+				}
+			}
+		}
+	}
+	if len(temps) == 0 {
+		zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+		for _, dir := range zones {
+			typ := readSysfs(filepath.Join(dir, "type"))
+			if !strings.Contains(typ, "cpu") && !strings.Contains(typ, "pkg") && !strings.Contains(typ, "soc") && typ != "acpitz" {
+				continue
+			}
+			if t, err := strconv.ParseFloat(readSysfs(filepath.Join(dir, "temp")), 64); err == nil {
+				temps = append(temps, t/1000)
+			}
+		}
+	}
+	if len(temps) == 0 {
+		return 0, errors.New("no CPU temperature sensors found in /sys/class/hwmon or /sys/class/thermal")
+	}
+	hottest := temps[0]
+	for _, t := range temps[1:] {
+		hottest = max(hottest, t)
+	}
+	return hottest, nil
+}
+
+func readSysfs(path string) string {
+	b, _ := os.ReadFile(path)
+	return strings.TrimSpace(string(b))
+}
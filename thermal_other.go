@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// reading the temperature elsewhere needs platform APIs (the SMC on macOS, WMI on Windows) that are not
+// worth a cgo dependency.
+func cpuTemp() (float64, error) {
+	return 0, fmt.Errorf("reading the CPU temperature is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// throttlePeriod is the length of one on/off cycle of the CPU throttle. workers only check the gate
+// between batches of attempts, which take tens of milliseconds, so much shorter periods would be inexact.
+const throttlePeriod = time.Second
+
+// throttles that can hold the workers, independently of each other and of the user pausing them.
+const (
+	holdCPU uint = 1 << iota
+	holdThermal
+)
+
+// cpuThrottle holds the workers of search for part of every throttlePeriod so that the search uses about limit
+// (a fraction in (0, 1]) of the machine's total CPU time, until ctx is done. each active worker is
+// assumed to keep one CPU busy.
+func cpuThrottle(ctx context.Context, search *vanity.Searcher, limit float64) {
+	defer search.Hold(holdCPU, false)
+	for {
+		active, _ := search.State()
+		duty := limit * float64(runtime.NumCPU()) / float64(max(active, 1))
+		if duty >= 1 {
+			search.Hold(holdCPU, false)
+			select {
+			case <-time.After(throttlePeriod):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		on := time.Duration(duty * float64(throttlePeriod))
+		search.Hold(holdCPU, false)
+		select {
+		case <-time.After(on):
+		case <-ctx.Done():
+			return
+		}
+		search.Hold(holdCPU, true)
+		select {
+		case <-time.After(throttlePeriod - on):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// percentFlag is a fraction in (0, 1] given as a percentage ("50%" or "50") or a fraction ("0.5").
+type percentFlag float64
+
+func (p *percentFlag) String() string {
+	return strconv.FormatFloat(float64(*p)*100, 'f', -1, 64) + "%"
+}
+
+func (p *percentFlag) Set(s string) error {
+	pct := strings.HasSuffix(s, "%")
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", s)
+	}
+	if pct || f > 1 {
+		f /= 100
+	}
+	if f <= 0 || f > 1 {
+		return fmt.Errorf("%q is not between 0 and 100%%", s)
+	}
+	*p = percentFlag(f)
+	return nil
+}
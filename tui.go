@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// dashboard is the -tui display: a block of status lines redrawn in place at the bottom of the terminal.
+// log output and results pass through it so they scroll by above the block instead of being overwritten.
+// commands are read a line at a time, so the terminal is never switched out of its normal mode and there
+// is nothing to restore if the process exits abruptly.
+type dashboard struct {
+	mu     sync.Mutex
+	term   io.Writer
+	lines  int // height of the last frame drawn
+	closed bool
+
+	title      string
+	search     *vanity.Searcher
+	patternLen int
+	d          float64
+	start      time.Time
+	deadline   time.Time
+	count      int
+	found      []common.Address
+
+	// rate sampling
+	last   time.Time
+	lastN  uint64
+	lastW  []uint64
+	rate   float64
+	wrates []float64
+}
+
+// isTerminal reports whether f is a character device, which is close enough to a terminal for deciding
+// whether to draw interactive output.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// run redraws the dashboard every interval until ctx is done.
+func (d *dashboard) run(ctx context.Context, interval time.Duration) {
+	d.mu.Lock()
+	d.last, d.lastN = time.Now(), d.search.Attempts()
+	d.lastW = d.search.WorkerAttempts()
+	d.wrates = make([]float64, len(d.lastW))
+	d.mu.Unlock()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+		d.mu.Lock()
+		d.sample()
+		d.redraw()
+		d.mu.Unlock()
+	}
+}
+
+// sample must be called with d.mu held.
+func (d *dashboard) sample() {
+	now, n := time.Now(), d.search.Attempts()
+	secs := now.Sub(d.last).Seconds()
+	d.rate = float64(n-d.lastN) / secs
+	for i, w := range d.search.WorkerAttempts() {
+		d.wrates[i] = float64(w-d.lastW[i]) / secs
+		d.lastW[i] = w
+	}
+	d.last, d.lastN = now, n
+}
+
+// readCommands handles the commands typed on r until it is closed. quitting sends os.Interrupt on quit.
+func (d *dashboard) readCommands(r io.Reader, quit chan<- os.Signal) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		d.mu.Lock()
+		d.lines++ // the echoed command moved the cursor down a line
+		active, paused := d.search.State()
+		d.mu.Unlock()
+		switch strings.TrimSpace(sc.Text()) {
+		case "p":
+			d.search.SetPaused(!paused)
+		case "+":
+			d.search.SetActive(active + 1)
+		case "-":
+			d.search.SetActive(max(active-1, 1))
+		case "q":
+			quit <- os.Interrupt
+			return
+		}
+		d.mu.Lock()
+		d.redraw()
+		d.mu.Unlock()
+	}
+}
+
+func (d *dashboard) addFound(a common.Address) {
+	d.mu.Lock()
+	d.found = append(d.found, a)
+	d.redraw()
+	d.mu.Unlock()
+}
+
+// Write writes p to the terminal above the dashboard. it is meant to be used as the log output.
+func (d *dashboard) Write(p []byte) (int, error) {
+	return d.writeTo(d.term, p)
+}
+
+// writeTo writes p to w, which should be the same terminal the dashboard is drawn on, above the dashboard.
+func (d *dashboard) writeTo(w io.Writer, p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.erase()
+	n, err := w.Write(p)
+	d.redraw()
+	return n, err
+}
+
+// close draws the dashboard a final time and stops further redraws.
+func (d *dashboard) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.redraw()
+	d.closed = true
+}
+
+// erase and redraw must be called with d.mu held.
+func (d *dashboard) erase() {
+	if d.lines > 0 && !d.closed {
+		fmt.Fprintf(d.term, "\r\x1b[%dA\x1b[J", d.lines)
+		d.lines = 0
+	}
+}
+
+func (d *dashboard) redraw() {
+	if d.closed {
+		return
+	}
+	d.erase()
+	var b strings.Builder
+	active, paused := d.search.State()
+	state := "running"
+	if paused {
+		state = "paused"
+	}
+	elapsed := time.Since(d.start)
+	n := d.search.Attempts()
+	avg := float64(n) / elapsed.Seconds()
+
+	fmt.Fprintf(&b, "%s [%s]\n", d.title, state)
+	fmt.Fprintf(&b, "attempts  %d in %s\n", n, elapsed.Round(time.Second))
+	fmt.Fprintf(&b, "rate      %.0f keys/s (%.0f keys/s overall)\n", d.rate, avg)
+	if d.deadline.IsZero() {
+		fmt.Fprintf(&b, "expected  %s to a match\n", fmtSeconds(d.d/avg))
+	} else {
+		left := max(time.Until(d.deadline), 0)
+		fmt.Fprintf(&b, "expected  %s to a match; %.1f%% chance within the remaining %s\n",
+			fmtSeconds(d.d/avg), 100*successProbability(avg*left.Seconds(), d.d), left.Round(time.Second))
+	}
+	fmt.Fprintf(&b, "workers   %d of %d enabled\n", active, len(d.wrates))
+	for i := 0; i < len(d.wrates) && i < active; i++ {
+		fmt.Fprintf(&b, "  %3d     %.0f keys/s\n", i+1, d.wrates[i])
+	}
+	if res, score := d.search.Best(); score > 0 {
+		fmt.Fprintf(&b, "best      %s (%d of %d characters)\n", res.Address.Hex(), score, d.patternLen)
+	}
+	fmt.Fprintf(&b, "found     %d of %d\n", len(d.found), d.count)
+	for _, a := range d.found {
+		fmt.Fprintf(&b, "          %s\n", a.Hex())
+	}
+	b.WriteString("commands  p pause/resume, + add worker, - remove worker, q quit (each followed by enter)\n")
+
+	s := b.String()
+	io.WriteString(d.term, s)
+	d.lines = strings.Count(s, "\n")
+}
+
+// dashboardWriter writes to an underlying writer on behalf of a dashboard.
+type dashboardWriter struct {
+	d *dashboard
+	w io.Writer
+}
+
+func (w dashboardWriter) Write(p []byte) (int, error) { return w.d.writeTo(w.w, p) }
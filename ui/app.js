@@ -0,0 +1,165 @@
+// the web interface of 'vanity serve'. it uses the same REST and WebSocket API as any other client.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+let token = sessionStorage.getItem("token") || "";
+let current = null; // the job being watched: {id, socket}
+
+async function api(method, path, body) {
+  const headers = {};
+  if (token) headers.Authorization = "Bearer " + token;
+  if (body !== undefined) headers["Content-Type"] = "application/json";
+  const resp = await fetch(path, {method, headers, body: body === undefined ? undefined : JSON.stringify(body)});
+  const data = await resp.json().catch(() => ({}));
+  if (!resp.ok) throw new Error(data.error || resp.statusText);
+  return data;
+}
+
+function pattern(j) {
+  return (j.prefix || "") + "…" + (j.suffix || "") + (j.case_insensitive ? " (any case)" : "");
+}
+
+function duration(s) {
+  if (!isFinite(s)) return "forever";
+  const units = [["years", 365.25 * 86400], ["days", 86400], ["h", 3600], ["m", 60]];
+  for (const [name, n] of units) {
+    if (s >= 2 * n) return (s / n).toFixed(1) + " " + name;
+  }
+  return s.toFixed(1) + " s";
+}
+
+async function refresh() {
+  let jobs;
+  try {
+    jobs = await api("GET", "/jobs");
+  } catch (e) {
+    $("submit-error").textContent = e.message;
+    return;
+  }
+  const body = $("jobs").tBodies[0];
+  body.replaceChildren();
+  for (const j of jobs.reverse()) {
+    const row = body.insertRow();
+    for (const text of [j.id, pattern(j), j.status, new Date(j.created).toLocaleString()]) {
+      row.insertCell().textContent = text;
+    }
+    row.addEventListener("click", () => watch(j.id));
+  }
+}
+
+function show(j) {
+  $("job-id").textContent = j.id;
+  $("job-pattern").textContent = pattern(j) + (j.split_key ? ", split key" : "");
+  $("job-status").textContent = j.status + (j.error ? ": " + j.error : "");
+  $("job-attempts").textContent = j.attempts.toLocaleString() + " of about " + j.expected_attempts.toLocaleString() + " expected";
+  $("job-rate").textContent = Math.round(j.rate).toLocaleString() + " keys/s";
+  $("job-eta").textContent = j.eta_seconds !== undefined ? duration(j.eta_seconds) : "–";
+  $("job-best").textContent = j.best ? `${j.best.address} (${j.best.matched} of ${j.best.pattern_length})` : "–";
+  // the chance that a match would have been found by now
+  $("job-progress").value = j.status === "done" ? 1 : 1 - Math.exp(-j.attempts / j.expected_attempts);
+  $("cancel").hidden = j.status !== "queued" && j.status !== "running";
+
+  const r = j.result;
+  $("download").hidden = !r;
+  if (!r) return;
+  $("job-address").textContent = r.address;
+  $("split-note").hidden = !j.split_key;
+  $("job-offset").hidden = !r.offset;
+  $("job-offset").textContent = r.offset || "";
+  $("keystore").hidden = j.split_key || r.downloaded;
+  if (r.downloaded) $("job-error").textContent = "the key has already been downloaded and the server has forgotten it";
+}
+
+function watch(id) {
+  if (current) current.socket.close();
+  $("job").hidden = false;
+  $("job-error").textContent = "";
+  const url = new URL(`/jobs/${id}/events`, location.href);
+  url.protocol = location.protocol === "https:" ? "wss:" : "ws:";
+  if (token) url.searchParams.set("access_token", token);
+  // keys are only fetched as keystores, so that watching does not use up one-time results
+  url.searchParams.set("result", "0");
+  const socket = new WebSocket(url);
+  current = {id, socket};
+  socket.onmessage = async (e) => {
+    const j = JSON.parse(e.data);
+    show(j);
+    if (j.split_key && j.result && !j.result.downloaded) {
+      try {
+        show(await api("GET", `/jobs/${id}`)); // an offset is no use without the base key, so it is shown
+      } catch (err) {
+        $("job-error").textContent = err.message;
+      }
+    }
+  };
+  socket.onclose = (e) => {
+    if (e.code !== 1000 && e.reason) $("job-error").textContent = e.reason;
+    if (current && current.socket === socket) refresh();
+  };
+}
+
+$("auth").addEventListener("submit", (e) => {
+  e.preventDefault();
+  token = $("token").value;
+  sessionStorage.setItem("token", token);
+  $("token").value = "";
+  refresh();
+});
+
+$("submit").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const f = e.target.elements;
+  const req = {
+    prefix: f.prefix.value,
+    suffix: f.suffix.value,
+    case_insensitive: f.case_insensitive.checked,
+    one_time: f.one_time.checked,
+    max_attempts: Number(f.max_attempts.value) || 0,
+    timeout_seconds: Number(f.timeout_seconds.value) || 0,
+    public_key: f.public_key.value.trim(),
+  };
+  $("submit-error").textContent = "";
+  try {
+    const j = await api("POST", "/jobs", req);
+    refresh();
+    watch(j.id);
+  } catch (err) {
+    $("submit-error").textContent = err.message;
+  }
+});
+
+$("refresh").addEventListener("click", refresh);
+
+$("cancel").addEventListener("click", async () => {
+  try {
+    show(await api("DELETE", `/jobs/${current.id}`));
+  } catch (err) {
+    $("job-error").textContent = err.message;
+  }
+});
+
+$("download").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const f = e.target.elements;
+  if (f.passphrase.value !== f.confirm.value) {
+    $("job-error").textContent = "the passphrases differ";
+    return;
+  }
+  $("job-error").textContent = "encrypting…";
+  try {
+    const ks = await api("POST", `/jobs/${current.id}/keystore`, {passphrase: f.passphrase.value});
+    const blob = new Blob([JSON.stringify(ks, null, 2) + "\n"], {type: "application/json"});
+    const a = document.createElement("a");
+    a.href = URL.createObjectURL(blob);
+    a.download = $("job-address").textContent + ".json";
+    a.click();
+    URL.revokeObjectURL(a.href);
+    $("job-error").textContent = "";
+    e.target.reset();
+  } catch (err) {
+    $("job-error").textContent = err.message;
+  }
+});
+
+refresh();
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// vaultTimeout bounds each request to Vault.
+const vaultTimeout = time.Minute
+
+// vaultClient writes keys to a HashiCorp Vault KV version 2 secrets engine, so that they never touch
+// the disk. Vault's transit engine cannot hold them: none of the key types it imports is secp256k1.
+type vaultClient struct {
+	addr      *url.URL
+	namespace string
+	mount     string // of the KV engine
+	token     string
+	roleID    string // for AppRole logins, which are repeated when the token expires
+	secretID  string
+}
+
+// openVault logs in to the Vault at $VAULT_ADDR, with $VAULT_TOKEN or, failing that, the AppRole
+// $VAULT_ROLE_ID and $VAULT_SECRET_ID, in $VAULT_NAMESPACE if it is set, and returns a client for the KV
+// v2 engine at mount.
+func openVault(mount string) (*vaultClient, error) {
+	raw := os.Getenv("VAULT_ADDR")
+	if raw == "" {
+		return nil, errors.New("-vault: set VAULT_ADDR")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("-vault: VAULT_ADDR: %w", err)
+	}
+	mount = strings.Trim(mount, "/")
+	if mount == "" || strings.HasPrefix(mount, "transit") {
+		return nil, fmt.Errorf("-vault-mount %q: give the mount of a KV version 2 engine; the transit engine does not support secp256k1 keys", mount)
+	}
+	v := &vaultClient{
+		addr:      u,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		mount:     mount,
+		token:     os.Getenv("VAULT_TOKEN"),
+		roleID:    os.Getenv("VAULT_ROLE_ID"),
+		secretID:  os.Getenv("VAULT_SECRET_ID"),
+	}
+	switch {
+	case v.token != "":
+		// checked now rather than once a key is found
+		err = v.do(http.MethodGet, "auth/token/lookup-self", nil, nil)
+	case v.roleID != "" && v.secretID != "":
+		err = v.login()
+	default:
+		err = errors.New("set VAULT_TOKEN, or VAULT_ROLE_ID and VAULT_SECRET_ID for AppRole")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("-vault: %w", err)
+	}
+	return v, nil
+}
+
+// login logs in with the client's AppRole.
+func (v *vaultClient) login() error {
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	v.token = ""
+	if err := v.do(http.MethodPost, "auth/approle/login", map[string]string{"role_id": v.roleID, "secret_id": v.secretID}, &resp); err != nil {
+		return err
+	}
+	if resp.Auth.ClientToken == "" {
+		return errors.New("the AppRole login returned no token")
+	}
+	v.token = resp.Auth.ClientToken
+	return nil
+}
+
+// name returns how a secret at path is reported, in place of the path of a key file.
+func (v *vaultClient) name(path string) string {
+	return "vault:" + v.mount + "/" + path
+}
+
+// putKey writes key to the secret at path, which must not exist yet, so that no key is ever replaced.
+func (v *vaultClient) putKey(path string, key *ecdsa.PrivateKey) error {
+	d := crypto.FromECDSA(key)
+	defer clear(d)
+	body := map[string]any{
+		"options": map[string]int{"cas": 0}, // write only if there is no secret at path
+		"data": map[string]string{
+			"address":     crypto.PubkeyToAddress(key.PublicKey).Hex(),
+			"private_key": hex.EncodeToString(d),
+		},
+	}
+	return v.do(http.MethodPost, v.mount+"/data/"+path, body, nil)
+}
+
+// checkKey reads back the secret at path and checks that it holds the key of addr.
+func (v *vaultClient) checkKey(path string, addr common.Address) error {
+	var resp struct {
+		Data struct {
+			Data struct {
+				PrivateKey string `json:"private_key"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.do(http.MethodGet, v.mount+"/data/"+path, nil, &resp); err != nil {
+		return err
+	}
+	d, err := hex.DecodeString(resp.Data.Data.PrivateKey)
+	if err != nil {
+		return err
+	}
+	defer clear(d)
+	return checkScalar(d, addr)
+}
+
+// do sends a request to the API path, with body as JSON if it is not nil, and decodes the response into
+// out if it is not nil. it logs in again once if an AppRole token has expired.
+func (v *vaultClient) do(method, path string, body, out any) error {
+	err := v.request(method, path, body, out)
+	var se *vaultStatusError
+	if errors.As(err, &se) && se.code == http.StatusForbidden && v.roleID != "" && path != "auth/approle/login" {
+		if err = v.login(); err == nil {
+			err = v.request(method, path, body, out)
+		}
+	}
+	return err
+}
+
+type vaultStatusError struct {
+	code int
+	msg  string
+}
+
+func (e *vaultStatusError) Error() string { return e.msg }
+
+func (v *vaultClient) request(method, path string, body, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+		defer clear(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.addr.JoinPath("v1", path).String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	defer clear(b) // it may hold a key, read back
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(b, &e)
+		return &vaultStatusError{resp.StatusCode, fmt.Sprintf("%s %s: %s: %s", method, path, resp.Status, strings.Join(e.Errors, "; "))}
+	}
+	if out != nil {
+		return json.Unmarshal(b, out)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// verify implements the verify subcommand, which re-derives the address of a saved key and checks it
+// against an expected address and/or pattern. it returns exitFailure on a mismatch.
+func verify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		keyPath     *string = fs.String("k", "priv.key", "path of the key file: hex, keystore (v3 or eip2335) or mnemonic")
+		addr        *string = fs.String("a", "", "expected address")
+		prefix      *string = fs.String("p", "", "expected address prefix (excluding 0x)")
+		suffix      *string = fs.String("s", "", "expected address suffix")
+		insensitive *bool   = fs.Bool("i", false, "match the prefix and suffix case-insensitively")
+		passFile    *string = fs.String("pass", "", "file containing the keystore passphrase (defaults to $VANITY_PASSPHRASE), or the BIP-39 passphrase for mnemonics")
+		hdPath      *string = fs.String("path", accounts.DefaultBaseDerivationPath.String(), "BIP-32 derivation path for mnemonics")
+		noColor     *bool   = fs.Bool("no-color", false, "never color the matched part of the address (also disabled by NO_COLOR)")
+		attPath     *string = fs.String("attestation", "", "check the attestation in this JSON file, such as a bundle's metadata.json or a serve job's result, instead of a key file")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s verify [flags]\n\nre-derives the address of a key file and checks it against an address or pattern.\nwith -attestation, checks that an attestation was signed by the key of its address instead.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := applyDefaults(fs, defaultConfigPath(), false); err != nil {
+		fatal(err)
+	}
+	if *addr != "" && !common.IsHexAddress(*addr) {
+		fatal(usageError{fmt.Errorf("invalid address %q", *addr)})
+	}
+	if *prefix+*suffix != "" {
+		if err := vanity.ValidatePattern(*prefix + *suffix); err != nil {
+			fatal(err)
+		}
+	}
+
+	var got common.Address
+	if *attPath != "" {
+		a, err := readAttestation(*attPath)
+		if err != nil {
+			fatal(err)
+		}
+		c, err := a.check()
+		if errors.Is(err, errBadAttestation) {
+			slog.Error(err.Error())
+			return exitFailure
+		} else if err != nil {
+			fatal(err)
+		}
+		got = common.HexToAddress(c.Address)
+		slog.Info("the attestation is signed by the key of its address", "timestamp", c.Timestamp, "version", c.Version)
+	} else {
+		data, err := os.ReadFile(*keyPath)
+		if err != nil {
+			fatal(err)
+		}
+		key, err := loadKey(data, *passFile, *hdPath)
+		if err != nil {
+			fatal(err)
+		}
+		got = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	if *prefix+*suffix != "" && useColor(os.Stdout, *noColor) {
+		fmt.Println(highlight(got, *prefix, *suffix, *insensitive))
+	} else {
+		fmt.Println(got)
+	}
+
+	ok := true
+	if *addr != "" && common.HexToAddress(*addr) != got {
+		slog.Error("address mismatch", "expected", common.HexToAddress(*addr))
+		ok = false
+	}
+	if *prefix+*suffix != "" {
+		s := &vanity.Searcher{Prefix: *prefix, Suffix: *suffix, CaseInsensitive: *insensitive}
+		if !s.Matches(got) {
+			slog.Error("address does not match the pattern", "prefix", *prefix, "suffix", *suffix)
+			ok = false
+		}
+	}
+	if !ok {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// readAttestation reads an attestation from a JSON file: the attestation itself, or a document that
+// has one, such as a bundle's metadata.json, a -webhook payload or a serve job's status.
+func readAttestation(path string) (*attestation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		attestation
+		Attestation *attestation `json:"attestation"`
+		Result      *struct {
+			Attestation *attestation `json:"attestation"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case v.Attestation != nil:
+		return v.Attestation, nil
+	case v.Result != nil && v.Result.Attestation != nil:
+		return v.Result.Attestation, nil
+	case v.Message != "":
+		return &v.attestation, nil
+	}
+	return nil, fmt.Errorf("%s has no attestation", path)
+}
+
+// loadKey decodes a key file in any of the supported formats.
+func loadKey(data []byte, passFile, hdPath string) (*ecdsa.PrivateKey, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var v struct {
+			Version int `json:"version"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		pass, err := readPassphrase(passFile)
+		if err != nil {
+			return nil, err
+		}
+		switch v.Version {
+		case 3:
+			k, err := keystore.DecryptKey(data, string(pass))
+			if err != nil {
+				return nil, err
+			}
+			return k.PrivateKey, nil
+		case 4:
+			return decryptV4(data, pass)
+		default:
+			return nil, fmt.Errorf("unsupported keystore version %d", v.Version)
+		}
+	}
+
+	s := strings.TrimPrefix(string(data), "0x")
+	if b, err := hex.DecodeString(s); err == nil {
+		return crypto.ToECDSA(b)
+	}
+
+	// anything else had better be a mnemonic
+	mnemonic := strings.Join(strings.Fields(string(data)), " ")
+	var pass []byte
+	if passFile != "" {
+		var err error
+		if pass, err = readPassphrase(passFile); err != nil {
+			return nil, err
+		}
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, string(pass))
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized key file: %w", err)
+	}
+	path, err := accounts.ParseDerivationPath(hdPath)
+	if err != nil {
+		return nil, err
+	}
+	return deriveKey(seed, path)
+}
+
+// deriveKey derives the private key at path from a BIP-32 seed. it is deliberately a separate
+// implementation from anything used to generate keys, so that it can act as an independent check.
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	n := crypto.S256().Params().N
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	k, chain := new(big.Int).SetBytes(sum[:32]), sum[32:]
+	if k.Sign() == 0 || k.Cmp(n) >= 0 {
+		return nil, errors.New("invalid master key")
+	}
+
+	for _, i := range path {
+		mac = hmac.New(sha512.New, chain)
+		if i >= 0x80000000 { // hardened
+			mac.Write([]byte{0})
+			mac.Write(math.PaddedBigBytes(k, 32))
+		} else {
+			priv, err := crypto.ToECDSA(math.PaddedBigBytes(k, 32))
+			if err != nil {
+				return nil, err
+			}
+			mac.Write(crypto.CompressPubkey(&priv.PublicKey))
+		}
+		mac.Write(binary.BigEndian.AppendUint32(nil, i))
+		sum = mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(n) >= 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", i)
+		}
+		k.Add(k, il).Mod(k, n)
+		if k.Sign() == 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", i)
+		}
+		chain = sum[32:]
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(k, 32))
+}
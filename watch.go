@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// watchFunds polls c every interval until every address in addrs has received funds, or ctx is done.
+// it calls funded for each address the first time its balance rises above what it was when watching
+// began. failed requests are logged and retried at the next poll, as a node that is briefly unreachable
+// is no reason to stop watching.
+func watchFunds(ctx context.Context, c *rpcClient, addrs []common.Address, interval time.Duration, funded func(common.Address, *big.Int)) {
+	initial := make(map[common.Address]*big.Int)
+	pending := append([]common.Address(nil), addrs...)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var left []common.Address
+		for _, addr := range pending {
+			if ctx.Err() != nil {
+				return
+			}
+			bal, err := c.balance(ctx, addr)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				slog.Warn("could not get the balance", "address", addr.Hex(), "err", err)
+			case initial[addr] == nil:
+				initial[addr] = bal
+				if bal.Sign() != 0 {
+					slog.Warn("the address already has a balance; watching for more", "address", addr.Hex(), "balance_wei", bal)
+				}
+			case bal.Cmp(initial[addr]) > 0:
+				funded(addr, bal)
+				continue
+			}
+			left = append(left, addr)
+		}
+		if pending = left; len(pending) == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
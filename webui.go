@@ -0,0 +1,100 @@
+package main
+
+import (
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ui is the single-page web interface served at /. it talks to the same REST and WebSocket API as any
+// other client, so it has no privileges of its own.
+//
+//go:embed ui
+var ui embed.FS
+
+// kdfSlots bounds the keystores being encrypted at once; scrypt needs 256 MiB for each.
+var kdfSlots = make(chan struct{}, 2)
+
+// keystoreRequest is the body of a POST /jobs/{id}/keystore request.
+type keystoreRequest struct {
+	Passphrase string `json:"passphrase"`
+	KDF        string `json:"kdf"` // scrypt (the default) or pbkdf2
+}
+
+// keystore serves POST /jobs/{id}/keystore, which returns a finished job's key as an EIP-2335 keystore
+// encrypted with the given passphrase, so that it need never be shown in the clear. like GET /jobs/{id},
+// it counts as fetching a one_time result.
+func (s *server) keystore(r *http.Request, c caller) (int, any, error) {
+	var req keystoreRequest
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return 0, nil, httpError{http.StatusBadRequest, err}
+	}
+	switch {
+	case req.Passphrase == "":
+		return 0, nil, httpError{http.StatusBadRequest, errors.New("a passphrase is required")}
+	case req.KDF == "":
+		req.KDF = "scrypt"
+	case req.KDF != "scrypt" && req.KDF != "pbkdf2":
+		return 0, nil, httpError{http.StatusBadRequest, fmt.Errorf("unsupported kdf %q", req.KDF)}
+	}
+	j, err := s.get(r.PathValue("id"), c.tenant)
+	if err != nil {
+		return 0, nil, err
+	}
+	if j.pub != nil {
+		return 0, nil, httpError{http.StatusConflict, errors.New("the result of a split-key job is an offset, not a key; combine it with 'vanity splitkey'")}
+	}
+	select {
+	case kdfSlots <- struct{}{}:
+		defer func() { <-kdfSlots }()
+	case <-r.Context().Done():
+		return 0, nil, r.Context().Err()
+	}
+	st, err := j.view(c)
+	switch {
+	case err != nil:
+		return 0, nil, err
+	case st.Result == nil:
+		return 0, nil, httpError{http.StatusConflict, fmt.Errorf("job %s has no result", j.id)}
+	case st.Result.Downloaded:
+		return 0, nil, httpError{http.StatusGone, fmt.Errorf("the one-time result of job %s has already been fetched", j.id)}
+	}
+	b, err := hex.DecodeString(st.Result.PrivateKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer clear(b)
+	key, err := crypto.ToECDSA(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	ks, err := encryptV4(key, []byte(req.Passphrase), req.KDF, "vanity address "+st.Result.Address)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, ks, nil
+}
+
+// uiHandler serves the web interface.
+func uiHandler() http.Handler {
+	root, err := fs.Sub(ui, "ui")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServerFS(root)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; connect-src 'self' ws: wss:; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		files.ServeHTTP(w, r)
+	})
+}
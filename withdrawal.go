@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// withdrawal credentials are the 32 bytes a validator's deposit commits to, which say where its stake is
+// withdrawn to. those with the 0x01 prefix name an execution address: 0x01, 11 zero bytes and then the
+// address. they can never be changed once the deposit is made.
+// see https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/validator.md#eth1_address_withdrawal_prefix
+const eth1AddressWithdrawalPrefix = 0x01
+
+// withdrawalCredentials returns the 0x01 withdrawal credentials of addr.
+func withdrawalCredentials(addr common.Address) common.Hash {
+	var wc common.Hash
+	wc[0] = eth1AddressWithdrawalPrefix
+	copy(wc[12:], addr[:])
+	return wc
+}
+
+// checkWithdrawalCredentials checks that wc are well-formed 0x01 withdrawal credentials for addr, as a
+// mistake in them would send the stake somewhere it could never be withdrawn from.
+func checkWithdrawalCredentials(wc []byte, addr common.Address) error {
+	switch {
+	case len(wc) != common.HashLength:
+		return fmt.Errorf("withdrawal credentials are %d bytes, not %d", len(wc), common.HashLength)
+	case wc[0] != eth1AddressWithdrawalPrefix:
+		return fmt.Errorf("withdrawal credentials begin with %#02x, not %#02x", wc[0], eth1AddressWithdrawalPrefix)
+	case !bytes.Equal(wc[1:12], make([]byte, 11)):
+		return errors.New("withdrawal credentials do not have 11 zero bytes after the prefix")
+	case common.BytesToAddress(wc[12:]) != addr:
+		return fmt.Errorf("withdrawal credentials name %s, not %s", common.BytesToAddress(wc[12:]).Hex(), addr.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cdillond/vanity/pkg/vanity"
+)
+
+// leet maps the letters that have no hex digit of their own to the digits that look most like them.
+var leet = map[rune]rune{
+	'g': '9', 'i': '1', 'l': '1', 'o': '0', 's': '5', 't': '7', 'z': '2',
+}
+
+// toHex spells word in hex digits, or returns an error naming the first character that can't be spelled.
+func toHex(word string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f':
+			b.WriteRune(r)
+		case leet[r] != 0:
+			b.WriteRune(leet[r])
+		default:
+			return "", fmt.Errorf("%q has no hex look-alike", r)
+		}
+	}
+	return b.String(), nil
+}
+
+// wizard interactively builds a generate command line for first-time users, then runs it and returns its
+// exit status.
+func wizard() int {
+	in := bufio.NewReader(os.Stdin)
+	ask := func(prompt, def string) string {
+		if def != "" {
+			fmt.Printf("%s [%s]: ", prompt, def)
+		} else {
+			fmt.Printf("%s: ", prompt)
+		}
+		line, err := in.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			fmt.Println()
+			os.Exit(exitUsage)
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			return def
+		}
+		return line
+	}
+
+	fmt.Println("no flags given, so let's set up a search. (run 'vanity help' for the non-interactive usage.)")
+	fmt.Println()
+	var word string
+	for word == "" {
+		w := ask("word or hex string your address should contain", "")
+		hex, err := toHex(w)
+		switch {
+		case err != nil:
+			fmt.Printf("  %s; try another word\n", err)
+		case len(hex) > 32:
+			fmt.Println("  that is too long; try another word")
+		default:
+			word = hex
+		}
+	}
+	fmt.Printf("  in hex, that is %s\n\n", word)
+
+	where := ""
+	for where != "p" && where != "s" {
+		where = strings.ToLower(ask("at the start (p) or end (s) of the address?", "p"))
+	}
+
+	fmt.Println("\nmeasuring how fast this machine generates keys...")
+	workers := runtime.GOMAXPROCS(0)
+	rate, err := vanity.MeasureRate(context.Background(), vanity.Geth, len(word), true, time.Second, workers)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("  %.0f keys/s\n\n", rate)
+
+	// matches are case-insensitive, which is much quicker and loses nothing for digits
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  #\tpattern\texpected time\t90% chance within")
+	for n := 1; n <= len(word); n++ {
+		pat := word[:n]
+		if where == "s" {
+			pat = word[len(word)-n:]
+		}
+		d := vanity.Difficulty(pat, "", false)
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\n", n, pat, fmtSeconds(d/rate), fmtSeconds(attemptsFor(0.9, d)/rate))
+	}
+	tw.Flush()
+	fmt.Println()
+
+	n := 0
+	for n < 1 || n > len(word) {
+		n, _ = strconv.Atoi(ask("which one?", strconv.Itoa(min(len(word), 5))))
+	}
+	path := ask("save the key to", "priv.key")
+
+	args := []string{"-i", "-o", path}
+	if where == "s" {
+		args = append(args, "-s", word[len(word)-n:])
+	} else {
+		args = append(args, "-p", word[:n])
+	}
+	if n > 5 {
+		args = append(args, "-l")
+	}
+	fmt.Printf("\nrunning: %s %s\n\n", os.Args[0], strings.Join(args, " "))
+	return generate(args)
+}
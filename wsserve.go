@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// watch serves GET /jobs/{id}/events, a WebSocket that receives the job's status, as returned by GET
+// /jobs/{id}, every ?interval (default 1s) until the job finishes, and then once more with its final
+// state before the server closes the connection. browsers cannot set headers on WebSockets, so the token
+// may be given as ?access_token instead. with ?result=0, the final state carries only the result's
+// address, leaving a one_time result to be fetched some other way, such as POST /jobs/{id}/keystore.
+func (s *server) watch(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if t := r.URL.Query().Get("access_token"); t != "" {
+		auth = "Bearer " + t
+	}
+	c, ok := s.authenticate(auth)
+	if !ok {
+		writeJSON(w, 0, nil, httpError{http.StatusUnauthorized, errors.New("missing or invalid bearer token")})
+		return
+	}
+	j, err := s.get(r.PathValue("id"), c.tenant)
+	if err != nil {
+		writeJSON(w, 0, nil, err)
+		return
+	}
+	interval := time.Second
+	if v := r.URL.Query().Get("interval"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil {
+			writeJSON(w, 0, nil, httpError{http.StatusBadRequest, err})
+			return
+		}
+		interval = max(interval, 100*time.Millisecond)
+	}
+
+	up := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	conn, err := up.Upgrade(w, r, nil) // which replies to the client itself on failure
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// the client sends nothing, but its messages must be read for close frames to be noticed
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(st jobStatus) error {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(st)
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-j.done:
+			code, reason := websocket.CloseNormalClosure, "job finished"
+			st, err := j.snapshot(), error(nil)
+			if r.URL.Query().Get("result") != "0" {
+				st, err = j.view(c)
+			}
+			if err != nil {
+				code, reason = websocket.ClosePolicyViolation, err.Error()
+			}
+			if send(st) == nil {
+				msg := websocket.FormatCloseMessage(code, reason)
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			}
+			return
+		default:
+		}
+		if err = send(j.snapshot()); err != nil {
+			slog.Debug("websocket closed", "id", j.id, "err", err)
+			return
+		}
+		select {
+		case <-tick.C:
+		case <-j.done:
+		case <-gone:
+			return
+		}
+	}
+}
+
+// checkOrigin allows WebSockets from pages served by the server itself, as the default check does, and
+// from the -ws-origin.
+func (s *server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.wsOrigin == "*" || origin == s.wsOrigin {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}